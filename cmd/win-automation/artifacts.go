@@ -72,6 +72,9 @@ func cmdArtifactsList(ctx context.Context, cfg config.Config, args []string) int
 		for _, art := range manifest.Artifacts {
 			fmt.Printf("%s (%s) %d bytes sha256=%s\n", art.Path, art.Type, art.SizeBytes, art.SHA256)
 		}
+		if _, ok := traceArtifact(manifest); ok {
+			fmt.Printf("open the trace with: win-automation playwright show-trace --job %s\n", manifest.JobID)
+		}
 	}
 
 	logx.Info("artifacts", "list", "ok",
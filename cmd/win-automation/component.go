@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/component"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// Lifecycle operations shared by the components win-automation installs on Windows.
+const (
+	opInstall   = "install"
+	opRepair    = "repair"
+	opUninstall = "uninstall"
+)
+
+// applyComponent runs op on c and logs each change under the component's name.
+// It reports whether any file was replaced or any piece newly installed.
+func applyComponent(ctx context.Context, cfg config.Config, op string, c component.Component) (changed bool, err error) {
+	report := func(change component.Change) {
+		logx.Info(c.Name, op, change.Kind,
+			logx.Field{Key: "name", Value: change.Name},
+			logx.Field{Key: "state", Value: change.State},
+		)
+		switch change.State {
+		case component.StateInstalled, component.StateDrifted, component.StateMissing:
+			changed = true
+		}
+	}
+	switch op {
+	case opInstall:
+		err = component.Install(ctx, cfg, c, report)
+	case opRepair:
+		err = component.Repair(ctx, cfg, c, report)
+	case opUninstall:
+		err = component.Uninstall(ctx, cfg, c, report)
+	default:
+		err = fmt.Errorf("unknown component operation %q", op)
+	}
+	return changed, err
+}
+
+// cmdAlohaLifecycle installs, repairs or removes the Aloha start scripts, their logon
+// tasks and the Aloha firewall rules.
+func cmdAlohaLifecycle(ctx context.Context, cfg config.Config, op string, args []string) int {
+	fs := flag.NewFlagSet("aloha "+op, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c := aloha.Component(cfg)
+	if op == opUninstall {
+		c = aloha.Removal()
+	} else if len(c.Files) == 0 {
+		logx.Warn("aloha", op, "no start command configured; only firewall rules are managed")
+	}
+	if _, err := applyComponent(ctx, cfg, op, c); err != nil {
+		logx.Error("aloha", op, "failed", err)
+		return 1
+	}
+	logx.Info("aloha", op, "ok")
+	return 0
+}
@@ -7,12 +7,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alejg/win-automation/internal/config"
 	"github.com/alejg/win-automation/internal/hatchet"
 	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/playwright"
 	"github.com/google/uuid"
 	"github.com/hatchet-dev/hatchet/pkg/client/rest"
 	sdk "github.com/hatchet-dev/hatchet/sdks/go"
@@ -29,10 +31,20 @@ func cmdJobs(ctx context.Context, cfg config.Config, args []string) int {
 		return cmdJobsEnqueue(ctx, cfg, args[1:])
 	case "status":
 		return cmdJobsStatus(ctx, cfg, args[1:])
+	case "list":
+		return cmdJobsList(ctx, cfg, args[1:])
+	case "result":
+		return cmdJobsResult(ctx, cfg, args[1:])
+	case "wait":
+		return cmdJobsWait(ctx, cfg, args[1:])
+	case "logs":
+		return cmdJobsLogs(ctx, cfg, args[1:])
 	case "cancel":
 		return cmdJobsCancel(ctx, cfg, args[1:])
 	case "run":
 		return cmdJobsRun(ctx, cfg, args[1:])
+	case "schedule":
+		return cmdJobsSchedule(ctx, cfg, args[1:])
 	default:
 		logx.Error("jobs", "dispatch", "unknown subcommand", fmt.Errorf("%s", args[0]))
 		return 2
@@ -43,6 +55,8 @@ type jobOutput struct {
 	JobID   string `json:"job_id"`
 	State   string `json:"state"`
 	TraceID string `json:"trace_id"`
+	// Existing is set when the idempotency key matched a job already enqueued.
+	Existing bool `json:"existing,omitempty"`
 }
 
 type jobEnqueueOptions struct {
@@ -50,11 +64,37 @@ type jobEnqueueOptions struct {
 	cmd             string
 	task            string
 	timeout         time.Duration
+	retryMax        int
+	retryBackoff    time.Duration
+	retryExitCodes  bool
+	idempotencyKey  string
+	priority        string
 	maxSteps        int
-	selectedScreen  int
+	selectedScreen  string
 	traceID         string
 	jsonOutput      bool
 	idempotentCheck string
+	interactive     bool
+	verify          string
+	verifyRetries   int
+	verifyDelay     time.Duration
+	taskRetries     int
+	budget          time.Duration
+	template        string
+	script          string
+	browser         string
+	policies        playwright.Policies
+	profile         string
+	url             string
+	screenshot      string
+	pdf             string
+	har             string
+	waitFor         string
+	viewport        string
+	file            string
+	workflow        string
+	parallel        int
+	wait            bool
 }
 
 type windowsExecPayload struct {
@@ -76,6 +116,12 @@ func cmdJobsEnqueue(ctx context.Context, cfg config.Config, args []string) int {
 		logx.Error("jobs", "enqueue", "invalid args", err)
 		return 2
 	}
+	if opts.file != "" {
+		return enqueueJobFile(ctx, cfg, opts)
+	}
+	if opts.workflow != "" {
+		return enqueueWorkflow(ctx, cfg, opts)
+	}
 
 	client, err := hatchet.NewSDKClient(cfg)
 	if err != nil {
@@ -83,7 +129,7 @@ func cmdJobsEnqueue(ctx context.Context, cfg config.Config, args []string) int {
 		return 2
 	}
 
-	result, err := enqueueJob(ctx, client, opts)
+	result, err := enqueueJob(ctx, client, hatchet.NewIdempotencyKeys(cfg, client), opts)
 	if err != nil {
 		if isJobsUsageError(err) {
 			logx.Error("jobs", "enqueue", "invalid args", err)
@@ -102,6 +148,7 @@ func cmdJobsStatus(ctx context.Context, cfg config.Config, args []string) int {
 	fs := flag.NewFlagSet("jobs status", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	jobID := fs.String("id", "", "workflow run id (required)")
+	detail := fs.Bool("detail", false, "also show the output, error, attempts, timings and artifact directory")
 	jsonOutput := fs.Bool("json", false, "output as json")
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -124,9 +171,13 @@ func cmdJobsStatus(ctx context.Context, cfg config.Config, args []string) int {
 		return 1
 	}
 
-	state := mapRunState(details.Run.Status)
-	traceID := traceIDFromInput(map[string]interface{}(details.Run.Input))
-	writeJobOutput(jobOutput{JobID: *jobID, State: state, TraceID: traceID}, *jsonOutput)
+	state := jobState(details)
+	if *detail {
+		writeJobDetail(jobDetailFromRun(cfg, *jobID, details), *jsonOutput)
+	} else {
+		traceID := traceIDFromInput(map[string]interface{}(details.Run.Input))
+		writeJobOutput(jobOutput{JobID: *jobID, State: state, TraceID: traceID}, *jsonOutput)
+	}
 	logx.Info("jobs", "status", "ok", logx.Field{Key: "job_id", Value: *jobID}, logx.Field{Key: "state", Value: state})
 	return 0
 }
@@ -172,7 +223,7 @@ func cmdJobsCancel(ctx context.Context, cfg config.Config, args []string) int {
 		return 1
 	}
 
-	state := mapRunState(details.Run.Status)
+	state := jobState(details)
 	traceID := traceIDFromInput(map[string]interface{}(details.Run.Input))
 	writeJobOutput(jobOutput{JobID: *jobID, State: state, TraceID: traceID}, *jsonOutput)
 	logx.Info("jobs", "cancel", "ok", logx.Field{Key: "job_id", Value: *jobID}, logx.Field{Key: "state", Value: state})
@@ -187,8 +238,12 @@ func cmdJobsRun(ctx context.Context, cfg config.Config, args []string) int {
 		logx.Error("jobs", "run", "invalid args", err)
 		return 2
 	}
+	if opts.file != "" || opts.workflow != "" {
+		logx.Error("jobs", "run", "invalid args", errors.New("--file and --workflow are only supported by jobs enqueue"))
+		return 2
+	}
 
-	logx.Warn("jobs", "run", "deprecated", logx.Field{Key: "detail", Value: "use jobs enqueue/status/cancel"})
+	logx.Warn("jobs", "run", "deprecated", logx.Field{Key: "detail", Value: "use jobs enqueue and jobs wait"})
 
 	client, err := hatchet.NewSDKClient(cfg)
 	if err != nil {
@@ -196,7 +251,7 @@ func cmdJobsRun(ctx context.Context, cfg config.Config, args []string) int {
 		return 2
 	}
 
-	result, err := enqueueJob(ctx, client, opts)
+	result, err := enqueueJob(ctx, client, hatchet.NewIdempotencyKeys(cfg, client), opts)
 	if err != nil {
 		if isJobsUsageError(err) {
 			logx.Error("jobs", "run", "invalid args", err)
@@ -206,7 +261,7 @@ func cmdJobsRun(ctx context.Context, cfg config.Config, args []string) int {
 		return 1
 	}
 
-	final, err := watchJob(client, result.JobID, result.TraceID, cfg.HatchetJobTimeout)
+	details, err := waitJob(ctx, cfg, client, result.JobID, cfg.HatchetJobTimeout, nil)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			logx.Error("jobs", "run", "timeout", err, logx.Field{Key: "job_id", Value: result.JobID})
@@ -216,44 +271,157 @@ func cmdJobsRun(ctx context.Context, cfg config.Config, args []string) int {
 		return 1
 	}
 
+	final := jobOutput{JobID: result.JobID, State: jobState(details), TraceID: result.TraceID}
 	writeJobOutput(final, opts.jsonOutput)
 	logx.Info("jobs", "run", "ok", logx.Field{Key: "job_id", Value: final.JobID}, logx.Field{Key: "state", Value: final.State})
-	if final.State == "failed" || final.State == "cancelled" {
-		return 1
-	}
-	return 0
+	return jobStateExitCode(final.State)
 }
 
 func parseJobsEnqueueFlags(fs *flag.FlagSet, cfg config.Config, args []string) (jobEnqueueOptions, error) {
-	jobType := fs.String("type", "", "job type: windows.exec or aloha.run (required)")
+	jobType := fs.String("type", "", "job type: windows.exec, aloha.run, playwright.run or playwright.capture (required)")
 	cmd := fs.String("cmd", "", "command for windows.exec")
 	task := fs.String("task", "", "task text for aloha.run")
 	timeout := fs.Duration("timeout", cfg.HatchetJobTimeout, "job timeout")
+	retryMax := fs.Int("retry-max", 0, "retries after a transient failure (default hatchet.retry_max)")
+	retryBackoff := fs.Duration("retry-backoff", 0, "delay before the first retry, doubling after (default hatchet.retry_backoff)")
+	retryExitCodes := fs.Bool("retry-exit-codes", false, "also retry when the command exits non-zero; only for jobs safe to rerun")
+	idempotencyKey := fs.String("idempotency-key", "", "return the job already enqueued with this key within hatchet.dedup_window; \"auto\" derives it from type and payload")
+	priority := fs.String("priority", hatchet.PriorityNormal, "queue priority: low, normal or high")
 	maxSteps := fs.Int("max-steps", 10, "max steps for aloha.run")
-	selectedScreen := fs.Int("selected-screen", 0, "screen index for aloha.run")
+	selectedScreen := fs.String("selected-screen", "", "screen index, \"primary\" or display name for aloha.run")
 	traceID := fs.String("trace-id", "", "trace id")
 	idempotentCheck := fs.String("idempotent-check", "", "PowerShell snippet to verify idempotency")
+	interactive := fs.Bool("interactive", false, "hold the desktop lock while a windows.exec command runs")
+	verifyScript := fs.String("verify", "", "PowerShell postcondition for aloha.run that must exit 0 after the task")
+	verifyRetries := fs.Int("verify-retries", 3, "extra attempts for the --verify check")
+	verifyDelay := fs.Duration("verify-delay", 2*time.Second, "delay between --verify attempts")
+	taskRetries := fs.Int("task-retries", 0, "re-run the aloha.run task up to N times when --verify fails")
+	budget := fs.Duration("budget", 0, "wall-clock budget for aloha.run (default aloha.task_budget)")
+	templateName := fs.String("template", "", "named task template to render")
+	params := paramFlags{}
+	fs.Var(params, "param", "template parameter key=value (repeatable)")
+	script := fs.String("script", "", "flow script (.js) for playwright.run")
+	browser := fs.String("browser", "", "browser for playwright jobs (default the worker's playwright.browser)")
+	var policies playwright.Policies
+	playwrightPolicyFlags(fs, &policies, true)
+	profile := fs.String("profile", "", "named browser profile for playwright jobs")
+	pageURL := fs.String("url", "", "page to capture for playwright.capture")
+	screenshot := fs.String("screenshot", "", "screenshot file name for playwright.capture")
+	pdf := fs.String("pdf", "", "PDF file name for playwright.capture")
+	har := fs.String("har", "", "HAR file name for playwright.capture")
+	waitFor := fs.String("wait-for", "", "CSS selector to wait for in playwright.capture")
+	viewport := fs.String("viewport", "", "viewport size for playwright.capture, e.g. 1920x1080")
 	jsonOutput := fs.Bool("json", false, "output as json")
+	file := fs.String("file", "", "enqueue every job in a JSONL or YAML file")
+	parallel := fs.Int("parallel", 8, "jobs dispatched at once with --file")
+	wait := fs.Bool("wait", false, "with --file, wait for every job and summarize pass/fail")
+	workflow := fs.String("workflow", "", "enqueue the multi-step workflow defined in a YAML file")
 
 	if err := fs.Parse(args); err != nil {
 		return jobEnqueueOptions{}, err
 	}
+	if err := checkNoArgs(fs); err != nil {
+		return jobEnqueueOptions{}, jobsUsageError{err: err}
+	}
+	if _, err := hatchet.RunPriority(*priority); err != nil {
+		return jobEnqueueOptions{}, jobsUsageError{err: err}
+	}
+
+	if *workflow != "" {
+		if *jobType != "" || *templateName != "" || *file != "" {
+			return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--workflow and --type/--template/--file are mutually exclusive")}
+		}
+		return jobEnqueueOptions{workflow: *workflow, traceID: ensureTraceID(*traceID), idempotencyKey: *idempotencyKey, priority: *priority, jsonOutput: *jsonOutput}, nil
+	}
+	if *file != "" {
+		if *jobType != "" || *templateName != "" {
+			return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--file and --type/--template are mutually exclusive")}
+		}
+		if *parallel < 1 {
+			return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--parallel must be positive")}
+		}
+		if *idempotencyKey != "" && *idempotencyKey != hatchet.IdempotencyKeyAuto {
+			return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--file takes only --idempotency-key auto; set idempotency_key per job in the file")}
+		}
+		return jobEnqueueOptions{file: *file, parallel: *parallel, wait: *wait, timeout: *timeout, idempotencyKey: *idempotencyKey, priority: *priority, jsonOutput: *jsonOutput}, nil
+	} else if *wait {
+		return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--wait requires --file; use jobs wait for a single job")}
+	}
+
+	if *templateName != "" {
+		rendered, err := renderTemplate(cfg, *templateName, params)
+		if err != nil {
+			return jobEnqueueOptions{}, jobsUsageError{err: err}
+		}
+		if *jobType != "" && *jobType != rendered.Type {
+			return jobEnqueueOptions{}, jobsUsageError{err: fmt.Errorf("--type %s does not match template type %s", *jobType, rendered.Type)}
+		}
+		if *cmd != "" || *task != "" {
+			return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--cmd/--task and --template are mutually exclusive")}
+		}
+		set := flagsSet(fs)
+		*jobType = rendered.Type
+		*cmd = rendered.Command
+		*task = rendered.Task
+		if !set["idempotent-check"] {
+			*idempotentCheck = rendered.IdempotentCheck
+		}
+		if !set["verify"] {
+			*verifyScript = rendered.Verify
+		}
+		if !set["max-steps"] && rendered.MaxSteps > 0 {
+			*maxSteps = rendered.MaxSteps
+		}
+	} else if len(params) > 0 {
+		return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--param requires --template")}
+	}
 
 	parsedType, err := parseJobType(*jobType)
 	if err != nil {
 		return jobEnqueueOptions{}, err
 	}
+	if *verifyRetries < 0 || *taskRetries < 0 {
+		return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--verify-retries and --task-retries must not be negative")}
+	}
+	if *budget < 0 {
+		return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--budget must not be negative")}
+	}
+	if *retryMax < 0 || *retryBackoff < 0 {
+		return jobEnqueueOptions{}, jobsUsageError{err: errors.New("--retry-max and --retry-backoff must not be negative")}
+	}
 
 	return jobEnqueueOptions{
 		jobType:         parsedType,
 		cmd:             *cmd,
 		task:            *task,
 		timeout:         *timeout,
+		retryMax:        *retryMax,
+		retryBackoff:    *retryBackoff,
+		retryExitCodes:  *retryExitCodes,
+		idempotencyKey:  *idempotencyKey,
+		priority:        *priority,
 		maxSteps:        *maxSteps,
 		selectedScreen:  *selectedScreen,
 		traceID:         ensureTraceID(*traceID),
 		jsonOutput:      *jsonOutput,
 		idempotentCheck: *idempotentCheck,
+		interactive:     *interactive,
+		verify:          *verifyScript,
+		verifyRetries:   *verifyRetries,
+		verifyDelay:     *verifyDelay,
+		taskRetries:     *taskRetries,
+		budget:          *budget,
+		template:        *templateName,
+		script:          *script,
+		browser:         *browser,
+		policies:        policies,
+		profile:         *profile,
+		url:             *pageURL,
+		screenshot:      *screenshot,
+		pdf:             *pdf,
+		har:             *har,
+		waitFor:         *waitFor,
+		viewport:        *viewport,
 	}, nil
 }
 
@@ -264,7 +432,7 @@ func parseJobType(value string) (hatchet.JobType, error) {
 	}
 
 	switch hatchet.JobType(value) {
-	case hatchet.JobTypeWindowsExec, hatchet.JobTypeAlohaRun:
+	case hatchet.JobTypeWindowsExec, hatchet.JobTypeAlohaRun, hatchet.JobTypePlaywrightRun, hatchet.JobTypePlaywrightCapture, hatchet.JobTypeWorkflowRun:
 		return hatchet.JobType(value), nil
 	default:
 		return "", jobsUsageError{err: fmt.Errorf("unknown job type: %s", value)}
@@ -278,19 +446,128 @@ func ensureTraceID(traceID string) string {
 	return traceID
 }
 
-func enqueueJob(ctx context.Context, client *sdk.Client, opts jobEnqueueOptions) (jobOutput, error) {
+func enqueueJob(ctx context.Context, client *sdk.Client, keys hatchet.IdempotencyKeys, opts jobEnqueueOptions) (jobOutput, error) {
+	req, err := jobRequest(opts)
+	if err != nil {
+		return jobOutput{}, err
+	}
+	input, err := req.WorkflowInput()
+	if err != nil {
+		return jobOutput{}, err
+	}
+
+	return enqueueOnce(ctx, client, keys, jobDispatch{
+		workflowName:   string(req.Type),
+		input:          input,
+		traceID:        opts.traceID,
+		idempotencyKey: req.IdempotencyKey,
+		priority:       req.Priority,
+	})
+}
+
+// jobRequest builds a single job from the enqueue flags, with the timeout and
+// retry settings the worker applies to it and its queue priority.
+func jobRequest(opts jobEnqueueOptions) (hatchet.JobRequest, error) {
 	workflowName, input, err := buildWorkflowInput(opts)
+	if err != nil {
+		return hatchet.JobRequest{}, err
+	}
+	return hatchet.JobRequest{
+		Type:         hatchet.JobType(workflowName),
+		Payload:      input,
+		Timeout:      opts.timeout,
+		RetryMax:     opts.retryMax,
+		RetryBackoff: opts.retryBackoff,
+		Idempotent:   opts.retryExitCodes,
+		TraceID:      opts.traceID,
+
+		IdempotencyKey: opts.idempotencyKey,
+		Priority:       opts.priority,
+	}, nil
+}
+
+// jobDispatch is a run to enqueue: the task, its input and its run options.
+type jobDispatch struct {
+	workflowName string
+	input        any
+	traceID      string
+	// idempotencyKey may be "auto" until enqueueOnce derives it.
+	idempotencyKey string
+	priority       string
+}
+
+// enqueueOnce dispatches the job unless its idempotency key already enqueued
+// one within the dedup window that has not failed or been cancelled; that job
+// is returned instead. The key "auto" is derived from the type and input; an
+// empty key always dispatches. The store is locked from lookup to record, so
+// concurrent enqueues of one key dispatch once where the store supports it.
+func enqueueOnce(ctx context.Context, client *sdk.Client, keys hatchet.IdempotencyKeys, job jobDispatch) (jobOutput, error) {
+	key := job.idempotencyKey
+	if key == "" {
+		return dispatchJob(ctx, client, job)
+	}
+	if key == hatchet.IdempotencyKeyAuto {
+		derived, err := hatchet.DeriveIdempotencyKey(hatchet.JobType(job.workflowName), job.input)
+		if err != nil {
+			return jobOutput{}, err
+		}
+		key = derived
+		job.idempotencyKey = derived
+	}
+
+	unlock, err := keys.Lock(ctx)
+	if err != nil {
+		return jobOutput{}, fmt.Errorf("lock idempotency keys: %w", err)
+	}
+	defer unlock()
+
+	jobID, err := keys.Find(ctx, key)
+	if err != nil {
+		return jobOutput{}, fmt.Errorf("look up idempotency key: %w", err)
+	}
+	if jobID != "" {
+		details, err := client.Runs().Get(ctx, jobID)
+		if err != nil {
+			return jobOutput{}, fmt.Errorf("look up job %s for idempotency key: %w", jobID, err)
+		}
+		if state := jobState(details); state != "failed" && state != "cancelled" {
+			logx.Info("jobs", "enqueue", "already enqueued",
+				logx.Field{Key: "job_id", Value: jobID},
+				logx.Field{Key: "idempotency_key", Value: key},
+				logx.Field{Key: "state", Value: state},
+			)
+			return jobOutput{JobID: jobID, State: state, TraceID: traceIDFromInput(details.Run.Input), Existing: true}, nil
+		}
+	}
+
+	output, err := dispatchJob(ctx, client, job)
 	if err != nil {
 		return jobOutput{}, err
 	}
+	if err := keys.Record(ctx, key, output.JobID); err != nil {
+		logx.Error("jobs", "enqueue", "record idempotency key failed", err, logx.Field{Key: "job_id", Value: output.JobID})
+	}
+	return output, nil
+}
 
-	logx.Info("jobs", "enqueue", "dispatching", logx.Field{Key: "type", Value: workflowName})
-	runRef, err := client.RunNoWait(ctx, workflowName, input)
+func dispatchJob(ctx context.Context, client *sdk.Client, job jobDispatch) (jobOutput, error) {
+	priority, err := hatchet.RunPriority(job.priority)
+	if err != nil {
+		return jobOutput{}, err
+	}
+	logx.Info("jobs", "enqueue", "dispatching", logx.Field{Key: "type", Value: job.workflowName})
+	// The trace id is also run metadata so jobs list can filter on it server-side,
+	// and so is the idempotency key, for later enqueues to find the job.
+	metadata := map[string]string{"trace_id": job.traceID}
+	if job.idempotencyKey != "" {
+		metadata[hatchet.IdempotencyMetadataKey] = job.idempotencyKey
+	}
+	runRef, err := client.RunNoWait(ctx, job.workflowName, job.input, sdk.WithRunMetadata(metadata), sdk.WithRunPriority(priority))
 	if err != nil {
 		return jobOutput{}, err
 	}
 
-	return jobOutput{JobID: runRef.RunId, State: "queued", TraceID: opts.traceID}, nil
+	return jobOutput{JobID: runRef.RunId, State: "queued", TraceID: job.traceID}, nil
 }
 
 func buildWorkflowInput(opts jobEnqueueOptions) (string, any, error) {
@@ -301,8 +578,9 @@ func buildWorkflowInput(opts jobEnqueueOptions) (string, any, error) {
 		}
 		payload := windowsExecPayload{
 			WindowsExecInput: hatchet.WindowsExecInput{
-				Command: opts.cmd,
-				Timeout: opts.timeout,
+				Command:     opts.cmd,
+				Timeout:     opts.timeout,
+				Interactive: opts.interactive,
 			},
 			TraceID:         opts.traceID,
 			IdempotentCheck: opts.idempotentCheck,
@@ -312,45 +590,69 @@ func buildWorkflowInput(opts jobEnqueueOptions) (string, any, error) {
 		if strings.TrimSpace(opts.task) == "" {
 			return "", nil, jobsUsageError{err: errors.New("--task is required for aloha.run")}
 		}
+		input := hatchet.AlohaRunInput{
+			Task:          opts.task,
+			TraceID:       opts.traceID,
+			MaxSteps:      opts.maxSteps,
+			Verify:        opts.verify,
+			VerifyRetries: opts.verifyRetries,
+			VerifyDelay:   opts.verifyDelay,
+			TaskRetries:   opts.taskRetries,
+			Budget:        opts.budget,
+			Template:      opts.template,
+		}
+		// Numeric selectors keep the plain selected_screen contract; names and
+		// "primary" are resolved by the worker against the attached displays.
+		if n, err := strconv.Atoi(opts.selectedScreen); err == nil {
+			input.SelectedScreen = n
+		} else {
+			input.Screen = opts.selectedScreen
+		}
 		payload := alohaRunPayload{
-			AlohaRunInput: hatchet.AlohaRunInput{
-				Task:           opts.task,
-				SelectedScreen: opts.selectedScreen,
-				TraceID:        opts.traceID,
-				MaxSteps:       opts.maxSteps,
-			},
+			AlohaRunInput:   input,
 			IdempotentCheck: opts.idempotentCheck,
 		}
 		return string(opts.jobType), payload, nil
+	case hatchet.JobTypePlaywrightRun:
+		// The script travels in the payload: the worker may not share this filesystem.
+		input, err := playwrightRunInput(opts.script, opts.browser, opts.policies, opts.profile, opts.traceID)
+		if err != nil {
+			return "", nil, jobsUsageError{err: fmt.Errorf("%w for playwright.run", err)}
+		}
+		return string(opts.jobType), input, nil
+	case hatchet.JobTypePlaywrightCapture:
+		input, err := playwrightCaptureInput(opts.url, opts.screenshot, opts.pdf, opts.har, opts.waitFor, opts.viewport, opts.browser, opts.policies, opts.profile, opts.traceID)
+		if err != nil {
+			return "", nil, jobsUsageError{err: fmt.Errorf("%w for playwright.capture", err)}
+		}
+		return string(opts.jobType), input, nil
+	case hatchet.JobTypeWorkflowRun:
+		return "", nil, jobsUsageError{err: errors.New("use --workflow <file> to enqueue a workflow")}
 	default:
 		return "", nil, fmt.Errorf("unknown job type: %s", opts.jobType)
 	}
 }
 
-func watchJob(client *sdk.Client, jobID string, traceID string, timeout time.Duration) (jobOutput, error) {
-	watchCtx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		details, err := client.Runs().Get(watchCtx, jobID)
-		if err != nil {
-			return jobOutput{}, err
-		}
-		state := mapRunState(details.Run.Status)
-		result := jobOutput{JobID: jobID, State: state, TraceID: traceID}
-		if isTerminalState(state) {
-			return result, nil
-		}
+// jobState is the state of a job run: Hatchet's, except that a windows.exec
+// command that exited non-zero is failed although its run completed.
+func jobState(details *rest.V1WorkflowRunDetails) string {
+	state := mapRunState(details.Run.Status)
+	if state == "completed" && runExitCode(details) != 0 {
+		return "failed"
+	}
+	return state
+}
 
-		select {
-		case <-watchCtx.Done():
-			return jobOutput{}, watchCtx.Err()
-		case <-ticker.C:
+// runExitCode returns the non-zero exit code of a windows.exec run, else 0. Jobs
+// are single task workflows, so the task's type and output stand for the job's.
+func runExitCode(details *rest.V1WorkflowRunDetails) int {
+	if n := len(details.Tasks); n > 0 {
+		task := details.Tasks[n-1]
+		if task.WorkflowName != nil {
+			return hatchet.FailedExitCode(hatchet.JobType(*task.WorkflowName), task.Output)
 		}
 	}
+	return hatchet.FailedExitCode(hatchet.JobType(details.Run.DisplayName), details.Run.Output)
 }
 
 func mapRunState(status rest.V1TaskStatus) string {
@@ -414,5 +716,9 @@ func writeJobOutput(output jobOutput, jsonOutput bool) {
 		fmt.Println(string(data))
 		return
 	}
+	if output.Existing {
+		fmt.Printf("job_id=%s state=%s trace_id=%s existing=true\n", output.JobID, output.State, output.TraceID)
+		return
+	}
 	fmt.Printf("job_id=%s state=%s trace_id=%s\n", output.JobID, output.State, output.TraceID)
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+)
+
+// batchJobResult is what jobs enqueue --file reports for one entry.
+type batchJobResult struct {
+	Line    int    `json:"line"`
+	Type    string `json:"type"`
+	JobID   string `json:"job_id,omitempty"`
+	State   string `json:"state"`
+	TraceID string `json:"trace_id"`
+	Error   string `json:"error,omitempty"`
+	// Existing is set when the job's idempotency key matched an earlier job.
+	Existing bool `json:"existing,omitempty"`
+}
+
+// batchSummary aggregates the final states of a waited batch.
+type batchSummary struct {
+	Total         int `json:"total"`
+	Completed     int `json:"completed"`
+	Failed        int `json:"failed"`
+	Cancelled     int `json:"cancelled"`
+	Pending       int `json:"pending"`
+	EnqueueFailed int `json:"enqueue_failed"`
+}
+
+// enqueueJobFile validates every job in opts.file, then dispatches them over one
+// client with at most opts.parallel requests in flight. A large batch outlasts
+// the command's context, which main bounds by cfg.Timeout, so dispatch and wait
+// are detached from it and bounded by opts.timeout instead.
+func enqueueJobFile(ctx context.Context, cfg config.Config, opts jobEnqueueOptions) int {
+	data, err := os.ReadFile(opts.file)
+	if err != nil {
+		logx.Error("jobs", "enqueue", "read job file", err, logx.Field{Key: "file", Value: opts.file})
+		return 2
+	}
+	entries, err := hatchet.ParseJobFile(opts.file, data)
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			logx.Error("jobs", "enqueue", "invalid job", errors.New(line))
+		}
+		return 2
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "enqueue", "client init failed", err)
+		return 2
+	}
+
+	dispatchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), opts.timeout)
+	defer cancel()
+	keys := hatchet.NewIdempotencyKeys(cfg, client)
+	results := make([]batchJobResult, len(entries))
+	jobs := make([]jobDispatch, len(entries))
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		results[i] = batchJobResult{Line: entry.Line, Type: string(entry.Request.Type), TraceID: ensureTraceID(entry.Request.TraceID)}
+		entry.Request.TraceID = results[i].TraceID
+		jobs[i], errs[i] = batchJobDispatch(entry.Request, opts)
+	}
+	// Lines sharing an idempotency key enqueue once: later lines reuse the first
+	// line's job instead of racing it to the store.
+	first := firstWithKey(jobs, errs)
+
+	forEachParallel(len(entries), opts.parallel, func(i int) {
+		if first[i] != i {
+			return
+		}
+		err := errs[i]
+		var output jobOutput
+		if err == nil {
+			output, err = enqueueOnce(dispatchCtx, client, keys, jobs[i])
+		}
+		if err != nil {
+			results[i].State = "enqueue_failed"
+			results[i].Error = err.Error()
+			logx.Error("jobs", "enqueue", "failed", err, logx.Field{Key: "line", Value: entries[i].Line})
+			return
+		}
+		results[i].JobID = output.JobID
+		results[i].State = output.State
+		results[i].TraceID = output.TraceID
+		results[i].Existing = output.Existing
+	})
+	for i, j := range first {
+		if i == j {
+			continue
+		}
+		results[i].JobID = results[j].JobID
+		results[i].State = results[j].State
+		results[i].TraceID = results[j].TraceID
+		results[i].Error = results[j].Error
+		results[i].Existing = results[j].JobID != ""
+		logx.Info("jobs", "enqueue", "duplicate idempotency key",
+			logx.Field{Key: "line", Value: entries[i].Line},
+			logx.Field{Key: "same_as_line", Value: entries[j].Line},
+			logx.Field{Key: "idempotency_key", Value: jobs[i].idempotencyKey},
+		)
+	}
+
+	if opts.wait {
+		return waitJobFile(ctx, client, opts, results)
+	}
+
+	failed := 0
+	for _, result := range results {
+		writeBatchResult(result, opts.jsonOutput)
+		if result.JobID == "" {
+			failed++
+		}
+	}
+	logx.Info("jobs", "enqueue", "ok",
+		logx.Field{Key: "file", Value: opts.file},
+		logx.Field{Key: "jobs", Value: len(results)},
+		logx.Field{Key: "enqueue_failed", Value: failed},
+	)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// batchJobDispatch returns the dispatch for one job file entry, applying the
+// command's idempotency key and priority as defaults and deriving an "auto" key,
+// so that lines with equal keys can be found before anything is dispatched.
+func batchJobDispatch(req hatchet.JobRequest, opts jobEnqueueOptions) (jobDispatch, error) {
+	input, err := req.WorkflowInput()
+	if err != nil {
+		return jobDispatch{}, err
+	}
+	job := jobDispatch{
+		workflowName:   string(req.Type),
+		input:          input,
+		traceID:        req.TraceID,
+		idempotencyKey: req.IdempotencyKey,
+		priority:       req.Priority,
+	}
+	if job.idempotencyKey == "" {
+		job.idempotencyKey = opts.idempotencyKey
+	}
+	if job.priority == "" {
+		job.priority = opts.priority
+	}
+	if job.idempotencyKey == hatchet.IdempotencyKeyAuto {
+		job.idempotencyKey, err = hatchet.DeriveIdempotencyKey(req.Type, input)
+		if err != nil {
+			return jobDispatch{}, err
+		}
+	}
+	return job, nil
+}
+
+// firstWithKey returns, for every job, the index of the first job with the same
+// idempotency key, or its own index when it has no key or is the first.
+func firstWithKey(jobs []jobDispatch, errs []error) []int {
+	first := make([]int, len(jobs))
+	byKey := map[string]int{}
+	for i, job := range jobs {
+		first[i] = i
+		if errs[i] != nil || job.idempotencyKey == "" {
+			continue
+		}
+		if j, ok := byKey[job.idempotencyKey]; ok {
+			first[i] = j
+		} else {
+			byKey[job.idempotencyKey] = i
+		}
+	}
+	return first
+}
+
+// waitJobFile polls every enqueued job until all are terminal or opts.timeout
+// passes, then prints each final state and the summary. Polling alone keeps one
+// connection for the whole batch rather than an event stream per job.
+func waitJobFile(ctx context.Context, client *sdk.Client, opts jobEnqueueOptions, results []batchJobResult) int {
+	waitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), opts.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	err := hatchet.PollUntil(waitCtx, nil, func(ctx context.Context) (bool, error) {
+		pending := 0
+		forEachParallel(len(results), opts.parallel, func(i int) {
+			mu.Lock()
+			result := results[i]
+			mu.Unlock()
+			if result.JobID == "" || isTerminalState(result.State) {
+				return
+			}
+			details, err := getRun(ctx, client, result.JobID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logx.Warn("jobs", "wait", "status failed",
+					logx.Field{Key: "job_id", Value: result.JobID},
+					logx.Field{Key: "err", Value: err.Error()},
+				)
+				pending++
+				return
+			}
+			state := jobState(details)
+			if state != result.State {
+				logx.Info("jobs", "wait", "state", logx.Field{Key: "job_id", Value: result.JobID}, logx.Field{Key: "state", Value: state})
+			}
+			results[i].State = state
+			if details.Run.ErrorMessage != nil {
+				results[i].Error = *details.Run.ErrorMessage
+			} else if code := runExitCode(details); code != 0 {
+				results[i].Error = fmt.Sprintf("exit code %d", code)
+			}
+			if !isTerminalState(state) {
+				pending++
+			}
+		})
+		return pending == 0, nil
+	})
+
+	var summary batchSummary
+	for _, result := range results {
+		writeBatchResult(result, opts.jsonOutput)
+		summary.Total++
+		switch {
+		case result.JobID == "":
+			summary.EnqueueFailed++
+		case result.State == "completed":
+			summary.Completed++
+		case result.State == "failed":
+			summary.Failed++
+		case result.State == "cancelled":
+			summary.Cancelled++
+		default:
+			summary.Pending++
+		}
+	}
+	if opts.jsonOutput {
+		data, _ := json.Marshal(summary)
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("total=%d completed=%d failed=%d cancelled=%d pending=%d enqueue_failed=%d\n",
+			summary.Total, summary.Completed, summary.Failed, summary.Cancelled, summary.Pending, summary.EnqueueFailed)
+	}
+
+	fields := []logx.Field{
+		{Key: "file", Value: opts.file},
+		{Key: "jobs", Value: summary.Total},
+		{Key: "completed", Value: summary.Completed},
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logx.Error("jobs", "wait", "timeout", err, fields...)
+			return 4
+		}
+		logx.Error("jobs", "wait", "failed", err, fields...)
+		return 1
+	}
+	if summary.Completed != summary.Total {
+		logx.Error("jobs", "wait", "failed", fmt.Errorf("%d of %d jobs did not complete", summary.Total-summary.Completed, summary.Total), fields...)
+		return 1
+	}
+	logx.Info("jobs", "wait", "ok", fields...)
+	return 0
+}
+
+func writeBatchResult(result batchJobResult, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.Marshal(result)
+		fmt.Println(string(data))
+		return
+	}
+	line := fmt.Sprintf("line=%d type=%s job_id=%s state=%s trace_id=%s", result.Line, result.Type, result.JobID, result.State, result.TraceID)
+	if result.Existing {
+		line += " existing=true"
+	}
+	if result.Error != "" {
+		line += fmt.Sprintf(" error=%q", result.Error)
+	}
+	fmt.Println(line)
+}
+
+// forEachParallel calls fn for 0..n-1 with at most limit calls running at once.
+func forEachParallel(n, limit int, fn func(int)) {
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/hatchet"
+)
+
+func TestBatchJobDispatch(t *testing.T) {
+	opts := jobEnqueueOptions{idempotencyKey: hatchet.IdempotencyKeyAuto, priority: hatchet.PriorityHigh}
+	a, err := batchJobDispatch(hatchet.JobRequest{Type: hatchet.JobTypeWindowsExec, Payload: map[string]any{"command": "hostname"}, TraceID: "t-1"}, opts)
+	if err != nil {
+		t.Fatalf("batchJobDispatch() error = %v", err)
+	}
+	b, err := batchJobDispatch(hatchet.JobRequest{Type: hatchet.JobTypeWindowsExec, Payload: map[string]any{"command": "hostname"}, TraceID: "t-2"}, opts)
+	if err != nil {
+		t.Fatalf("batchJobDispatch() error = %v", err)
+	}
+	if a.idempotencyKey == hatchet.IdempotencyKeyAuto || a.idempotencyKey != b.idempotencyKey {
+		t.Errorf("keys = %q, %q; want equal derived keys", a.idempotencyKey, b.idempotencyKey)
+	}
+	if a.priority != hatchet.PriorityHigh || a.traceID != "t-1" {
+		t.Errorf("dispatch = %+v", a)
+	}
+
+	own, err := batchJobDispatch(hatchet.JobRequest{Type: hatchet.JobTypeWindowsExec, Payload: map[string]any{"command": "hostname"}, IdempotencyKey: "web-1", Priority: hatchet.PriorityLow}, opts)
+	if err != nil {
+		t.Fatalf("batchJobDispatch() error = %v", err)
+	}
+	if own.idempotencyKey != "web-1" || own.priority != hatchet.PriorityLow {
+		t.Errorf("dispatch = %+v, want the line's own key and priority", own)
+	}
+}
+
+func TestFirstWithKey(t *testing.T) {
+	jobs := []jobDispatch{
+		{idempotencyKey: "a"},
+		{},
+		{idempotencyKey: "b"},
+		{idempotencyKey: "a"},
+		{},
+		{idempotencyKey: "b"},
+		{idempotencyKey: "a"},
+	}
+	errs := make([]error, len(jobs))
+	errs[6] = errors.New("invalid payload")
+	if got, want := firstWithKey(jobs, errs), []int{0, 1, 2, 0, 4, 2, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("firstWithKey() = %v, want %v", got, want)
+	}
+}
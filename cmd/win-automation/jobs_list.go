@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// jobSummary is one row of jobs list.
+type jobSummary struct {
+	JobID      string     `json:"job_id"`
+	Type       string     `json:"type"`
+	State      string     `json:"state"`
+	TraceID    string     `json:"trace_id"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int        `json:"duration_ms,omitempty"`
+}
+
+func cmdJobsList(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	states := fs.String("state", "", "comma-separated states: queued, running, completed, failed or cancelled")
+	jobType := fs.String("type", "", "only jobs of this type, e.g. windows.exec")
+	since := fs.Duration("since", 24*time.Hour, "only jobs created within this window")
+	traceID := fs.String("trace-id", "", "only jobs with this trace id")
+	limit := fs.Int("limit", 50, "maximum jobs to show")
+	offset := fs.Int("offset", 0, "skip this many jobs (for paging)")
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	params, wanted, err := jobsListParams(*states, *since, *traceID, *limit, *offset)
+	if err != nil {
+		logx.Error("jobs", "list", "invalid args", err)
+		return 2
+	}
+	if *jobType != "" {
+		if _, err := parseJobType(*jobType); err != nil {
+			logx.Error("jobs", "list", "invalid args", err)
+			return 2
+		}
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "list", "client init failed", err)
+		return 2
+	}
+
+	if *jobType != "" {
+		workflowID, err := client.Workflows().GetId(ctx, *jobType)
+		if err != nil {
+			logx.Error("jobs", "list", "resolve type failed", err, logx.Field{Key: "type", Value: *jobType})
+			return 1
+		}
+		params.WorkflowIds = &[]openapi_types.UUID{workflowID}
+	}
+
+	jobs, err := listJobs(ctx, client.Runs().List, params, wanted)
+	if err != nil {
+		logx.Error("jobs", "list", "failed", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		data, err := json.Marshal(jobs)
+		if err != nil {
+			logx.Error("jobs", "list", "marshal", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, job := range jobs {
+			fmt.Printf("job_id=%s type=%s state=%s trace_id=%s started_at=%s finished_at=%s duration=%s\n",
+				job.JobID, job.Type, job.State, job.TraceID,
+				formatJobTime(job.StartedAt), formatJobTime(job.FinishedAt),
+				time.Duration(job.DurationMs)*time.Millisecond,
+			)
+		}
+	}
+	logx.Info("jobs", "list", "ok", logx.Field{Key: "jobs", Value: len(jobs)})
+	return 0
+}
+
+// jobsListPage is how many runs jobs list fetches per request when it filters
+// states itself.
+const jobsListPage = 100
+
+// listJobs returns the jobs params selects. With states, the filter applies to
+// the job state rather than Hatchet's (a windows.exec command that exited
+// non-zero is failed, though its run completed), so runs are fetched page by
+// page and params' limit and offset count matching jobs.
+func listJobs(ctx context.Context, list func(context.Context, rest.V1WorkflowRunListParams) (*rest.V1TaskSummaryList, error), params rest.V1WorkflowRunListParams, states map[string]bool) ([]jobSummary, error) {
+	jobs := []jobSummary{}
+	if len(states) == 0 {
+		runs, err := list(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range runs.Rows {
+			jobs = append(jobs, summarizeRun(row))
+		}
+		return jobs, nil
+	}
+
+	limit, skip := int(*params.Limit), int(*params.Offset)
+	pageSize := int64(jobsListPage)
+	params.Limit = &pageSize
+	for from := int64(0); ; from += pageSize {
+		offset := from
+		params.Offset = &offset
+		runs, err := list(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range runs.Rows {
+			job := summarizeRun(row)
+			if !states[job.State] {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			jobs = append(jobs, job)
+			if len(jobs) == limit {
+				return jobs, nil
+			}
+		}
+		if len(runs.Rows) < jobsListPage {
+			return jobs, nil
+		}
+	}
+}
+
+// jobsListParams builds the runs list query and returns the requested states,
+// which listJobs matches. Trace ids are matched through the trace_id run
+// metadata set by jobs enqueue.
+func jobsListParams(states string, since time.Duration, traceID string, limit, offset int) (rest.V1WorkflowRunListParams, map[string]bool, error) {
+	if since <= 0 {
+		return rest.V1WorkflowRunListParams{}, nil, errors.New("--since must be positive")
+	}
+	if limit < 1 || offset < 0 {
+		return rest.V1WorkflowRunListParams{}, nil, errors.New("--limit must be positive and --offset not negative")
+	}
+	limit64, offset64 := int64(limit), int64(offset)
+	params := rest.V1WorkflowRunListParams{
+		Since:  time.Now().Add(-since),
+		Limit:  &limit64,
+		Offset: &offset64,
+	}
+	var wanted map[string]bool
+	if states != "" {
+		wanted = map[string]bool{}
+		var statuses []rest.V1TaskStatus
+		add := func(status rest.V1TaskStatus) {
+			if !slices.Contains(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+		for _, state := range strings.Split(states, ",") {
+			state = strings.TrimSpace(state)
+			status, err := parseRunState(state)
+			if err != nil {
+				return rest.V1WorkflowRunListParams{}, nil, err
+			}
+			wanted[state] = true
+			add(status)
+			// Failed commands complete their run in Hatchet.
+			if status == rest.V1TaskStatusFAILED {
+				add(rest.V1TaskStatusCOMPLETED)
+			}
+		}
+		params.Statuses = &statuses
+	}
+	if traceID != "" {
+		params.AdditionalMetadata = &[]string{"trace_id:" + traceID}
+	}
+	return params, wanted, nil
+}
+
+// parseRunState is the inverse of mapRunState.
+func parseRunState(state string) (rest.V1TaskStatus, error) {
+	for _, status := range []rest.V1TaskStatus{
+		rest.V1TaskStatusQUEUED,
+		rest.V1TaskStatusRUNNING,
+		rest.V1TaskStatusCOMPLETED,
+		rest.V1TaskStatusFAILED,
+		rest.V1TaskStatusCANCELLED,
+	} {
+		if mapRunState(status) == state {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown state %q (want queued, running, completed, failed or cancelled)", state)
+}
+
+func summarizeRun(row rest.V1TaskSummary) jobSummary {
+	job := jobSummary{
+		JobID:      row.WorkflowRunExternalId.String(),
+		Type:       row.DisplayName,
+		State:      mapRunState(row.Status),
+		TraceID:    traceIDFromInput(map[string]interface{}(row.Input)),
+		StartedAt:  row.StartedAt,
+		FinishedAt: row.FinishedAt,
+	}
+	if row.WorkflowName != nil {
+		job.Type = *row.WorkflowName
+	}
+	if job.State == "completed" && hatchet.FailedExitCode(hatchet.JobType(job.Type), row.Output) != 0 {
+		job.State = "failed"
+	}
+	if job.TraceID == "" && row.AdditionalMetadata != nil {
+		job.TraceID, _ = (*row.AdditionalMetadata)["trace_id"].(string)
+	}
+	if row.Duration != nil {
+		job.DurationMs = *row.Duration
+	}
+	return job
+}
+
+func formatJobTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
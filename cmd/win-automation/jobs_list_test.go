@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+)
+
+// execRow is a windows.exec run that Hatchet reports with status and output.
+func execRow(status rest.V1TaskStatus, output map[string]interface{}) rest.V1TaskSummary {
+	name := "windows.exec"
+	return rest.V1TaskSummary{WorkflowRunExternalId: uuid.New(), WorkflowName: &name, Status: status, Output: output}
+}
+
+// stubRunsList serves rows in pages, recording the statuses it was asked for.
+func stubRunsList(rows []rest.V1TaskSummary, statuses *[]rest.V1TaskStatus) func(context.Context, rest.V1WorkflowRunListParams) (*rest.V1TaskSummaryList, error) {
+	return func(_ context.Context, params rest.V1WorkflowRunListParams) (*rest.V1TaskSummaryList, error) {
+		if params.Statuses != nil {
+			*statuses = *params.Statuses
+		}
+		from := min(int(*params.Offset), len(rows))
+		to := min(from+int(*params.Limit), len(rows))
+		return &rest.V1TaskSummaryList{Rows: rows[from:to]}, nil
+	}
+}
+
+func TestListJobs_FiltersOnJobState(t *testing.T) {
+	exited := execRow(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"exit_code": float64(3)})
+	ok := execRow(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"exit_code": float64(0)})
+	failed := execRow(rest.V1TaskStatusFAILED, nil)
+	rows := []rest.V1TaskSummary{exited, ok, failed}
+
+	tests := []struct {
+		states       string
+		limit        int
+		offset       int
+		want         []rest.V1TaskSummary
+		wantStatuses []rest.V1TaskStatus
+	}{
+		{"failed", 50, 0, []rest.V1TaskSummary{exited, failed}, []rest.V1TaskStatus{rest.V1TaskStatusFAILED, rest.V1TaskStatusCOMPLETED}},
+		{"completed", 50, 0, []rest.V1TaskSummary{ok}, []rest.V1TaskStatus{rest.V1TaskStatusCOMPLETED}},
+		{"completed,failed", 50, 0, rows, []rest.V1TaskStatus{rest.V1TaskStatusCOMPLETED, rest.V1TaskStatusFAILED}},
+		{"failed", 1, 1, []rest.V1TaskSummary{failed}, []rest.V1TaskStatus{rest.V1TaskStatusFAILED, rest.V1TaskStatusCOMPLETED}},
+	}
+	for _, tt := range tests {
+		params, wanted, err := jobsListParams(tt.states, time.Hour, "", tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("jobsListParams(%q) error = %v", tt.states, err)
+		}
+		var statuses []rest.V1TaskStatus
+		jobs, err := listJobs(context.Background(), stubRunsList(rows, &statuses), params, wanted)
+		if err != nil {
+			t.Fatalf("listJobs(%q) error = %v", tt.states, err)
+		}
+		var got, want []string
+		for _, job := range jobs {
+			got = append(got, job.JobID)
+		}
+		for _, row := range tt.want {
+			want = append(want, row.WorkflowRunExternalId.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("listJobs(%q, limit %d, offset %d) = %v, want %v", tt.states, tt.limit, tt.offset, got, want)
+		}
+		if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+			t.Errorf("server statuses for %q = %v, want %v", tt.states, statuses, tt.wantStatuses)
+		}
+	}
+}
+
+func TestListJobs_PagesPastFilteredRuns(t *testing.T) {
+	var rows []rest.V1TaskSummary
+	for range jobsListPage + 5 {
+		rows = append(rows, execRow(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"exit_code": float64(0)}))
+	}
+	exited := execRow(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"exit_code": float64(1)})
+	rows = append(rows, exited)
+
+	params, wanted, err := jobsListParams("failed", time.Hour, "", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []rest.V1TaskStatus
+	jobs, err := listJobs(context.Background(), stubRunsList(rows, &statuses), params, wanted)
+	if err != nil {
+		t.Fatalf("listJobs() error = %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != exited.WorkflowRunExternalId.String() || jobs[0].State != "failed" {
+		t.Errorf("listJobs() = %+v, want only the command that exited 1", jobs)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+)
+
+// jobDetail is what jobs status --detail and jobs result report about a run.
+type jobDetail struct {
+	JobID       string          `json:"job_id"`
+	Type        string          `json:"type"`
+	State       string          `json:"state"`
+	TraceID     string          `json:"trace_id"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts,omitempty"`
+	QueuedAt    *time.Time      `json:"queued_at,omitempty"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	DurationMs  int             `json:"duration_ms,omitempty"`
+	ArtifactDir string          `json:"artifact_dir,omitempty"`
+	Output      json.RawMessage `json:"output,omitempty"`
+}
+
+// jobDetailFromRun collects the run's timings, error and output. Jobs are single
+// task workflows, so the task's attempts and output stand for the job's.
+func jobDetailFromRun(cfg config.Config, jobID string, details *rest.V1WorkflowRunDetails) jobDetail {
+	run := details.Run
+	detail := jobDetail{
+		JobID:      jobID,
+		Type:       run.DisplayName,
+		State:      jobState(details),
+		TraceID:    traceIDFromInput(map[string]interface{}(run.Input)),
+		QueuedAt:   run.CreatedAt,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+	}
+	if run.ErrorMessage != nil {
+		detail.Error = *run.ErrorMessage
+	}
+	if run.Duration != nil {
+		detail.DurationMs = *run.Duration
+	}
+	output := map[string]interface{}(run.Output)
+	if n := len(details.Tasks); n > 0 {
+		task := details.Tasks[n-1]
+		if task.WorkflowName != nil {
+			detail.Type = *task.WorkflowName
+		}
+		detail.Attempts = 1
+		if task.RetryCount != nil {
+			detail.Attempts += *task.RetryCount
+		}
+		if detail.Error == "" && task.ErrorMessage != nil {
+			detail.Error = *task.ErrorMessage
+		}
+		if len(task.Output) > 0 {
+			output = task.Output
+		}
+	}
+	if detail.Error == "" {
+		if code := runExitCode(details); code != 0 {
+			detail.Error = fmt.Sprintf("exit code %d", code)
+		}
+	}
+	if len(output) > 0 {
+		detail.Output, _ = json.Marshal(output)
+	}
+	if root := artifactRoot(cfg, jobID); dirExists(root) {
+		detail.ArtifactDir = root
+	}
+	return detail
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func writeJobDetail(detail jobDetail, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.Marshal(detail)
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Printf("job_id=%s type=%s state=%s trace_id=%s\n", detail.JobID, detail.Type, detail.State, detail.TraceID)
+	fmt.Printf("attempts=%d queued_at=%s started_at=%s finished_at=%s duration=%s\n",
+		detail.Attempts, formatJobTime(detail.QueuedAt), formatJobTime(detail.StartedAt),
+		formatJobTime(detail.FinishedAt), time.Duration(detail.DurationMs)*time.Millisecond)
+	if detail.Error != "" {
+		fmt.Printf("error=%q\n", detail.Error)
+	}
+	if detail.ArtifactDir != "" {
+		fmt.Printf("artifacts=%s\n", detail.ArtifactDir)
+	}
+	if len(detail.Output) > 0 {
+		fmt.Printf("output=%s\n", detail.Output)
+	}
+}
+
+// cmdJobsResult prints a finished job's decoded output: remote stdout and stderr
+// for windows.exec (exiting with the remote exit code, see remoteExitCode), the raw response for
+// aloha.run, the produced files for playwright jobs and the steps of a
+// workflow.run.
+func cmdJobsResult(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs result", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jobID := fs.String("id", "", "workflow run id (required)")
+	jsonOutput := fs.Bool("json", false, "output the job detail as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if strings.TrimSpace(*jobID) == "" {
+		logx.Error("jobs", "result", "missing id", errors.New("--id is required"))
+		return 2
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "result", "client init failed", err)
+		return 2
+	}
+
+	details, err := client.Runs().Get(ctx, *jobID)
+	if err != nil {
+		logx.Error("jobs", "result", "failed", err)
+		return 1
+	}
+	detail := jobDetailFromRun(cfg, *jobID, details)
+	fields := []logx.Field{
+		{Key: "job_id", Value: detail.JobID},
+		{Key: "state", Value: detail.State},
+		{Key: "attempts", Value: detail.Attempts},
+		{Key: "started_at", Value: formatJobTime(detail.StartedAt)},
+		{Key: "finished_at", Value: formatJobTime(detail.FinishedAt)},
+		{Key: "duration_ms", Value: detail.DurationMs},
+	}
+	if detail.ArtifactDir != "" {
+		fields = append(fields, logx.Field{Key: "artifacts", Value: detail.ArtifactDir})
+	}
+	if !isTerminalState(detail.State) {
+		logx.Error("jobs", "result", "not finished", fmt.Errorf("job is %s", detail.State), fields...)
+		return 1
+	}
+
+	if *jsonOutput {
+		writeJobDetail(detail, true)
+	} else if err := printJobOutput(cfg, detail); err != nil {
+		logx.Error("jobs", "result", "decode output", err, fields...)
+		return 1
+	}
+
+	if detail.Type == string(hatchet.JobTypeWindowsExec) && len(detail.Output) > 0 {
+		var output hatchet.WindowsExecOutput
+		if err := json.Unmarshal(detail.Output, &output); err == nil && output.ExitCode != 0 {
+			logx.Error("jobs", "result", "remote command failed", fmt.Errorf("exit code %d", output.ExitCode), fields...)
+			return remoteExitCode(output.ExitCode)
+		}
+	}
+	if detail.State != "completed" {
+		logx.Error("jobs", "result", detail.State, errors.New(detail.Error), fields...)
+		return 1
+	}
+	logx.Info("jobs", "result", "ok", fields...)
+	return 0
+}
+
+// remoteExitCode maps a windows.exec exit code to the one jobs result exits with.
+// Codes outside 1..255 would wrap (256 becomes 0), and 2 and 4 are the CLI's own
+// usage and timeout codes, so those exit 1; the remote code is logged either way.
+func remoteExitCode(code int) int {
+	if code < 1 || code > 255 || code == 2 || code == 4 {
+		return 1
+	}
+	return code
+}
+
+// printJobOutput writes the job's output in the form its type calls for.
+func printJobOutput(cfg config.Config, detail jobDetail) error {
+	if len(detail.Output) == 0 {
+		return nil
+	}
+	switch hatchet.JobType(detail.Type) {
+	case hatchet.JobTypeWindowsExec:
+		var output hatchet.WindowsExecOutput
+		if err := json.Unmarshal(detail.Output, &output); err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stdout, output.Stdout)
+		fmt.Fprint(os.Stderr, output.Stderr)
+	case hatchet.JobTypeAlohaRun:
+		var output hatchet.AlohaRunOutput
+		if err := json.Unmarshal(detail.Output, &output); err != nil {
+			return err
+		}
+		fmt.Println(output.Raw)
+	case hatchet.JobTypePlaywrightRun, hatchet.JobTypePlaywrightCapture:
+		var output hatchet.PlaywrightRunOutput
+		if err := json.Unmarshal(detail.Output, &output); err != nil {
+			return err
+		}
+		for _, file := range output.Files {
+			fmt.Println(filepath.Join(output.ArtifactDir, file))
+		}
+	case hatchet.JobTypeWorkflowRun:
+		return printWorkflowOutput(cfg, detail.Output)
+	default:
+		fmt.Println(string(detail.Output))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+)
+
+func execRunDetails(status rest.V1TaskStatus, output map[string]interface{}) *rest.V1WorkflowRunDetails {
+	name := "windows.exec"
+	details := &rest.V1WorkflowRunDetails{
+		Run:   rest.V1WorkflowRun{DisplayName: "windows.exec-abc", Status: status, Input: map[string]interface{}{"trace_id": "t-1"}},
+		Tasks: []rest.V1TaskSummary{{WorkflowName: &name, Status: status, Output: output}},
+	}
+	return details
+}
+
+func TestJobDetailFromRun_CommandExitedNonZero(t *testing.T) {
+	details := execRunDetails(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"stdout": "partial\n", "stderr": "boom\n", "exit_code": float64(3)})
+	detail := jobDetailFromRun(config.Config{}, "run-1", details)
+	if detail.State != "failed" || detail.Error != "exit code 3" {
+		t.Errorf("state = %q, error = %q, want failed, exit code 3", detail.State, detail.Error)
+	}
+	if detail.Type != "windows.exec" || detail.TraceID != "t-1" || detail.Attempts != 1 {
+		t.Errorf("detail = %+v", detail)
+	}
+	var output map[string]any
+	if err := json.Unmarshal(detail.Output, &output); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if output["stdout"] != "partial\n" || output["exit_code"] != float64(3) {
+		t.Errorf("output = %v, want the command's stdout and exit code", output)
+	}
+}
+
+func TestJobDetailFromRun_Completed(t *testing.T) {
+	details := execRunDetails(rest.V1TaskStatusCOMPLETED, map[string]interface{}{"stdout": "ok\n", "exit_code": float64(0)})
+	detail := jobDetailFromRun(config.Config{}, "run-1", details)
+	if detail.State != "completed" || detail.Error != "" {
+		t.Errorf("state = %q, error = %q, want completed without error", detail.State, detail.Error)
+	}
+}
+
+func TestJobDetailFromRun_Failed(t *testing.T) {
+	details := execRunDetails(rest.V1TaskStatusFAILED, nil)
+	msg := "ssh command failed (exit 255)"
+	details.Run.ErrorMessage = &msg
+	detail := jobDetailFromRun(config.Config{}, "run-1", details)
+	if detail.State != "failed" || detail.Error != msg || len(detail.Output) != 0 {
+		t.Errorf("detail = %+v, want failed with the run's error and no output", detail)
+	}
+}
+
+// captureOutput returns what fn writes to stdout and stderr.
+func captureOutput(t *testing.T, fn func()) (string, string) {
+	t.Helper()
+	read := func(target **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *target
+		*target = w
+		done := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			done <- string(data)
+		}()
+		return func() string {
+			*target = orig
+			w.Close()
+			return <-done
+		}
+	}
+	stdout, stderr := read(&os.Stdout), read(&os.Stderr)
+	fn()
+	return stdout(), stderr()
+}
+
+func TestPrintJobOutput_WindowsExec(t *testing.T) {
+	detail := jobDetail{Type: "windows.exec", Output: json.RawMessage(`{"stdout":"partial\n","stderr":"boom\n","exit_code":3}`)}
+	var err error
+	stdout, stderr := captureOutput(t, func() { err = printJobOutput(config.Config{}, detail) })
+	if err != nil {
+		t.Fatalf("printJobOutput() error = %v", err)
+	}
+	if stdout != "partial\n" || stderr != "boom\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestPrintJobOutput_Playwright(t *testing.T) {
+	detail := jobDetail{Type: "playwright.run", Output: json.RawMessage(`{"artifact_dir":"/tmp/a","files":["shot.png"]}`)}
+	stdout, _ := captureOutput(t, func() {
+		if err := printJobOutput(config.Config{}, detail); err != nil {
+			t.Errorf("printJobOutput() error = %v", err)
+		}
+	})
+	if stdout != "/tmp/a/shot.png\n" {
+		t.Errorf("stdout = %q, want the artifact path", stdout)
+	}
+}
+
+func TestRemoteExitCode(t *testing.T) {
+	for code, want := range map[int]int{1: 1, 3: 3, 255: 255, 2: 1, 4: 1, 0: 1, 256: 1, -1: 1, 1000: 1} {
+		if got := remoteExitCode(code); got != want {
+			t.Errorf("remoteExitCode(%d) = %d, want %d", code, got, want)
+		}
+	}
+}
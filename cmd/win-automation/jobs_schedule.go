@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+type scheduleOutput struct {
+	Name    string          `json:"name"`
+	ID      string          `json:"id,omitempty"`
+	Cron    string          `json:"cron"`
+	Type    hatchet.JobType `json:"type"`
+	Overlap string          `json:"overlap"`
+	State   string          `json:"state"`
+	NextRun *time.Time      `json:"next_run,omitempty"`
+}
+
+func cmdJobsSchedule(ctx context.Context, cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		logx.Error("jobs", "schedule", "missing subcommand", errors.New("missing subcommand: create, list, delete, pause or resume"))
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return cmdJobsScheduleCreate(ctx, cfg, args[1:])
+	case "list":
+		return cmdJobsScheduleList(ctx, cfg, args[1:])
+	case "delete":
+		return cmdJobsScheduleUpdate(ctx, cfg, "delete", args[1:])
+	case "pause":
+		return cmdJobsScheduleUpdate(ctx, cfg, "pause", args[1:])
+	case "resume":
+		return cmdJobsScheduleUpdate(ctx, cfg, "resume", args[1:])
+	default:
+		logx.Error("jobs", "schedule", "unknown subcommand", fmt.Errorf("%s", args[0]))
+		return 2
+	}
+}
+
+func cmdJobsScheduleCreate(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs schedule create", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	name := fs.String("name", "", "schedule name (required)")
+	cronExpr := fs.String("cron", "", "five-field cron expression, e.g. \"0 2 * * *\" (required)")
+	overlap := fs.String("overlap", hatchet.OverlapSkip, "while the previous job runs: skip, queue or cancel-previous")
+	paused := fs.Bool("paused", false, "create the schedule paused")
+	opts, err := parseJobsEnqueueFlags(fs, cfg, args)
+	if err == nil {
+		err = checkScheduleFlags(*name, *cronExpr, opts)
+	}
+	if err != nil {
+		logx.Error("jobs", "schedule", "invalid args", err)
+		return 2
+	}
+
+	job, err := scheduleJob(cfg, opts)
+	if err != nil {
+		logx.Error("jobs", "schedule", "invalid args", err)
+		return 2
+	}
+	schedule := hatchet.Schedule{
+		Name:    *name,
+		Cron:    *cronExpr,
+		Overlap: *overlap,
+		Job:     job,
+		Created: time.Now().UTC(),
+		Paused:  *paused,
+	}
+	if err := schedule.Validate(); err != nil {
+		logx.Error("jobs", "schedule", "invalid args", err)
+		return 2
+	}
+
+	store, err := hatchet.NewScheduleStore(cfg)
+	if err != nil {
+		logx.Error("jobs", "schedule", "client init failed", err)
+		return 2
+	}
+	created, err := store.Create(ctx, schedule)
+	if err != nil {
+		logx.Error("jobs", "schedule", "create failed", err, logx.Field{Key: "name", Value: schedule.Name})
+		return 1
+	}
+	writeSchedule(created, opts.jsonOutput)
+	logx.Info("jobs", "schedule", "created",
+		logx.Field{Key: "name", Value: created.Name},
+		logx.Field{Key: "cron", Value: created.Cron},
+		logx.Field{Key: "scheduler", Value: cfg.HatchetScheduler},
+	)
+	return 0
+}
+
+func checkScheduleFlags(name, cronExpr string, opts jobEnqueueOptions) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(cronExpr) == "" {
+		return errors.New("--name and --cron are required")
+	}
+	if opts.file != "" {
+		return errors.New("--file cannot be scheduled; schedule each job or use --workflow")
+	}
+	if opts.idempotencyKey != "" {
+		return errors.New("--idempotency-key cannot be scheduled; every tick enqueues a new job")
+	}
+	return nil
+}
+
+// scheduleJob builds the job a schedule enqueues from the enqueue flags. Each
+// tick replaces the trace id with a fresh one.
+func scheduleJob(cfg config.Config, opts jobEnqueueOptions) (hatchet.JobRequest, error) {
+	if opts.workflow != "" {
+		def, err := loadWorkflow(cfg, opts.workflow)
+		if err != nil {
+			return hatchet.JobRequest{}, fmt.Errorf("%s: %w", opts.workflow, err)
+		}
+		return hatchet.JobRequest{Type: hatchet.JobTypeWorkflowRun, Payload: hatchet.WorkflowRunInput{Workflow: def}, Priority: opts.priority}, nil
+	}
+	job, err := jobRequest(opts)
+	if err != nil {
+		return hatchet.JobRequest{}, err
+	}
+	job.TraceID = ""
+	payload, err := job.WorkflowInput()
+	if err != nil {
+		return hatchet.JobRequest{}, err
+	}
+	delete(payload, "trace_id")
+	job.Payload = payload
+	return job, hatchet.ValidateJobPayload(job.Type, payload)
+}
+
+func cmdJobsScheduleList(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs schedule list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	store, err := hatchet.NewScheduleStore(cfg)
+	if err != nil {
+		logx.Error("jobs", "schedule", "client init failed", err)
+		return 2
+	}
+	schedules, err := store.List(ctx)
+	if err != nil {
+		logx.Error("jobs", "schedule", "list failed", err)
+		return 1
+	}
+	for _, schedule := range schedules {
+		writeSchedule(schedule, *jsonOutput)
+	}
+	logx.Info("jobs", "schedule", "listed", logx.Field{Key: "count", Value: len(schedules)}, logx.Field{Key: "scheduler", Value: cfg.HatchetScheduler})
+	return 0
+}
+
+// cmdJobsScheduleUpdate deletes, pauses or resumes the schedule named by --name.
+func cmdJobsScheduleUpdate(ctx context.Context, cfg config.Config, action string, args []string) int {
+	fs := flag.NewFlagSet("jobs schedule "+action, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	name := fs.String("name", "", "schedule name (required)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*name) == "" {
+		logx.Error("jobs", "schedule", "missing name", errors.New("--name is required"))
+		return 2
+	}
+
+	store, err := hatchet.NewScheduleStore(cfg)
+	if err != nil {
+		logx.Error("jobs", "schedule", "client init failed", err)
+		return 2
+	}
+	switch action {
+	case "delete":
+		err = store.Delete(ctx, *name)
+	case "pause":
+		err = store.SetPaused(ctx, *name, true)
+	case "resume":
+		err = store.SetPaused(ctx, *name, false)
+	}
+	if err != nil {
+		logx.Error("jobs", "schedule", action+" failed", err, logx.Field{Key: "name", Value: *name})
+		return 1
+	}
+	logx.Info("jobs", "schedule", action+" ok", logx.Field{Key: "name", Value: *name})
+	return 0
+}
+
+func writeSchedule(schedule hatchet.Schedule, jsonOutput bool) {
+	output := scheduleOutput{
+		Name:    schedule.Name,
+		ID:      schedule.ID,
+		Cron:    schedule.Cron,
+		Type:    schedule.Job.Type,
+		Overlap: schedule.Overlap,
+		State:   "active",
+	}
+	if schedule.Paused {
+		output.State = "paused"
+	} else if next := schedule.NextRun(time.Now().UTC()); !next.IsZero() {
+		output.NextRun = &next
+	}
+	if jsonOutput {
+		data, _ := json.Marshal(output)
+		fmt.Println(string(data))
+		return
+	}
+	line := fmt.Sprintf("name=%s cron=%q type=%s overlap=%s state=%s", output.Name, output.Cron, output.Type, output.Overlap, output.State)
+	if output.NextRun != nil {
+		line += " next_run=" + output.NextRun.UTC().Format(time.RFC3339)
+	}
+	if output.ID != "" {
+		line += " id=" + output.ID
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+)
+
+func cmdJobsWait(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs wait", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jobID := fs.String("id", "", "workflow run id (required)")
+	timeout := fs.Duration("timeout", cfg.HatchetJobTimeout, "how long to wait for the job to finish")
+	detailOutput := fs.Bool("detail", false, "print timings, error and output once finished")
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if strings.TrimSpace(*jobID) == "" {
+		logx.Error("jobs", "wait", "missing id", errors.New("--id is required"))
+		return 2
+	}
+	if *timeout <= 0 {
+		logx.Error("jobs", "wait", "invalid args", errors.New("--timeout must be positive"))
+		return 2
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "wait", "client init failed", err)
+		return 2
+	}
+
+	details, err := waitJob(ctx, cfg, client, *jobID, *timeout, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logx.Error("jobs", "wait", "timeout", err, logx.Field{Key: "job_id", Value: *jobID})
+			return 4
+		}
+		logx.Error("jobs", "wait", "failed", err, logx.Field{Key: "job_id", Value: *jobID})
+		return 1
+	}
+
+	state := jobState(details)
+	if *detailOutput {
+		writeJobDetail(jobDetailFromRun(cfg, *jobID, details), *jsonOutput)
+	} else {
+		traceID := traceIDFromInput(map[string]interface{}(details.Run.Input))
+		writeJobOutput(jobOutput{JobID: *jobID, State: state, TraceID: traceID}, *jsonOutput)
+	}
+	logx.Info("jobs", "wait", "ok", logx.Field{Key: "job_id", Value: *jobID}, logx.Field{Key: "state", Value: state})
+	return jobStateExitCode(state)
+}
+
+func cmdJobsLogs(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("jobs logs", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jobID := fs.String("id", "", "workflow run id (required)")
+	follow := fs.Bool("follow", false, "keep streaming until the job finishes")
+	timeout := fs.Duration("timeout", cfg.HatchetJobTimeout, "how long to follow before giving up")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if strings.TrimSpace(*jobID) == "" {
+		logx.Error("jobs", "logs", "missing id", errors.New("--id is required"))
+		return 2
+	}
+	if *timeout <= 0 {
+		logx.Error("jobs", "logs", "invalid args", errors.New("--timeout must be positive"))
+		return 2
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "logs", "client init failed", err)
+		return 2
+	}
+
+	printer := newJobLogPrinter(client)
+	var details *rest.V1WorkflowRunDetails
+	if *follow {
+		details, err = waitJob(ctx, cfg, client, *jobID, *timeout, printer.print)
+		if err == nil {
+			// Log lines can land after the run is marked finished; drain once more.
+			// The follow outlived the command's context, so the drain gets a fresh one.
+			drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Timeout)
+			err = printer.print(drainCtx, details)
+			cancel()
+		}
+	} else {
+		details, err = client.Runs().Get(ctx, *jobID)
+		if err == nil {
+			err = printer.print(ctx, details)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logx.Error("jobs", "logs", "timeout", err, logx.Field{Key: "job_id", Value: *jobID})
+			return 4
+		}
+		logx.Error("jobs", "logs", "failed", err, logx.Field{Key: "job_id", Value: *jobID})
+		return 1
+	}
+
+	state := jobState(details)
+	logx.Info("jobs", "logs", "ok", logx.Field{Key: "job_id", Value: *jobID}, logx.Field{Key: "state", Value: state})
+	return jobStateExitCode(state)
+}
+
+// Stubbed in tests.
+var (
+	getRun = func(ctx context.Context, client *sdk.Client, jobID string) (*rest.V1WorkflowRunDetails, error) {
+		return client.Runs().Get(ctx, jobID)
+	}
+	runEvents = hatchet.RunEvents
+)
+
+// waitJob blocks until the run reaches a terminal state or timeout passes. The
+// run's event stream wakes it promptly when available; otherwise it falls back
+// to backoff polling. onPoll, when set, sees every fetched state. The wait is
+// detached from ctx, which main bounds by cfg.Timeout, so timeout alone bounds it.
+func waitJob(ctx context.Context, cfg config.Config, client *sdk.Client, jobID string, timeout time.Duration, onPoll func(context.Context, *rest.V1WorkflowRunDetails) error) (*rest.V1WorkflowRunDetails, error) {
+	waitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	var last *rest.V1WorkflowRunDetails
+	lastState := ""
+	err := hatchet.PollUntil(waitCtx, runEvents(waitCtx, cfg, jobID), func(ctx context.Context) (bool, error) {
+		details, err := getRun(ctx, client, jobID)
+		if err != nil {
+			return false, err
+		}
+		last = details
+		state := jobState(details)
+		if state != lastState {
+			logx.Info("jobs", "wait", "state", logx.Field{Key: "job_id", Value: jobID}, logx.Field{Key: "state", Value: state})
+			lastState = state
+		}
+		if onPoll != nil {
+			if err := onPoll(ctx, details); err != nil {
+				return false, err
+			}
+		}
+		return isTerminalState(state), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// jobStateExitCode maps a job state to the exit code jobs wait and jobs logs
+// return: 1 when the job failed or was cancelled, 0 otherwise.
+func jobStateExitCode(state string) int {
+	switch state {
+	case "failed", "cancelled":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// jobLogPrinter prints a run's step events and task log lines, each once, so a
+// follow loop can hand it every poll.
+type jobLogPrinter struct {
+	client *sdk.Client
+	events map[int]bool
+	lines  map[string]bool
+	since  map[string]time.Time
+}
+
+func newJobLogPrinter(client *sdk.Client) *jobLogPrinter {
+	return &jobLogPrinter{
+		client: client,
+		events: map[int]bool{},
+		lines:  map[string]bool{},
+		since:  map[string]time.Time{},
+	}
+}
+
+func (p *jobLogPrinter) print(ctx context.Context, details *rest.V1WorkflowRunDetails) error {
+	for _, event := range details.TaskEvents {
+		if p.events[event.Id] {
+			continue
+		}
+		p.events[event.Id] = true
+		task := "-"
+		if event.TaskDisplayName != nil {
+			task = *event.TaskDisplayName
+		}
+		message := event.Message
+		if event.ErrorMessage != nil && *event.ErrorMessage != "" {
+			message = *event.ErrorMessage
+		}
+		fmt.Printf("%s event %s %s %s\n", event.Timestamp.UTC().Format(time.RFC3339), event.EventType, task, message)
+	}
+
+	order := rest.V1LogLineOrderByDirectionASC
+	for _, task := range details.Tasks {
+		taskID := task.TaskExternalId.String()
+		params := &rest.V1LogLineListParams{OrderByDirection: &order}
+		if since, ok := p.since[taskID]; ok {
+			params.Since = &since
+		}
+		lines, err := p.client.Logs().List(ctx, task.TaskExternalId, params)
+		if err != nil {
+			return fmt.Errorf("list logs for task %s: %w", task.DisplayName, err)
+		}
+		if lines.Rows == nil {
+			continue
+		}
+		for _, line := range *lines.Rows {
+			key := taskID + "|" + line.CreatedAt.String() + "|" + line.Message
+			if p.lines[key] {
+				continue
+			}
+			p.lines[key] = true
+			if line.CreatedAt.After(p.since[taskID]) {
+				p.since[taskID] = line.CreatedAt
+			}
+			level := "INFO"
+			if line.Level != nil {
+				level = string(*line.Level)
+			}
+			fmt.Printf("%s %s %s %s\n", line.CreatedAt.UTC().Format(time.RFC3339), level, task.DisplayName, line.Message)
+		}
+	}
+	return nil
+}
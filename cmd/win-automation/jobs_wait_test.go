@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+)
+
+// stubRun serves a run that is running until finishAfter has passed, then
+// completed, and wakes the wait every few milliseconds instead of polling.
+func stubRun(t *testing.T, finishAfter time.Duration) {
+	t.Helper()
+	origGet, origEvents := getRun, runEvents
+	start := time.Now()
+	getRun = func(ctx context.Context, _ *sdk.Client, _ string) (*rest.V1WorkflowRunDetails, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		status := rest.V1TaskStatusRUNNING
+		if time.Since(start) >= finishAfter {
+			status = rest.V1TaskStatusCOMPLETED
+		}
+		return &rest.V1WorkflowRunDetails{Run: rest.V1WorkflowRun{Status: status}}, nil
+	}
+	runEvents = func(ctx context.Context, _ config.Config, _ string) <-chan struct{} {
+		wake := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(5 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					select {
+					case wake <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}()
+		return wake
+	}
+	t.Cleanup(func() { getRun, runEvents = origGet, origEvents })
+}
+
+func TestWaitJob_OutlivesCommandContext(t *testing.T) {
+	stubRun(t, 150*time.Millisecond)
+	// main bounds every command's context by cfg.Timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	details, err := waitJob(ctx, config.Config{}, nil, "run-1", time.Minute, nil)
+	if err != nil {
+		t.Fatalf("waitJob() error = %v", err)
+	}
+	if state := jobState(details); state != "completed" {
+		t.Errorf("state = %q, want completed", state)
+	}
+}
+
+func TestWaitJob_Timeout(t *testing.T) {
+	stubRun(t, time.Hour)
+	_, err := waitJob(context.Background(), config.Config{}, nil, "run-1", 30*time.Millisecond, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waitJob() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestWaitJobFile_OutlivesCommandContext(t *testing.T) {
+	stubRun(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	results := []batchJobResult{{Line: 1, JobID: "run-1", State: "queued"}, {Line: 2, JobID: "run-2", State: "queued"}}
+	opts := jobEnqueueOptions{file: "jobs.jsonl", parallel: 2, timeout: time.Minute}
+	var exitCode int
+	stdout, _ := captureOutput(t, func() { exitCode = waitJobFile(ctx, nil, opts, results) })
+	if exitCode != 0 {
+		t.Fatalf("waitJobFile() exit code = %d, want 0\n%s", exitCode, stdout)
+	}
+	if !strings.Contains(stdout, "total=2 completed=2 ") {
+		t.Errorf("summary = %q, want both jobs completed", stdout)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// enqueueWorkflow validates the workflow in opts.workflow and enqueues it as one
+// workflow.run job; the worker runs each step as a child job.
+func enqueueWorkflow(ctx context.Context, cfg config.Config, opts jobEnqueueOptions) int {
+	def, err := loadWorkflow(cfg, opts.workflow)
+	if err != nil {
+		logx.Error("jobs", "enqueue", "invalid workflow", err, logx.Field{Key: "file", Value: opts.workflow})
+		return 2
+	}
+
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("jobs", "enqueue", "client init failed", err)
+		return 2
+	}
+
+	input := hatchet.WorkflowRunInput{Workflow: def, TraceID: opts.traceID}
+	result, err := enqueueOnce(ctx, client, hatchet.NewIdempotencyKeys(cfg, client), jobDispatch{
+		workflowName:   string(hatchet.JobTypeWorkflowRun),
+		input:          input,
+		traceID:        opts.traceID,
+		idempotencyKey: opts.idempotencyKey,
+		priority:       opts.priority,
+	})
+	if err != nil {
+		logx.Error("jobs", "enqueue", "failed", err)
+		return 1
+	}
+
+	writeJobOutput(result, opts.jsonOutput)
+	logx.Info("jobs", "enqueue", "ok",
+		logx.Field{Key: "job_id", Value: result.JobID},
+		logx.Field{Key: "workflow", Value: def.Name},
+		logx.Field{Key: "steps", Value: len(def.Steps)},
+		logx.Field{Key: "trace_id", Value: result.TraceID},
+	)
+	return 0
+}
+
+// loadWorkflow reads, parses and validates the workflow definition in path.
+func loadWorkflow(cfg config.Config, path string) (hatchet.WorkflowDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return hatchet.WorkflowDef{}, err
+	}
+	def, err := hatchet.ParseWorkflow(path, data)
+	if err != nil {
+		return hatchet.WorkflowDef{}, err
+	}
+	return def, def.Validate(cfg.HatchetJobTimeout)
+}
+
+// printWorkflowOutput prints one line per workflow step with the step's job id,
+// so its output and artifacts can be looked up with jobs result.
+func printWorkflowOutput(cfg config.Config, raw json.RawMessage) error {
+	var output hatchet.WorkflowRunOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return err
+	}
+	for _, step := range output.Steps {
+		line := fmt.Sprintf("step=%s type=%s state=%s job_id=%s attempts=%d duration=%s",
+			step.ID, step.Type, step.State, step.JobID, step.Attempts, step.Duration.Round(time.Millisecond))
+		if step.JobID != "" {
+			if root := artifactRoot(cfg, step.JobID); dirExists(root) {
+				line += " artifacts=" + root
+			}
+		}
+		if step.Error != "" {
+			line += fmt.Sprintf(" error=%q", step.Error)
+		}
+		if step.Reason != "" {
+			line += fmt.Sprintf(" reason=%q", step.Reason)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -2,19 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/artifacts"
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/desktop"
 	"github.com/alejg/win-automation/internal/hatchet"
 	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/playwright"
 	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/tasks"
+	"github.com/alejg/win-automation/internal/verify"
 	"github.com/alejg/win-automation/internal/win"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -70,6 +79,8 @@ func run(args []string) int {
 		}
 		return 2
 	}
+	logx.RegisterSecret(cfg.PlaywrightWSPath)
+	logx.RegisterSecret(cfg.HatchetToken)
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
@@ -91,6 +102,8 @@ func run(args []string) int {
 		return cmdArtifacts(ctx, cfg, remaining[1:])
 	case "supervisor":
 		return cmdSupervisor(ctx, cfg, remaining[1:])
+	case "tasks":
+		return cmdTasks(ctx, cfg, remaining[1:])
 	case "version":
 		fmt.Printf("version=%s\n", version)
 		return 0
@@ -141,14 +154,48 @@ func usage() {
 
 Usage:
   win-automation doctor
-  win-automation windows exec [--raw] -- <command...>
+  win-automation windows exec [--raw] [--interactive] -- <command...>
+  win-automation windows displays [--json]
   win-automation aloha health
-  win-automation aloha run --task <text> [--max-steps N] [--selected-screen N] [--trace-id ID]
+  win-automation aloha run --task <text> [--max-steps N] [--selected-screen N|primary|NAME] [--trace-id ID]
+                          [--verify <script>] [--verify-retries N] [--verify-delay 2s] [--task-retries N] [--budget 15m]
+  win-automation aloha run --template <name> [--param key=value ...]
+  win-automation aloha usage [--since 7d] [--json]
+  win-automation aloha install|repair|uninstall
   win-automation jobs enqueue --type <windows.exec|aloha.run> [--cmd <command>] [--task <text>] [--timeout <duration>]
-  win-automation jobs status --id <job-id>
+                              [--verify <script>] [--verify-retries N] [--verify-delay 2s] [--task-retries N] [--budget 15m]
+  win-automation jobs enqueue ... [--retry-max N] [--retry-backoff 5s] [--retry-exit-codes]
+  win-automation jobs enqueue ... [--idempotency-key <key>|auto] [--priority low|normal|high]
+  win-automation jobs enqueue --type playwright.run --script <flow.js> [--browser <name>] [--trace[=POLICY]]
+                              [--video=POLICY] [--screenshot-policy=POLICY] [--profile <name>]
+  win-automation jobs enqueue --type playwright.capture --url <url> [--screenshot <name>] [--pdf <name>] [--har <name>]
+  win-automation jobs enqueue --template <name> [--param key=value ...]
+  win-automation jobs enqueue --file <jobs.jsonl|jobs.yaml> [--parallel 8] [--wait [--timeout <duration>]] [--json]
+  win-automation jobs enqueue --workflow <workflow.yaml> [--trace-id ID] [--json]
+  win-automation jobs list [--state <state,...>] [--type <type>] [--since 24h] [--trace-id ID] [--limit 50] [--offset N] [--json]
+  win-automation jobs status --id <job-id> [--detail] [--json]
+  win-automation jobs result --id <job-id> [--json]
+  win-automation jobs wait --id <job-id> [--timeout <duration>] [--detail] [--json]
+  win-automation jobs logs --id <job-id> [--follow] [--timeout <duration>]
   win-automation jobs cancel --id <job-id>
-  win-automation jobs run --type <windows.exec|aloha.run> [--cmd <command>] [--task <text>] [--timeout <duration>] (deprecated)
+  win-automation jobs schedule create --name <name> --cron "<expr>" [--overlap skip|queue|cancel-previous] [--paused] <enqueue flags>
+  win-automation jobs schedule list [--json]
+  win-automation jobs schedule <delete|pause|resume> --name <name>
+  win-automation jobs run --type <windows.exec|aloha.run> [--cmd <command>] [--task <text>] [--timeout <duration>] (deprecated: use enqueue + wait)
   win-automation worker
+  win-automation tasks list [--json]
+  win-automation playwright install [--version X.Y] [--browser chromium|msedge|firefox|webkit] [--bootstrap [--cache <dir>]]
+  win-automation playwright health [--expect-version X.Y] [--browser <name>]
+  win-automation playwright upgrade [--version X.Y] [--force]
+  win-automation playwright rotate-secret
+  win-automation playwright repair
+  win-automation playwright uninstall
+  win-automation playwright run --script <flow.js> [--browser <name>] [--trace[=POLICY]] [--video=POLICY]
+                                [--screenshot-policy=POLICY] [--profile <name>] [--json]
+  win-automation playwright capture --url <url> [--screenshot out.png] [--pdf out.pdf] [--har out.har]
+                                    [--wait-for <selector>] [--viewport 1920x1080] [--browser <name>]
+                                    [--trace[=POLICY]] [--video=POLICY] [--profile <name>]
+  win-automation playwright show-trace --job <job-id> | --file <trace.zip>
 
 Global options (must precede subcommands):
   --config <path>       path to config file (alternatively set WIN_AUTOMATION_CONFIG)
@@ -160,7 +207,22 @@ Environment (defaults shown):
   WIN_AUTOMATION_WINDOWS_SSH_IDENTITY_FILE=
   WIN_AUTOMATION_ALOHA_SERVER_URL=http://127.0.0.1:7887
   WIN_AUTOMATION_ALOHA_CLIENT_URL=http://127.0.0.1:7888
+  WIN_AUTOMATION_ALOHA_TASK_BUDGET=15m
+  WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET=0
   WIN_AUTOMATION_TIMEOUT=10s
+  WIN_AUTOMATION_PLAYWRIGHT_WS_PATH=
+  WIN_AUTOMATION_PLAYWRIGHT_WS_PATH_FILE=
+  WIN_AUTOMATION_PLAYWRIGHT_VERSION=
+  WIN_AUTOMATION_PLAYWRIGHT_BROWSER=chromium
+  WIN_AUTOMATION_PLAYWRIGHT_BROWSERS=chromium
+  WIN_AUTOMATION_PLAYWRIGHT_CACHE_DIR=
+  WIN_AUTOMATION_PLAYWRIGHT_TRACE=off
+  WIN_AUTOMATION_PLAYWRIGHT_VIDEO=off
+  WIN_AUTOMATION_PLAYWRIGHT_SCREENSHOT=off
+  WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS=4
+  WIN_AUTOMATION_TASKS_DIR=./tasks
+  WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT=10m
+  WIN_AUTOMATION_DESKTOP_LOCK_TTL=5m
 
   WIN_AUTOMATION_HATCHET_HTTP_URL=http://127.0.0.1:8888
   WIN_AUTOMATION_HATCHET_GRPC_ADDRESS=localhost:7077
@@ -228,10 +290,77 @@ func cmdDoctor(ctx context.Context, cfg config.Config) int {
 	}
 	logx.Info("doctor", "hatchet", "ready ok")
 
+	if code := doctorPlaywright(ctx, cfg); code != 0 {
+		return code
+	}
+
 	logx.Info("doctor", "finish", "ok")
 	return 0
 }
 
+// doctorPlaywright compares the Playwright version pinned on Windows with the
+// expected client version and checks the server. A server that was never installed
+// or configured is skipped rather than failed.
+func doctorPlaywright(ctx context.Context, cfg config.Config) int {
+	logx.Info("doctor", "playwright", "checking")
+	remote, err := playwright.RemoteVersion(ctx, cfg)
+	switch {
+	case errors.Is(err, playwright.ErrNotInstalled):
+		logx.Warn("doctor", "playwright", "no pinned version installed; run playwright install --version X.Y")
+	case err != nil:
+		logx.Error("doctor", "playwright", "version check failed", err)
+		return 1
+	}
+
+	expected, known := playwright.ExpectedVersion(ctx, cfg)
+	if err == nil {
+		if !known {
+			logx.Warn("doctor", "playwright", "client version unknown; set playwright.version",
+				logx.Field{Key: "remote_version", Value: remote.String()})
+		} else if !remote.Compatible(expected) {
+			logx.Error("doctor", "playwright", "version mismatch",
+				fmt.Errorf("windows runs %s, clients use %s; run playwright upgrade", remote, expected),
+				logx.Field{Key: "remote_version", Value: remote.String()},
+				logx.Field{Key: "client_version", Value: expected.String()},
+			)
+			return 1
+		}
+	}
+
+	if cfg.PlaywrightWSPath == "" {
+		logx.Warn("doctor", "playwright", "ws path not configured; skipping server check")
+		return 0
+	}
+	failed := false
+	for _, server := range playwright.Servers(cfg) {
+		ep := server.Endpoint(cfg)
+		if known {
+			ep.ClientVersion = fmt.Sprintf("%d.%d", expected.Major, expected.Minor)
+		}
+		info, err := playwright.Check(ctx, ep)
+		if err != nil {
+			logx.Error("doctor", "playwright", playwrightHealthReason(err), err,
+				logx.Field{Key: "browser", Value: server.Browser},
+				logx.Field{Key: "addr", Value: ep.Addr()},
+			)
+			failed = true
+			continue
+		}
+		fields := []logx.Field{
+			{Key: "browser", Value: server.Browser},
+			{Key: "browser_version", Value: info.Version},
+		}
+		if remote != (playwright.Version{}) {
+			fields = append(fields, logx.Field{Key: "remote_version", Value: remote.String()})
+		}
+		logx.Info("doctor", "playwright", "ok", fields...)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
 func getHatchetSDKVersion() string {
 	bi, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -253,18 +382,55 @@ func cmdWindows(ctx context.Context, cfg config.Config, args []string) int {
 	switch args[0] {
 	case "exec":
 		return cmdWindowsExec(ctx, cfg, args[1:])
+	case "displays":
+		return cmdWindowsDisplays(ctx, cfg, args[1:])
 	default:
 		logx.Error("windows", "dispatch", "unknown subcommand", fmt.Errorf("%s", args[0]))
 		return 2
 	}
 }
 
+func cmdWindowsDisplays(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("windows displays", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	displays, err := desktop.ListDisplays(ctx, cfg)
+	if err != nil {
+		logx.Error("windows", "displays", "failed", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 4
+		}
+		return 1
+	}
+
+	if *jsonOutput {
+		data, err := json.Marshal(displays)
+		if err != nil {
+			logx.Error("windows", "displays", "marshal", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, d := range displays {
+			fmt.Printf("%d name=%s resolution=%dx%d position=%d,%d scale=%g primary=%t\n",
+				d.Index, d.Name, d.Width, d.Height, d.X, d.Y, d.Scale, d.Primary)
+		}
+	}
+	logx.Info("windows", "displays", "ok", logx.Field{Key: "count", Value: len(displays)})
+	return 0
+}
+
 func cmdWindowsExec(ctx context.Context, cfg config.Config, args []string) int {
 	fs := flag.NewFlagSet("windows exec", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	raw := fs.Bool("raw", false, "suppress logs and print stdout only")
 	idempotent := fs.Bool("idempotent", false, "enable idempotent guard")
 	idempotentCheck := fs.String("idempotent-check", "", "PowerShell snippet to check idempotence")
+	interactive := fs.Bool("interactive", false, "hold the desktop lock while the command runs")
 	_ = fs.Parse(args)
 	rest := fs.Args()
 	logEnabled := !*raw
@@ -290,12 +456,26 @@ func cmdWindowsExec(ctx context.Context, cfg config.Config, args []string) int {
 		}
 	}
 
+	if *interactive {
+		lease, exitCode := acquireDesktopLease(ctx, cfg, logEnabled, "windows", "exec", "")
+		if lease == nil {
+			return exitCode
+		}
+		defer releaseDesktopLease(lease, logEnabled, "windows", "exec")
+		var cancel context.CancelFunc
+		ctx, cancel = lease.Context(ctx)
+		defer cancel()
+	}
+
 	remote := strings.Join(rest[1:], " ")
 	if logEnabled {
 		logx.Info("windows", "exec", "running", logx.Field{Key: "command", Value: remote})
 	}
 	res, err := sshx.Run(ctx, cfg, remote)
 	if err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, desktop.ErrLeaseLost) {
+			err = cause
+		}
 		if logEnabled {
 			fields := []logx.Field{
 				{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
@@ -337,60 +517,252 @@ func cmdAloha(ctx context.Context, cfg config.Config, args []string) int {
 		logx.Info("aloha", "health", "ok")
 		return 0
 	case "run":
-		return cmdAlohaRun(ctx, cfg, a, args[1:])
+		return cmdAlohaRun(ctx, cfg, args[1:])
+	case "usage":
+		return cmdAlohaUsage(ctx, cfg, args[1:])
+	case opInstall, opRepair, opUninstall:
+		return cmdAlohaLifecycle(ctx, cfg, args[0], args[1:])
 	default:
 		logx.Error("aloha", "dispatch", "unknown subcommand", fmt.Errorf("%s", args[0]))
 		return 2
 	}
 }
 
-func cmdAlohaRun(ctx context.Context, cfg config.Config, a *aloha.Client, args []string) int {
+func cmdAlohaRun(ctx context.Context, cfg config.Config, args []string) int {
+	opts, exitCode := parseAlohaRunFlags(cfg, args)
+	if exitCode != 0 {
+		return exitCode
+	}
+	input := opts.input
+	if opts.idempotent {
+		if blocked := runDesktopUnlockedCheck(ctx, cfg, true, "aloha", "run"); blocked {
+			return 1
+		}
+		if skipped, exitCode := runIdempotentCheck(ctx, cfg, true, "aloha", "run", opts.idempotentCheck); skipped {
+			return exitCode
+		}
+	}
+
+	// The lock wait (desktop.lock_timeout) and the task budget bound the run, not
+	// the command timeout; an interrupt still releases the lock.
+	ctx, stop := signalContext(ctx)
+	defer stop()
+
+	lease, exitCode := acquireDesktopLease(ctx, cfg, true, "aloha", "run", input.TraceID)
+	if lease == nil {
+		return exitCode
+	}
+	defer releaseDesktopLease(lease, true, "aloha", "run")
+	ctx, cancel := lease.Context(ctx)
+	defer cancel()
+
+	var rec *artifacts.Recorder
+	if strings.TrimSpace(input.Verify) != "" {
+		rec = artifacts.NewRecorder(cfg.ArtifactOutDir, uuid.NewString(), input.TraceID)
+	}
+
+	logx.Info("aloha", "run", "requesting", logx.Field{Key: "trace_id", Value: input.TraceID})
+	resp, err := hatchet.RunAloha(ctx, cfg, input, rec)
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, desktop.ErrLeaseLost) {
+		err = cause
+	}
+	if closeErr := rec.Close(); closeErr != nil {
+		logx.Error("aloha", "run", "write artifacts failed", closeErr, logx.Field{Key: "path", Value: rec.Root()})
+	}
+	if err != nil {
+		fields := []logx.Field{{Key: "attempts", Value: resp.Attempts}, {Key: "steps", Value: resp.Usage.Steps}}
+		if rec != nil {
+			fields = append(fields, logx.Field{Key: "artifacts", Value: rec.Root()})
+		}
+		if errors.Is(err, hatchet.ErrTaskBudgetExceeded) {
+			logx.Error("aloha", "run", "budget exceeded", err, fields...)
+			return 4
+		}
+		if errors.Is(err, verify.ErrFailed) {
+			fmt.Println(resp.Raw)
+			logx.Error("aloha", "run", "verification failed", err, fields...)
+			return 1
+		}
+		logx.Error("aloha", "run", "failed", err, fields...)
+		return 1
+	}
+
+	fmt.Println(resp.Raw)
+	if resp.Verification != nil {
+		logx.Info("aloha", "run", "verified",
+			logx.Field{Key: "attempts", Value: resp.Attempts},
+			logx.Field{Key: "artifacts", Value: rec.Root()},
+		)
+	}
+	logx.Info("aloha", "run", "ok",
+		logx.Field{Key: "steps", Value: resp.Usage.Steps},
+		logx.Field{Key: "duration_ms", Value: resp.Duration.Milliseconds()},
+	)
+	return 0
+}
+
+// alohaRunOptions are the parsed flags of aloha run.
+type alohaRunOptions struct {
+	input           hatchet.AlohaRunInput
+	idempotent      bool
+	idempotentCheck string
+}
+
+// parseAlohaRunFlags parses and validates the aloha run flags, rendering
+// --template when given. Invalid flags are logged and return exit code 2.
+func parseAlohaRunFlags(cfg config.Config, args []string) (alohaRunOptions, int) {
 	fs := flag.NewFlagSet("aloha run", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 	task := fs.String("task", "", "task text (required)")
 	maxSteps := fs.Int("max-steps", 10, "max steps")
-	selectedScreen := fs.Int("selected-screen", 0, "screen index")
+	selectedScreen := fs.String("selected-screen", "0", "screen index, \"primary\" or display name")
 	traceID := fs.String("trace-id", "win-automation", "trace id")
 	idempotent := fs.Bool("idempotent", false, "enable idempotent guard")
 	idempotentCheck := fs.String("idempotent-check", "", "PowerShell snippet to check idempotence")
+	verifyScript := fs.String("verify", "", "PowerShell postcondition that must exit 0 after the task")
+	verifyRetries := fs.Int("verify-retries", 3, "extra attempts for the --verify check")
+	verifyDelay := fs.Duration("verify-delay", 2*time.Second, "delay between --verify attempts")
+	taskRetries := fs.Int("task-retries", 0, "re-run the task up to N times when --verify fails")
+	budget := fs.Duration("budget", 0, "wall-clock budget for the run (default aloha.task_budget)")
+	templateName := fs.String("template", "", "named task template to render")
+	params := paramFlags{}
+	fs.Var(params, "param", "template parameter key=value (repeatable)")
 	_ = fs.Parse(args)
 
+	if *templateName != "" {
+		rendered, err := renderTemplate(cfg, *templateName, params)
+		if err != nil {
+			logx.Error("aloha", "run", "invalid template", err, logx.Field{Key: "template", Value: *templateName})
+			return alohaRunOptions{}, 2
+		}
+		if rendered.Type != tasks.TypeAlohaRun {
+			logx.Error("aloha", "run", "invalid template", fmt.Errorf("template %s has type %s, want %s", rendered.Name, rendered.Type, tasks.TypeAlohaRun))
+			return alohaRunOptions{}, 2
+		}
+		if strings.TrimSpace(*task) != "" {
+			logx.Error("aloha", "run", "invalid args", errors.New("--task and --template are mutually exclusive"))
+			return alohaRunOptions{}, 2
+		}
+		set := flagsSet(fs)
+		*task = rendered.Task
+		if !set["idempotent-check"] && rendered.IdempotentCheck != "" {
+			*idempotentCheck = rendered.IdempotentCheck
+			*idempotent = true
+		}
+		if !set["verify"] {
+			*verifyScript = rendered.Verify
+		}
+		if !set["max-steps"] && rendered.MaxSteps > 0 {
+			*maxSteps = rendered.MaxSteps
+		}
+	} else if len(params) > 0 {
+		logx.Error("aloha", "run", "invalid args", errors.New("--param requires --template"))
+		return alohaRunOptions{}, 2
+	}
+
 	if *idempotent && strings.TrimSpace(*idempotentCheck) == "" {
 		logx.Error("aloha", "run", "missing idempotent check", errors.New("--idempotent-check is required when --idempotent is set"))
-		return 2
+		return alohaRunOptions{}, 2
 	}
 	if strings.TrimSpace(*task) == "" {
 		logx.Error("aloha", "run", "missing task", errors.New("--task is required"))
+		return alohaRunOptions{}, 2
+	}
+	if *verifyRetries < 0 || *taskRetries < 0 {
+		logx.Error("aloha", "run", "invalid retries", errors.New("--verify-retries and --task-retries must not be negative"))
+		return alohaRunOptions{}, 2
+	}
+	if *budget < 0 {
+		logx.Error("aloha", "run", "invalid budget", errors.New("--budget must not be negative"))
+		return alohaRunOptions{}, 2
+	}
+
+	input := hatchet.AlohaRunInput{
+		Task:          *task,
+		Screen:        *selectedScreen,
+		TraceID:       *traceID,
+		MaxSteps:      *maxSteps,
+		Verify:        *verifyScript,
+		VerifyRetries: *verifyRetries,
+		VerifyDelay:   *verifyDelay,
+		TaskRetries:   *taskRetries,
+		Budget:        *budget,
+		Template:      *templateName,
+	}
+	return alohaRunOptions{input: input, idempotent: *idempotent, idempotentCheck: *idempotentCheck}, 0
+}
+
+func cmdAlohaUsage(_ context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("aloha usage", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	sinceFlag := fs.String("since", "7d", "report window of this host's usage ledger, e.g. 24h or 7d")
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	if *idempotent {
-		if blocked := runDesktopUnlockedCheck(ctx, cfg, true, "aloha", "run"); blocked {
-			return 1
-		}
-		if skipped, exitCode := runIdempotentCheck(ctx, cfg, true, "aloha", "run", *idempotentCheck); skipped {
-			return exitCode
-		}
-	}
-
-	req := aloha.RunTaskRequest{
-		Task:           *task,
-		SelectedScreen: *selectedScreen,
-		TraceID:        *traceID,
-		MaxSteps:       *maxSteps,
+	window, err := parseSince(*sinceFlag)
+	if err != nil {
+		logx.Error("aloha", "usage", "invalid args", err)
+		return 2
 	}
 
-	logx.Info("aloha", "run", "requesting", logx.Field{Key: "trace_id", Value: *traceID})
-	resp, err := a.RunTask(ctx, req)
+	ledger := aloha.UsageLedgerPath(cfg)
+	records, err := aloha.LoadUsage(ledger, time.Now().Add(-window))
 	if err != nil {
-		logx.Error("aloha", "run", "failed", err)
+		logx.Error("aloha", "usage", "read ledger failed", err, logx.Field{Key: "path", Value: ledger})
 		return 1
 	}
+	summaries := aloha.SummarizeUsage(records)
 
-	fmt.Println(resp.Raw)
-	logx.Info("aloha", "run", "ok")
+	if *jsonOutput {
+		data, err := json.Marshal(summaries)
+		if err != nil {
+			logx.Error("aloha", "usage", "marshal", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		var total aloha.Usage
+		runs := 0
+		for _, sum := range summaries {
+			template := sum.Template
+			if template == "" {
+				template = "-"
+			}
+			fmt.Printf("template=%s trace_id=%s outcome=%s runs=%d steps=%d duration=%s tokens=%d cost_usd=%.4f\n",
+				template, sum.TraceID, sum.Outcome, sum.Runs, sum.Steps, sum.Duration.Round(time.Second), sum.TotalTokens, sum.CostUSD)
+			total.Add(sum.Usage)
+			runs += sum.Runs
+		}
+		fmt.Printf("total runs=%d steps=%d tokens=%d cost_usd=%.4f\n", runs, total.Steps, total.TotalTokens, total.CostUSD)
+		if cfg.AlohaDailyStepBudget > 0 {
+			used, err := aloha.StepsUsedToday(ledger, cfg.HatchetNamespace, time.Now())
+			if err == nil {
+				fmt.Printf("today namespace=%s steps=%d budget=%d\n", cfg.HatchetNamespace, used, cfg.AlohaDailyStepBudget)
+			}
+		}
+	}
+	logx.Info("aloha", "usage", "ok", logx.Field{Key: "since", Value: *sinceFlag}, logx.Field{Key: "records", Value: len(records)})
 	return 0
 }
 
+// parseSince parses a look-back window: a Go duration or a whole number of days ("7d").
+func parseSince(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("--since must be a duration (e.g. 24h) or days (e.g. 7d): %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("--since must be a duration (e.g. 24h) or days (e.g. 7d): %q", value)
+	}
+	return d, nil
+}
+
 func runDesktopUnlockedCheck(ctx context.Context, cfg config.Config, logEnabled bool, component string, action string) bool {
 	res, err := sshx.Run(ctx, cfg, win.DesktopUnlockedCheck())
 	if err != nil {
@@ -414,6 +786,41 @@ func runDesktopUnlockedCheck(ctx context.Context, cfg config.Config, logEnabled
 	return false
 }
 
+// acquireDesktopLease takes the exclusive desktop lease for a CLI invocation. On
+// failure it returns a nil lease and the exit code to use: 4 on lock timeout, 1 otherwise.
+func acquireDesktopLease(ctx context.Context, cfg config.Config, logEnabled bool, component string, action string, traceID string) (*desktop.Lease, int) {
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("cli:%s:%d", hostname, os.Getpid())
+	if traceID != "" {
+		holder += ":" + traceID
+	}
+
+	lease, err := desktop.Acquire(ctx, cfg, holder, func(status desktop.Status) {
+		if logEnabled {
+			logx.Info(component, action, "waiting for desktop lock",
+				logx.Field{Key: "position", Value: status.Position},
+				logx.Field{Key: "holder", Value: status.Holder},
+			)
+		}
+	})
+	if err != nil {
+		if logEnabled {
+			logx.Error(component, action, "desktop lock failed", err)
+		}
+		if errors.Is(err, desktop.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, 4
+		}
+		return nil, 1
+	}
+	return lease, 0
+}
+
+func releaseDesktopLease(lease *desktop.Lease, logEnabled bool, component string, action string) {
+	if err := lease.Release(); err != nil && logEnabled {
+		logx.Error(component, action, "desktop lock release failed", err)
+	}
+}
+
 func runIdempotentCheck(ctx context.Context, cfg config.Config, logEnabled bool, component string, action string, script string) (bool, int) {
 	res, err := sshx.Run(ctx, cfg, win.PowerShellCommand(script))
 	if err == nil {
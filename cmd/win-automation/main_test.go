@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+func TestParseAlohaRunFlags(t *testing.T) {
+	opts, exitCode := parseAlohaRunFlags(config.Config{}, []string{"--task", "open notepad", "--budget", "90s", "--selected-screen", "primary"})
+	if exitCode != 0 {
+		t.Fatalf("parseAlohaRunFlags() exit code = %d, want 0", exitCode)
+	}
+	if opts.input.Budget != 90*time.Second {
+		t.Errorf("Budget = %s, want 1m30s", opts.input.Budget)
+	}
+	if opts.input.Task != "open notepad" || opts.input.Screen != "primary" || opts.input.Template != "" {
+		t.Errorf("input = %+v", opts.input)
+	}
+}
+
+func TestParseAlohaRunFlags_Template(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"type":"aloha.run","prompt":"Open {{.app}}","max_steps":15,"params":[{"name":"app"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "open-app.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, exitCode := parseAlohaRunFlags(config.Config{TasksDir: dir}, []string{"--template", "open-app", "--param", "app=Notepad", "--budget", "2m"})
+	if exitCode != 0 {
+		t.Fatalf("parseAlohaRunFlags() exit code = %d, want 0", exitCode)
+	}
+	in := opts.input
+	if in.Task != "Open Notepad" || in.MaxSteps != 15 || in.Template != "open-app" || in.Budget != 2*time.Minute {
+		t.Errorf("input = %+v", in)
+	}
+}
+
+func TestParseAlohaRunFlags_Invalid(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"--task", "x", "--budget", "-1s"},
+		{"--task", "x", "--param", "a=b"},
+		{"--task", "x", "--idempotent"},
+	} {
+		if _, exitCode := parseAlohaRunFlags(config.Config{}, args); exitCode != 2 {
+			t.Errorf("parseAlohaRunFlags(%q) exit code = %d, want 2", args, exitCode)
+		}
+	}
+}
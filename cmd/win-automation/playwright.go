@@ -2,23 +2,40 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/alejg/win-automation/internal/artifacts"
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
 	"github.com/alejg/win-automation/internal/logx"
 	"github.com/alejg/win-automation/internal/playwright"
 	"github.com/alejg/win-automation/internal/sshx"
 	"github.com/alejg/win-automation/internal/win"
+	"github.com/google/uuid"
 )
 
 const (
-	playwrightInstallDir = `C:\ProgramData\win-automation\playwright`
-	playwrightWSPath     = "win-automation-playwright"
+	playwrightInstallDir = playwright.InstallDir
+	playwrightWSPathFile = playwrightInstallDir + `\ws_path.txt`
 )
 
+// playwrightInstallTimeout is the default --timeout of install, upgrade and
+// repair, which download npm packages and browser builds or re-upload scripts and
+// restart servers, and so run far longer than cfg.Timeout.
+const playwrightInstallTimeout = 20 * time.Minute
+
+// playwrightRestartTimeout is the default --timeout of rotate-secret, which
+// restarts every server and waits for each to come back healthy.
+const playwrightRestartTimeout = 3 * time.Minute
+
 func cmdPlaywright(ctx context.Context, cfg config.Config, args []string) int {
 	if len(args) == 0 {
 		logx.Error("playwright", "dispatch", "missing subcommand", errors.New("missing subcommand"))
@@ -29,93 +46,913 @@ func cmdPlaywright(ctx context.Context, cfg config.Config, args []string) int {
 		return cmdPlaywrightInstall(ctx, cfg, args[1:])
 	case "health":
 		return cmdPlaywrightHealth(ctx, cfg, args[1:])
+	case "rotate-secret":
+		return cmdPlaywrightRotateSecret(ctx, cfg, args[1:])
+	case "run":
+		return cmdPlaywrightRun(ctx, cfg, args[1:])
+	case "capture":
+		return cmdPlaywrightCapture(ctx, cfg, args[1:])
+	case "show-trace":
+		return cmdPlaywrightShowTrace(ctx, cfg, args[1:])
+	case "upgrade":
+		return cmdPlaywrightUpgrade(ctx, cfg, args[1:])
+	case "repair":
+		return cmdPlaywrightRepair(ctx, cfg, args[1:])
+	case "uninstall":
+		return cmdPlaywrightUninstall(ctx, cfg, args[1:])
 	default:
 		logx.Error("playwright", "dispatch", "unknown subcommand", fmt.Errorf("%s", args[0]))
 		return 2
 	}
 }
 
-func cmdPlaywrightInstall(ctx context.Context, cfg config.Config, _ []string) int {
-	logx.Info("playwright", "install", "starting")
-	if err := checkPlaywrightPrereqs(ctx, cfg); err != nil {
-		logx.Error("playwright", "install", "prereq check failed", err)
-		return 3
+func cmdPlaywrightInstall(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright install", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	version := fs.String("version", cfg.PlaywrightVersion, "pin and install this Playwright version (X.Y or X.Y.Z)")
+	browser := fs.String("browser", "", "install only this browser server: chromium, msedge, firefox or webkit (default playwright.browsers)")
+	bootstrap := fs.Bool("bootstrap", false, "install Node.js, Playwright and browsers from the local cache (no internet needed on Windows)")
+	cacheDir := fs.String("cache", cfg.PlaywrightCacheDir, "local cache directory for --bootstrap")
+	timeout := fs.Duration("timeout", playwrightInstallTimeout, "how long the install may take")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+	servers, err := selectPlaywrightServers(cfg, *browser)
+	if err != nil {
+		logx.Error("playwright", "install", "invalid browser", err)
+		return 2
+	}
+	if *browser != "" && !playwright.Configured(cfg, *browser) {
+		logx.Warn("playwright", "install", "browser not in playwright.browsers; the supervisor will not watch it",
+			logx.Field{Key: "browser", Value: *browser})
+	}
+	if *version != "" {
+		if _, err := playwright.ParseVersion(*version); err != nil {
+			logx.Error("playwright", "install", "invalid version", err)
+			return 2
+		}
+		*version = strings.TrimPrefix(strings.TrimSpace(*version), "v")
+	}
+
+	var cache playwright.Cache
+	if *bootstrap {
+		if *cacheDir == "" {
+			logx.Error("playwright", "install", "cache not configured", errors.New("pass --cache or set playwright.cache_dir"))
+			return 2
+		}
+		cache, err = playwright.FindCache(*cacheDir, *version)
+		if err != nil {
+			logx.Error("playwright", "install", "invalid cache", err, logx.Field{Key: "cache", Value: *cacheDir})
+			return 2
+		}
+		*version = cache.Playwright.String()
+	}
+
+	logx.Info("playwright", "install", "starting",
+		logx.Field{Key: "version", Value: *version},
+		logx.Field{Key: "bootstrap", Value: *bootstrap},
+	)
+	if !*bootstrap {
+		if err := checkPlaywrightPrereqs(ctx, cfg, *version != ""); err != nil {
+			logx.Error("playwright", "install", "prereq check failed", err)
+			return 3
+		}
 	}
 
 	if err := ensureRemoteDir(ctx, cfg, playwrightInstallDir); err != nil {
 		logx.Error("playwright", "install", "failed to prepare remote directory", err)
 		return 1
 	}
+	if *bootstrap {
+		if err := bootstrapPlaywright(ctx, cfg, cache, servers); err != nil {
+			logx.Error("playwright", "install", "bootstrap failed", err)
+			return 1
+		}
+	}
 
-	launchScriptPath := playwrightInstallDir + `\launch-server.js`
-	installScriptPath := playwrightInstallDir + `\install-playwright.ps1`
+	wsPath, generated, err := ensurePlaywrightWSPath(cfg)
+	if err != nil {
+		logx.Error("playwright", "install", "failed to generate ws path", err)
+		return 1
+	}
+	if err := uploadWSPath(ctx, cfg, wsPath); err != nil {
+		logx.Error("playwright", "install", "failed to upload ws path", err)
+		return 1
+	}
+
+	if *version != "" && !*bootstrap {
+		if err := installPlaywrightVersion(ctx, cfg, "install", *version, servers); err != nil {
+			logx.Error("playwright", "install", "npm install failed", err, logx.Field{Key: "version", Value: *version})
+			return 1
+		}
+	}
 
-	launchScript, err := playwright.LaunchServerJS()
+	c, err := playwright.Component(cfg, servers)
 	if err != nil {
-		logx.Error("playwright", "install", "failed to read launch script", err)
+		logx.Error("playwright", "install", "failed to read embedded scripts", err)
 		return 1
 	}
-	if err := uploadEmbeddedScript(ctx, cfg, launchScript, launchScriptPath); err != nil {
-		logx.Error("playwright", "install", "failed to upload launch script", err)
+	if _, err := applyComponent(ctx, cfg, opInstall, c); err != nil {
+		logx.Error("playwright", "install", "failed to install servers", err)
 		return 1
 	}
 
-	installScript, err := playwright.InstallPlaywrightPS1()
+	if generated {
+		if code := persistWSPath(cfg, "install", wsPath); code != 0 {
+			return code
+		}
+	}
+
+	logx.Info("playwright", "install", "ok",
+		logx.Field{Key: "servers", Value: len(servers)},
+		logx.Field{Key: "ws_path_generated", Value: generated},
+	)
+	return 0
+}
+
+// cmdPlaywrightRotateSecret replaces the ws path secret on Windows, restarts the
+// server and stores the new secret locally.
+func cmdPlaywrightRotateSecret(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright rotate-secret", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	timeout := fs.Duration("timeout", playwrightRestartTimeout, "how long the rotation, restarts and health checks may take")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	// Once uploaded the secret must also be restarted into and stored, so the
+	// whole rotation gets its own timeout rather than cfg.Timeout.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+
+	wsPath, err := playwright.NewWSPath()
 	if err != nil {
-		logx.Error("playwright", "install", "failed to read install script", err)
+		logx.Error("playwright", "rotate_secret", "failed to generate ws path", err)
 		return 1
 	}
-	if err := uploadEmbeddedScript(ctx, cfg, installScript, installScriptPath); err != nil {
-		logx.Error("playwright", "install", "failed to upload install script", err)
+	logx.RegisterSecret(wsPath)
+
+	if err := uploadWSPath(ctx, cfg, wsPath); err != nil {
+		logx.Error("playwright", "rotate_secret", "failed to upload ws path", err)
 		return 1
 	}
+	servers := playwright.Servers(cfg)
+	for _, server := range servers {
+		if res, err := sshx.Run(ctx, cfg, win.ScheduledTaskRestart(server.Task)); err != nil {
+			logx.Error("playwright", "rotate_secret", "failed to restart server", err,
+				logx.Field{Key: "browser", Value: server.Browser},
+				logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
+			)
+			return 1
+		}
+	}
+	if code := persistWSPath(cfg, "rotate_secret", wsPath); code != 0 {
+		return code
+	}
+
+	// The servers need a moment to come back with the new path.
+	cfg.PlaywrightWSPath = wsPath
+	for _, server := range servers {
+		ep := server.Endpoint(cfg)
+		info, err := waitPlaywrightHealthy(ctx, ep)
+		if err != nil {
+			logx.Error("playwright", "rotate_secret", "server not healthy after rotation", err,
+				logx.Field{Key: "browser", Value: server.Browser},
+				logx.Field{Key: "addr", Value: ep.Addr()},
+			)
+			return 1
+		}
+		logx.Info("playwright", "rotate_secret", "server restarted",
+			logx.Field{Key: "browser", Value: server.Browser},
+			logx.Field{Key: "version", Value: info.Version},
+		)
+	}
+
+	logx.Info("playwright", "rotate_secret", "ok", logx.Field{Key: "servers", Value: len(servers)})
+	return 0
+}
+
+// cmdPlaywrightUpgrade reinstalls the servers at a new version: stop the tasks, pin
+// the version, start them again and verify health against that version. When the new
+// version does not come up healthy the previous one is reinstalled.
+func cmdPlaywrightUpgrade(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright upgrade", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	versionFlag := fs.String("version", "", "target Playwright version X.Y[.Z] (default playwright.version or the local package)")
+	force := fs.Bool("force", false, "reinstall even when the server already runs a compatible version")
+	timeout := fs.Duration("timeout", playwrightInstallTimeout, "how long the upgrade, and then the rollback, may each take")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	// The rollback gets its own --timeout, so it still runs when the upgrade
+	// used up all of it.
+	base := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithTimeout(base, *timeout)
+	defer cancel()
+
+	target := strings.TrimPrefix(strings.TrimSpace(*versionFlag), "v")
+	if target == "" {
+		v, ok := playwright.ExpectedVersion(ctx, cfg)
+		if !ok {
+			logx.Error("playwright", "upgrade", "missing version", errors.New("pass --version or set playwright.version"))
+			return 2
+		}
+		target = fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+	want, err := playwright.ParseVersion(target)
+	if err != nil {
+		logx.Error("playwright", "upgrade", "invalid version", err)
+		return 2
+	}
+	if cfg.PlaywrightWSPath == "" {
+		logx.Error("playwright", "upgrade", "ws path not configured", errors.New("set WIN_AUTOMATION_PLAYWRIGHT_WS_PATH or playwright.ws_path_file"))
+		return 2
+	}
 
-	cmd := fmt.Sprintf("& '%s' -WsPath '%s' -Port %d", installScriptPath, playwrightWSPath, cfg.PlaywrightPort)
-	res, err := sshx.Run(ctx, cfg, win.PowerShellCommand(cmd))
+	previous, err := playwright.RemoteVersion(ctx, cfg)
 	if err != nil {
-		logx.Error("playwright", "install", "install script failed", err,
-			logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
-			logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
+		logx.Error("playwright", "upgrade", "read installed version failed", err)
+		if errors.Is(err, playwright.ErrNotInstalled) {
+			logx.Info("playwright", "upgrade", "run playwright install --version first")
+			return 2
+		}
+		return 3
+	}
+	// A major.minor target is satisfied by any patch of it.
+	if !*force && (previous == want || strings.Count(target, ".") == 1 && previous.Compatible(want)) {
+		logx.Info("playwright", "upgrade", "already at version", logx.Field{Key: "version", Value: previous.String()})
+		return 0
+	}
+
+	logx.Info("playwright", "upgrade", "starting",
+		logx.Field{Key: "from", Value: previous.String()},
+		logx.Field{Key: "to", Value: target},
+	)
+	err = reinstallPlaywright(ctx, cfg, target, want)
+	if err == nil {
+		logx.Info("playwright", "upgrade", "ok",
+			logx.Field{Key: "from", Value: previous.String()},
+			logx.Field{Key: "to", Value: target},
 		)
+		return 0
+	}
+	logx.Error("playwright", "upgrade", "new version not healthy; rolling back", err, logx.Field{Key: "version", Value: target})
+
+	rollbackCtx, cancelRollback := context.WithTimeout(base, *timeout)
+	defer cancelRollback()
+	if rollbackErr := reinstallPlaywright(rollbackCtx, cfg, previous.String(), previous); rollbackErr != nil {
+		logx.Error("playwright", "upgrade", "rollback failed", rollbackErr, logx.Field{Key: "version", Value: previous.String()})
 		return 1
 	}
+	logx.Info("playwright", "upgrade", "rolled back", logx.Field{Key: "version", Value: previous.String()})
+	return 1
+}
 
-	logx.Info("playwright", "install", "ok",
-		logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
-		logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
+// reinstallPlaywright stops every configured server task, pins version and
+// restarts the tasks, returning once each server answers as client version want.
+func reinstallPlaywright(ctx context.Context, cfg config.Config, version string, want playwright.Version) error {
+	servers := playwright.Servers(cfg)
+	for _, server := range servers {
+		if _, err := sshx.Run(ctx, cfg, win.ScheduledTaskStop(server.Task)); err != nil {
+			return fmt.Errorf("stop %s server: %w", server.Browser, err)
+		}
+	}
+	if err := installPlaywrightVersion(ctx, cfg, "upgrade", version, servers); err != nil {
+		return err
+	}
+	for _, server := range servers {
+		if _, err := sshx.Run(ctx, cfg, win.ScheduledTaskStart(server.Task)); err != nil {
+			return fmt.Errorf("start %s server: %w", server.Browser, err)
+		}
+	}
+	for _, server := range servers {
+		ep := server.Endpoint(cfg)
+		ep.ClientVersion = fmt.Sprintf("%d.%d", want.Major, want.Minor)
+		info, err := waitPlaywrightHealthy(ctx, ep)
+		if err != nil {
+			return fmt.Errorf("%s server: %w", server.Browser, err)
+		}
+		logx.Info("playwright", "upgrade", "server healthy",
+			logx.Field{Key: "browser", Value: server.Browser},
+			logx.Field{Key: "browser_version", Value: info.Version},
+		)
+	}
+	return nil
+}
+
+// installPlaywrightVersion pins playwright@version in the install directory on
+// Windows and downloads the browser builds the servers need.
+func installPlaywrightVersion(ctx context.Context, cfg config.Config, op, version string, servers []playwright.Server) error {
+	browsers := make([]string, 0, len(servers))
+	for _, server := range servers {
+		browsers = append(browsers, server.Browser)
+	}
+	logx.Info("playwright", op, "installing package",
+		logx.Field{Key: "version", Value: version},
+		logx.Field{Key: "browsers", Value: strings.Join(browsers, ",")},
 	)
-	return 0
+	res, err := sshx.Run(ctx, cfg, win.PlaywrightInstall(playwrightInstallDir, version, playwright.Engines(browsers)))
+	if err != nil {
+		return fmt.Errorf("npm install playwright@%s: %w: %s", version, err, strings.TrimSpace(res.Stderr))
+	}
+	return nil
 }
 
-func cmdPlaywrightHealth(ctx context.Context, cfg config.Config, _ []string) int {
-	logx.Info("playwright", "health", "checking", logx.Field{Key: "port", Value: cfg.PlaywrightPort})
-	res, err := sshx.Run(ctx, cfg, win.PortListeningCheck(cfg.PlaywrightPort))
+// cmdPlaywrightRepair re-uploads server scripts that differ from the embedded ones,
+// re-registers the configured server tasks and firewall rules, and restarts the
+// servers when a script changed.
+func cmdPlaywrightRepair(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright repair", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	timeout := fs.Duration("timeout", playwrightInstallTimeout, "how long the repair, restarts and health checks may take")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+
+	servers := playwright.Servers(cfg)
+	c, err := playwright.Component(cfg, servers)
+	if err != nil {
+		logx.Error("playwright", "repair", "failed to read embedded scripts", err)
+		return 1
+	}
+	changed, err := applyComponent(ctx, cfg, opRepair, c)
 	if err != nil {
-		logx.Error("playwright", "health", "failed", err,
-			logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
-			logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
+		logx.Error("playwright", "repair", "failed", err)
+		return 1
+	}
+
+	// Running servers keep the old script until restarted; stopped ones are started.
+	for _, server := range servers {
+		cmd := win.ScheduledTaskStart(server.Task)
+		if changed {
+			cmd = win.ScheduledTaskRestart(server.Task)
+		}
+		if res, err := sshx.Run(ctx, cfg, cmd); err != nil {
+			logx.Error("playwright", "repair", "failed to start server", err,
+				logx.Field{Key: "browser", Value: server.Browser},
+				logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
+			)
+			return 1
+		}
+	}
+	if cfg.PlaywrightWSPath == "" {
+		logx.Warn("playwright", "repair", "ws path not configured; skipping health check")
+		return 0
+	}
+	for _, server := range servers {
+		ep := server.Endpoint(cfg)
+		if _, err := waitPlaywrightHealthy(ctx, ep); err != nil {
+			logx.Error("playwright", "repair", "server not healthy after repair", err,
+				logx.Field{Key: "browser", Value: server.Browser},
+				logx.Field{Key: "addr", Value: ep.Addr()},
+			)
+			return 1
+		}
+	}
+
+	logx.Info("playwright", "repair", "ok",
+		logx.Field{Key: "servers", Value: len(servers)},
+		logx.Field{Key: "restarted", Value: changed},
+	)
+	return 0
+}
+
+// cmdPlaywrightUninstall removes every browser server task, the Playwright firewall
+// rules and the install directory, including the package, browsers and ws path.
+func cmdPlaywrightUninstall(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright uninstall", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if _, err := applyComponent(ctx, cfg, opUninstall, playwright.Removal(cfg)); err != nil {
+		logx.Error("playwright", "uninstall", "failed", err)
+		return 1
+	}
+	logx.Info("playwright", "uninstall", "ok")
+	return 0
+}
+
+// bootstrapPlaywright installs Node.js, the Playwright package and the servers'
+// browsers from cache, logging the state of each step.
+func bootstrapPlaywright(ctx context.Context, cfg config.Config, cache playwright.Cache, servers []playwright.Server) error {
+	browsers := make([]string, 0, len(servers))
+	for _, server := range servers {
+		browsers = append(browsers, server.Browser)
+	}
+	nodeInstalled := false
+	err := playwright.Bootstrap(ctx, cfg, cache, browsers, func(step playwright.BootstrapStep) {
+		logx.Info("playwright", "bootstrap", step.Name,
+			logx.Field{Key: "state", Value: step.State},
+			logx.Field{Key: "detail", Value: step.Detail},
 		)
+		nodeInstalled = nodeInstalled || step.Name == "node" && step.State == playwright.StateInstalled
+	})
+	if nodeInstalled {
+		logx.Warn("playwright", "bootstrap", "node added to PATH; restart sshd so new SSH sessions find it (Restart-Service sshd)")
+	}
+	return err
+}
+
+// selectPlaywrightServers returns the server of browser, or every configured
+// server when browser is empty.
+func selectPlaywrightServers(cfg config.Config, browser string) ([]playwright.Server, error) {
+	if browser == "" {
+		return playwright.Servers(cfg), nil
+	}
+	if err := playwright.ValidateBrowser(browser); err != nil {
+		return nil, err
+	}
+	return []playwright.Server{playwright.ServerFor(cfg, browser)}, nil
+}
+
+// waitPlaywrightHealthy retries the health check while a restarted server comes up.
+func waitPlaywrightHealthy(ctx context.Context, ep playwright.Endpoint) (playwright.BrowserInfo, error) {
+	var info playwright.BrowserInfo
+	var err error
+	for attempt := 0; ; attempt++ {
+		info, err = playwright.Check(ctx, ep)
+		if err == nil || attempt == 4 || ctx.Err() != nil {
+			return info, err
+		}
+		if sleepErr := sleepContext(ctx, 2*time.Second); sleepErr != nil {
+			return info, err
+		}
+	}
+}
+
+// cmdPlaywrightRun runs a flow script on Windows and stores its screenshots,
+// videos and trace under a new artifact directory.
+func cmdPlaywrightRun(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright run", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	scriptPath := fs.String("script", "", "flow script (.js) to run (required)")
+	browser := fs.String("browser", cfg.PlaywrightBrowser, "browser: chromium, msedge, firefox or webkit")
+	var policies playwright.Policies
+	playwrightPolicyFlags(fs, &policies, true)
+	profile := fs.String("profile", "", "named profile whose cookies and storage the run starts from and saves")
+	traceID := fs.String("trace-id", "win-automation", "trace id")
+	timeout := fs.Duration("timeout", cfg.HatchetJobTimeout, "how long the flow may run")
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkNoArgs(fs); err != nil {
+		logx.Error("playwright", "run", "invalid args", err)
+		return 2
+	}
+	if *timeout <= 0 {
+		logx.Error("playwright", "run", "invalid args", errors.New("--timeout must be positive"))
+		return 2
+	}
+	// A flow runs far longer than cfg.Timeout; --timeout alone bounds it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+
+	input, err := playwrightRunInput(*scriptPath, *browser, policies, *profile, *traceID)
+	if err != nil {
+		logx.Error("playwright", "run", "invalid args", err)
+		return 2
+	}
+
+	rec := artifacts.NewRecorder(cfg.ArtifactOutDir, uuid.NewString(), *traceID)
+	logx.Info("playwright", "run", "starting",
+		logx.Field{Key: "script", Value: input.ScriptName},
+		logx.Field{Key: "browser", Value: *browser},
+	)
+	output, err := hatchet.RunPlaywright(ctx, cfg, input, rec)
+	return reportPlaywrightRun("run", rec, output, err, *jsonOutput)
+}
+
+// cmdPlaywrightCapture renders a URL in the remote browser and saves the requested
+// screenshot, PDF and HAR locally, in addition to the job artifacts.
+func cmdPlaywrightCapture(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright capture", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	pageURL := fs.String("url", "", "page to capture (required)")
+	screenshot := fs.String("screenshot", "", "write a full-page screenshot to this path")
+	pdf := fs.String("pdf", "", "write a PDF to this path")
+	har := fs.String("har", "", "write a HAR of the page load to this path")
+	waitFor := fs.String("wait-for", "", "CSS selector to wait for before capturing")
+	viewport := fs.String("viewport", "", "viewport size, e.g. 1920x1080")
+	browser := fs.String("browser", cfg.PlaywrightBrowser, "browser: chromium, msedge, firefox or webkit")
+	var policies playwright.Policies
+	playwrightPolicyFlags(fs, &policies, false)
+	profile := fs.String("profile", "", "named profile to load the page with")
+	traceID := fs.String("trace-id", "win-automation", "trace id")
+	timeout := fs.Duration("timeout", cfg.HatchetJobTimeout, "how long the capture may take")
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkNoArgs(fs); err != nil {
+		logx.Error("playwright", "capture", "invalid args", err)
+		return 2
+	}
+	if *timeout <= 0 {
+		logx.Error("playwright", "capture", "invalid args", errors.New("--timeout must be positive"))
+		return 2
+	}
+	// Page loads, PDFs and HARs run far longer than cfg.Timeout; --timeout alone
+	// bounds the capture.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+
+	input, err := playwrightCaptureInput(*pageURL, *screenshot, *pdf, *har, *waitFor, *viewport, *browser, policies, *profile, *traceID)
+	if err != nil {
+		logx.Error("playwright", "capture", "invalid args", err)
+		return 2
+	}
+
+	rec := artifacts.NewRecorder(cfg.ArtifactOutDir, uuid.NewString(), *traceID)
+	logx.Info("playwright", "capture", "starting", logx.Field{Key: "url", Value: *pageURL})
+	output, err := hatchet.RunPlaywrightCapture(ctx, cfg, input, rec)
+	if err == nil {
+		targets := map[string]string{input.Screenshot: *screenshot, input.PDF: *pdf, input.HAR: *har}
+		for _, file := range output.Files {
+			dst := targets[filepath.Base(file)]
+			if dst == "" {
+				continue
+			}
+			if copyErr := copyFile(filepath.Join(output.ArtifactDir, file), dst); copyErr != nil {
+				err = fmt.Errorf("copy %s: %w", dst, copyErr)
+				break
+			}
+		}
+	}
+	return reportPlaywrightRun("capture", rec, output, err, *jsonOutput)
+}
+
+// cmdPlaywrightShowTrace opens a job's trace.zip, or any trace file, in the
+// Playwright trace viewer on this host.
+func cmdPlaywrightShowTrace(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright show-trace", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jobID := fs.String("job", "", "job whose trace to open")
+	file := fs.String("file", "", "trace.zip to open")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*jobID == "") == (*file == "") {
+		logx.Error("playwright", "show-trace", "invalid args", errors.New("pass exactly one of --job or --file"))
+		return 2
+	}
+
+	path := *file
+	if *jobID != "" {
+		root := artifactRoot(cfg, *jobID)
+		manifest, err := artifacts.ReadManifest(root)
+		if err != nil {
+			logx.Error("playwright", "show-trace", "read manifest", err, logx.Field{Key: "path", Value: root})
+			return 1
+		}
+		rel, ok := traceArtifact(manifest)
+		if !ok {
+			logx.Error("playwright", "show-trace", "no trace", errors.New("job has no playwright trace; run with --trace"),
+				logx.Field{Key: "job_id", Value: *jobID})
+			return 1
+		}
+		path = filepath.Join(root, rel)
+	}
+
+	npx, err := exec.LookPath("npx")
+	if err != nil {
+		logx.Error("playwright", "show-trace", "npx not found", err)
+		return 3
+	}
+	logx.Info("playwright", "show-trace", "opening", logx.Field{Key: "path", Value: path})
+	cmd := exec.CommandContext(ctx, npx, "playwright", "show-trace", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		logx.Error("playwright", "show-trace", "failed", err)
+		return 1
+	}
+	return 0
+}
+
+// traceArtifact returns the manifest path of the job's Playwright trace.
+func traceArtifact(manifest *artifacts.Manifest) (string, bool) {
+	for _, art := range manifest.Artifacts {
+		if art.Type == "playwright" && filepath.Base(art.Path) == playwright.TraceFile {
+			return art.Path, true
+		}
+	}
+	return "", false
+}
+
+// reportPlaywrightRun writes the manifest, prints the produced files and maps the
+// run error to an exit code.
+func reportPlaywrightRun(op string, rec *artifacts.Recorder, output hatchet.PlaywrightRunOutput, err error, jsonOutput bool) int {
+	if closeErr := rec.Close(); closeErr != nil {
+		logx.Error("playwright", op, "write artifacts failed", closeErr, logx.Field{Key: "path", Value: rec.Root()})
+	}
+
+	if jsonOutput {
+		data, marshalErr := json.Marshal(output)
+		if marshalErr != nil {
+			logx.Error("playwright", op, "marshal", marshalErr)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, file := range output.Files {
+			fmt.Println(filepath.Join(output.ArtifactDir, file))
+		}
+	}
+
+	fields := []logx.Field{
+		{Key: "artifacts", Value: rec.Root()},
+		{Key: "files", Value: len(output.Files)},
+		{Key: "duration_ms", Value: output.Duration.Milliseconds()},
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logx.Error("playwright", op, "timeout", err, fields...)
+			return 4
+		}
+		logx.Error("playwright", op, "failed", err, fields...)
 		return 1
 	}
+	logx.Info("playwright", op, "ok", fields...)
+	return 0
+}
+
+// policyFlag sets one trace, video or screenshot policy. It is a boolean flag so a
+// bare --trace still means on; other policies are given as --trace=retain-on-failure.
+type policyFlag struct {
+	policy *playwright.Policy
+}
+
+func (f policyFlag) String() string {
+	if f.policy == nil {
+		return ""
+	}
+	return string(*f.policy)
+}
+
+func (f policyFlag) Set(value string) error {
+	switch value {
+	case "true":
+		value = string(playwright.PolicyOn)
+	case "false":
+		value = string(playwright.PolicyOff)
+	}
+	policy, err := playwright.ParsePolicy(value)
+	if err != nil {
+		return err
+	}
+	*f.policy = policy
+	return nil
+}
+
+func (f policyFlag) IsBoolFlag() bool { return true }
+
+// playwrightPolicyFlags registers --trace, --video and, for flow runs,
+// --screenshot-policy. Unset policies fall back to the worker's configuration.
+func playwrightPolicyFlags(fs *flag.FlagSet, policies *playwright.Policies, screenshot bool) {
+	fs.Var(policyFlag{&policies.Trace}, "trace", "trace policy: off, on or retain-on-failure (bare --trace means on)")
+	fs.Var(policyFlag{&policies.Video}, "video", "video policy: off, on or retain-on-failure")
+	if screenshot {
+		fs.Var(policyFlag{&policies.Screenshot}, "screenshot-policy", "final screenshot policy: off, on or retain-on-failure")
+	}
+}
+
+// checkNoArgs rejects arguments left over after the flags. The policy flags
+// accept a bare --trace, so "--trace retain-on-failure" would otherwise leave
+// the policy behind as an ignored argument.
+func checkNoArgs(fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q; pass policies as --trace=POLICY", fs.Arg(0))
+	}
+	return nil
+}
+
+// playwrightRunInput reads the flow script and validates the run options shared
+// by playwright run and jobs enqueue.
+func playwrightRunInput(scriptPath, browser string, policies playwright.Policies, profile, traceID string) (hatchet.PlaywrightRunInput, error) {
+	if strings.TrimSpace(scriptPath) == "" {
+		return hatchet.PlaywrightRunInput{}, errors.New("--script is required")
+	}
+	// An empty browser leaves the choice to the worker's playwright.browser.
+	if browser != "" {
+		if err := playwright.ValidateBrowser(browser); err != nil {
+			return hatchet.PlaywrightRunInput{}, err
+		}
+	}
+	if profile != "" {
+		if err := playwright.ValidateProfile(profile); err != nil {
+			return hatchet.PlaywrightRunInput{}, err
+		}
+	}
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return hatchet.PlaywrightRunInput{}, fmt.Errorf("read script: %w", err)
+	}
+	return hatchet.PlaywrightRunInput{
+		Script:           string(script),
+		ScriptName:       filepath.Base(scriptPath),
+		Browser:          browser,
+		TracePolicy:      string(policies.Trace),
+		VideoPolicy:      string(policies.Video),
+		ScreenshotPolicy: string(policies.Screenshot),
+		Profile:          profile,
+		TraceID:          traceID,
+	}, nil
+}
+
+// playwrightCaptureInput validates a capture shared by playwright capture and jobs
+// enqueue. Output paths are reduced to file names for the remote run.
+func playwrightCaptureInput(pageURL, screenshot, pdf, har, waitFor, viewport, browser string, policies playwright.Policies, profile, traceID string) (hatchet.PlaywrightCaptureInput, error) {
+	// An empty browser leaves the choice to the worker's playwright.browser.
+	if browser != "" {
+		if err := playwright.ValidateBrowser(browser); err != nil {
+			return hatchet.PlaywrightCaptureInput{}, err
+		}
+	}
+	if profile != "" {
+		if err := playwright.ValidateProfile(profile); err != nil {
+			return hatchet.PlaywrightCaptureInput{}, err
+		}
+	}
+	base := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		return filepath.Base(path)
+	}
+	input := hatchet.PlaywrightCaptureInput{
+		URL:         pageURL,
+		WaitFor:     waitFor,
+		Screenshot:  base(screenshot),
+		PDF:         base(pdf),
+		HAR:         base(har),
+		Viewport:    viewport,
+		Browser:     browser,
+		TracePolicy: string(policies.Trace),
+		VideoPolicy: string(policies.Video),
+		Profile:     profile,
+		TraceID:     traceID,
+	}
+	spec := playwright.CaptureSpec{
+		URL:        input.URL,
+		Screenshot: input.Screenshot,
+		PDF:        input.PDF,
+		HAR:        input.HAR,
+		Viewport:   input.Viewport,
+	}
+	if err := spec.Validate(); err != nil {
+		return hatchet.PlaywrightCaptureInput{}, err
+	}
+	return input, nil
+}
+
+// ensurePlaywrightWSPath returns the configured ws path, or a freshly generated one
+// when none is configured.
+func ensurePlaywrightWSPath(cfg config.Config) (wsPath string, generated bool, err error) {
+	if cfg.PlaywrightWSPath != "" {
+		return cfg.PlaywrightWSPath, false, nil
+	}
+	wsPath, err = playwright.NewWSPath()
+	if err != nil {
+		return "", false, err
+	}
+	logx.RegisterSecret(wsPath)
+	return wsPath, true, nil
+}
+
+// uploadWSPath copies the secret to ws_path.txt on Windows via scp, so it never
+// appears on a remote command line, and restricts the file to administrators.
+func uploadWSPath(ctx context.Context, cfg config.Config, wsPath string) error {
+	if err := ensureRemoteDir(ctx, cfg, playwrightInstallDir); err != nil {
+		return err
+	}
+	if err := uploadEmbeddedScript(ctx, cfg, []byte(wsPath), playwrightWSPathFile); err != nil {
+		return err
+	}
+	_, err := sshx.Run(ctx, cfg, win.RestrictToAdmins(playwrightWSPathFile))
+	return err
+}
 
-	if strings.EqualFold(strings.TrimSpace(res.Stdout), "True") {
-		logx.Info("playwright", "health", "ok")
+// persistWSPath stores a new secret in the configured ws_path_file. Without one the
+// secret is printed on stdout, the only place it is ever shown, so it can be
+// configured via WIN_AUTOMATION_PLAYWRIGHT_WS_PATH.
+func persistWSPath(cfg config.Config, op, wsPath string) int {
+	if cfg.PlaywrightWSPathFile == "" {
+		fmt.Println(wsPath)
+		logx.Warn("playwright", op, "no ws_path_file configured; set WIN_AUTOMATION_PLAYWRIGHT_WS_PATH to the value printed on stdout")
 		return 0
 	}
+	if err := config.WriteSecretFile(cfg.PlaywrightWSPathFile, wsPath); err != nil {
+		fmt.Println(wsPath)
+		logx.Error("playwright", op, "failed to write ws_path_file; store the value printed on stdout manually", err,
+			logx.Field{Key: "path", Value: cfg.PlaywrightWSPathFile},
+		)
+		return 1
+	}
+	logx.Info("playwright", op, "ws path stored", logx.Field{Key: "path", Value: cfg.PlaywrightWSPathFile})
+	return 0
+}
+
+func cmdPlaywrightHealth(ctx context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("playwright health", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	expectVersion := fs.String("expect-version", "", "Playwright version (major.minor) the server must run (default playwright.version or the local package)")
+	browser := fs.String("browser", "", "check only this browser server (default playwright.browsers)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if cfg.PlaywrightWSPath == "" {
+		logx.Error("playwright", "health", "ws path not configured", errors.New("set WIN_AUTOMATION_PLAYWRIGHT_WS_PATH or playwright.ws_path_file"))
+		return 2
+	}
+	servers, err := selectPlaywrightServers(cfg, *browser)
+	if err != nil {
+		logx.Error("playwright", "health", "invalid browser", err)
+		return 2
+	}
+	clientVersion := *expectVersion
+	if clientVersion == "" {
+		if v, ok := playwright.ExpectedVersion(ctx, cfg); ok {
+			clientVersion = fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		}
+	}
+
+	// Every server is checked and reported; the first failure sets the exit code.
+	code := 0
+	for _, server := range servers {
+		ep := server.Endpoint(cfg)
+		ep.ClientVersion = clientVersion
+		if c := checkPlaywrightServer(ctx, server, ep); c != 0 && code == 0 {
+			code = c
+		}
+	}
+	return code
+}
+
+// checkPlaywrightServer runs the health check against one browser server and maps
+// the failure to an exit code.
+func checkPlaywrightServer(ctx context.Context, server playwright.Server, ep playwright.Endpoint) int {
+	logx.Info("playwright", "health", "checking",
+		logx.Field{Key: "browser", Value: server.Browser},
+		logx.Field{Key: "addr", Value: ep.Addr()},
+		logx.Field{Key: "client_version", Value: ep.ClientVersion},
+	)
+	info, err := playwright.Check(ctx, ep)
+	if err != nil {
+		logx.Error("playwright", "health", playwrightHealthReason(err), err,
+			logx.Field{Key: "browser", Value: server.Browser},
+			logx.Field{Key: "addr", Value: ep.Addr()},
+		)
+		switch {
+		case errors.Is(err, playwright.ErrNotListening):
+			return 3
+		case errors.Is(err, context.DeadlineExceeded):
+			return 4
+		default:
+			return 1
+		}
+	}
 
-	logx.Error("playwright", "health", "not listening", errors.New("playwright port is not listening"),
-		logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
+	logx.Info("playwright", "health", "ok",
+		logx.Field{Key: "browser", Value: server.Browser},
+		logx.Field{Key: "engine", Value: info.Name},
+		logx.Field{Key: "version", Value: info.Version},
+		logx.Field{Key: "client_version", Value: ep.ClientVersion},
 	)
-	return 3
+	return 0
 }
 
-func checkPlaywrightPrereqs(ctx context.Context, cfg config.Config) error {
-	checks := []struct {
+// playwrightHealthReason names a failed health check for logs.
+func playwrightHealthReason(err error) string {
+	switch {
+	case errors.Is(err, playwright.ErrNotListening):
+		return "not listening"
+	case errors.Is(err, playwright.ErrWrongPath):
+		return "wrong ws path"
+	case errors.Is(err, playwright.ErrVersionMismatch):
+		return "version mismatch"
+	default:
+		return "failed"
+	}
+}
+
+// checkPlaywrightPrereqs checks node on Windows and, unless install pins a version
+// itself, that a playwright package is already resolvable.
+func checkPlaywrightPrereqs(ctx context.Context, cfg config.Config, pinned bool) error {
+	type prereq struct {
 		name   string
 		script string
-	}{
-		{name: "node", script: "node --version"},
-		{name: "playwright", script: "node -e \"require('playwright');\""},
+	}
+	checks := []prereq{{name: "node", script: "node --version"}}
+	if pinned {
+		checks = append(checks, prereq{name: "npm", script: "npm --version"})
+	} else {
+		checks = append(checks, prereq{name: "playwright", script: "node -e \"require('playwright');\""})
 	}
 
 	for _, check := range checks {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+func TestParseJobsEnqueueFlags_RejectsStrayArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"--type", "windows.exec", "--cmd", "hostname"}, false},
+		{[]string{"--type", "windows.exec", "--cmd", "hostname", "--trace=retain-on-failure"}, false},
+		{[]string{"--type", "windows.exec", "--cmd", "hostname", "--trace", "retain-on-failure"}, true},
+		{[]string{"--type", "windows.exec", "hostname"}, true},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("jobs enqueue", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		_, err := parseJobsEnqueueFlags(fs, config.Config{}, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseJobsEnqueueFlags(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+		}
+		if err != nil && !isJobsUsageError(err) {
+			t.Errorf("parseJobsEnqueueFlags(%q) error = %v, want a usage error", tt.args, err)
+		}
+	}
+}
+
+func TestCmdPlaywrightRun_RejectsStrayArgs(t *testing.T) {
+	args := []string{"--script", "flow.js", "--trace", "retain-on-failure"}
+	if code := cmdPlaywrightRun(context.Background(), config.Config{}, args); code != 2 {
+		t.Errorf("cmdPlaywrightRun(%q) = %d, want 2", args, code)
+	}
+	args = []string{"--url", "https://example.com", "--screenshot", "page.png", "--trace", "on"}
+	if code := cmdPlaywrightCapture(context.Background(), config.Config{}, args); code != 2 {
+		t.Errorf("cmdPlaywrightCapture(%q) = %d, want 2", args, code)
+	}
+}
@@ -11,8 +11,10 @@ import (
 
 	"github.com/alejg/win-automation/internal/aloha"
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/desktop"
 	"github.com/alejg/win-automation/internal/hatchet"
 	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/playwright"
 	"github.com/alejg/win-automation/internal/sshx"
 	"github.com/alejg/win-automation/internal/win"
 )
@@ -21,6 +23,7 @@ const (
 	supervisorCircuitThreshold    = 3
 	supervisorCircuitOpenDuration = 60 * time.Second
 	supervisorLoopInterval        = 10 * time.Second
+	supervisorDesktopLockWait     = 30 * time.Second
 	supervisorFailpointEnv        = "WIN_AUTOMATION_SUPERVISOR_FAILPOINT"
 
 	supervisorExitFailure    = 1
@@ -112,6 +115,13 @@ type supervisorRunner struct {
 	hatchet *hatchet.Client
 }
 
+type supervisorCheck struct {
+	name      string
+	exitCode  int
+	check     func(context.Context) error
+	remediate func(context.Context) error
+}
+
 type supervisorResult struct {
 	exitCode int
 	err      error
@@ -124,12 +134,7 @@ func (r supervisorRunner) runOnce(ctx context.Context) supervisorResult {
 		return supervisorResult{exitCode: supervisorExitFailure, err: err}
 	}
 
-	checks := []struct {
-		name      string
-		exitCode  int
-		check     func(context.Context) error
-		remediate func(context.Context) error
-	}{
+	checks := []supervisorCheck{
 		{
 			name:     "ssh",
 			exitCode: supervisorExitFailure,
@@ -152,12 +157,16 @@ func (r supervisorRunner) runOnce(ctx context.Context) supervisorResult {
 			exitCode: supervisorExitDependency,
 			check:    r.checkHatchet,
 		},
-		{
-			name:      "playwright",
+	}
+	// Each configured browser has its own server task; a failing one is restarted
+	// without touching the others.
+	for _, server := range playwright.Servers(r.cfg) {
+		checks = append(checks, supervisorCheck{
+			name:      "playwright_" + server.Browser,
 			exitCode:  supervisorExitDependency,
-			check:     r.checkPlaywright,
-			remediate: r.remediatePlaywright,
-		},
+			check:     func(ctx context.Context) error { return r.checkPlaywright(ctx, server) },
+			remediate: func(ctx context.Context) error { return r.remediatePlaywright(ctx, server) },
+		})
 	}
 
 	for _, check := range checks {
@@ -232,40 +241,75 @@ func (r supervisorRunner) checkHatchet(ctx context.Context) error {
 	return r.hatchet.HealthReady(ctx)
 }
 
-func (r supervisorRunner) checkPlaywright(ctx context.Context) error {
-	res, err := sshx.Run(ctx, r.cfg, win.PortListeningCheck(r.cfg.PlaywrightPort))
-	if err != nil {
-		logx.Error("supervisor", "playwright", "port check failed", err,
-			logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
-			logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
-		)
-		return err
+func (r supervisorRunner) checkPlaywright(ctx context.Context, server playwright.Server) error {
+	if r.cfg.PlaywrightWSPath == "" {
+		return errors.New("playwright ws path not configured")
 	}
-	if strings.EqualFold(strings.TrimSpace(res.Stdout), "True") {
-		return nil
+	ep := server.Endpoint(r.cfg)
+	info, err := playwright.Check(ctx, ep)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", ep.Addr(), playwrightHealthReason(err), err)
 	}
-	return errors.New("playwright port is not listening")
+	r.debugLog("playwright", "handshake ok",
+		logx.Field{Key: "server", Value: server.Browser},
+		logx.Field{Key: "browser", Value: info.Name},
+		logx.Field{Key: "version", Value: info.Version},
+	)
+	return nil
 }
 
 func (r supervisorRunner) remediateAlohaServer(ctx context.Context) error {
 	if err := r.ensureFirewallRule(ctx, "Aloha-7887", 7887); err != nil {
 		return err
 	}
-	return r.runStartCommand(ctx, "aloha_server", r.cfg.AlohaServerStartCmd)
+	return r.withDesktopLock(ctx, "aloha_server", func(ctx context.Context) error {
+		return r.runStartCommand(ctx, "aloha_server", r.cfg.AlohaServerStartCmd)
+	})
 }
 
 func (r supervisorRunner) remediateAlohaClient(ctx context.Context) error {
 	if err := r.ensureFirewallRule(ctx, "Aloha-7888", 7888); err != nil {
 		return err
 	}
-	return r.runStartCommand(ctx, "aloha_client", r.cfg.AlohaClientStartCmd)
+	return r.withDesktopLock(ctx, "aloha_client", func(ctx context.Context) error {
+		return r.runStartCommand(ctx, "aloha_client", r.cfg.AlohaClientStartCmd)
+	})
+}
+
+// withDesktopLock runs fn while holding the desktop lock so that restarting Aloha
+// never interrupts a GUI task in progress. It waits at most supervisorDesktopLockWait,
+// and fn's context is cancelled if the lock is lost.
+func (r supervisorRunner) withDesktopLock(ctx context.Context, name string, fn func(context.Context) error) error {
+	lockCtx, cancel := context.WithTimeout(ctx, supervisorDesktopLockWait)
+	lease, err := desktop.Acquire(lockCtx, r.cfg, "supervisor:"+name, func(status desktop.Status) {
+		r.debugLog("remediate", fmt.Sprintf("%s waiting for desktop lock", name),
+			logx.Field{Key: "position", Value: status.Position},
+			logx.Field{Key: "holder", Value: status.Holder},
+		)
+	})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("%s remediation skipped: %w", name, err)
+	}
+	defer func() {
+		if err := lease.Release(); err != nil {
+			logx.Error("supervisor", "remediate", "desktop lock release failed", err)
+		}
+	}()
+	leaseCtx, cancelLease := lease.Context(ctx)
+	defer cancelLease()
+	err = fn(leaseCtx)
+	if lostErr := lease.Err(); lostErr != nil {
+		return fmt.Errorf("%s remediation: %w", name, lostErr)
+	}
+	return err
 }
 
-func (r supervisorRunner) remediatePlaywright(ctx context.Context) error {
-	cmd := "Start-ScheduledTask -TaskName 'WinAutomation-Playwright'"
-	res, err := sshx.Run(ctx, r.cfg, win.PowerShellCommand(cmd))
+func (r supervisorRunner) remediatePlaywright(ctx context.Context, server playwright.Server) error {
+	res, err := sshx.Run(ctx, r.cfg, win.ScheduledTaskStart(server.Task))
 	if err != nil {
 		logx.Error("supervisor", "playwright", "failed to start scheduled task", err,
+			logx.Field{Key: "task", Value: server.Task},
 			logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
 			logx.Field{Key: "stderr", Value: strings.TrimSpace(res.Stderr)},
 		)
@@ -297,9 +341,7 @@ func (r supervisorRunner) ensureFirewallRule(ctx context.Context, displayName st
 		return nil
 	}
 
-	escapedName := strings.ReplaceAll(displayName, "'", "''")
-	script := fmt.Sprintf("$rule = Get-NetFirewallRule -DisplayName '%s' -ErrorAction SilentlyContinue; if ($null -eq $rule) { New-NetFirewallRule -DisplayName '%s' -Direction Inbound -Action Allow -Protocol TCP -LocalPort %d -Profile Any | Out-Null } else { Set-NetFirewallRule -DisplayName '%s' -Enabled True | Out-Null }", escapedName, escapedName, port, escapedName)
-	res, err := sshx.Run(ctx, r.cfg, win.PowerShellCommand(script))
+	res, err := sshx.Run(ctx, r.cfg, win.FirewallRuleEnsure(displayName, port))
 	if err != nil {
 		logx.Error("supervisor", "remediate", fmt.Sprintf("firewall rule %s failed", displayName), err,
 			logx.Field{Key: "stdout", Value: strings.TrimSpace(res.Stdout)},
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/tasks"
+)
+
+func cmdTasks(ctx context.Context, cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		logx.Error("tasks", "dispatch", "missing subcommand", errors.New("missing subcommand"))
+		return 2
+	}
+	switch args[0] {
+	case "list":
+		return cmdTasksList(ctx, cfg, args[1:])
+	default:
+		logx.Error("tasks", "dispatch", "unknown subcommand", fmt.Errorf("%s", args[0]))
+		return 2
+	}
+}
+
+func cmdTasksList(_ context.Context, cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("tasks list", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	jsonOutput := fs.Bool("json", false, "output as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	lib, err := tasks.LoadDir(cfg.TasksDir)
+	if err != nil {
+		logx.Error("tasks", "list", "load failed", err, logx.Field{Key: "dir", Value: cfg.TasksDir})
+		return 2
+	}
+
+	templates := lib.Sorted()
+	if *jsonOutput {
+		data, err := json.Marshal(templates)
+		if err != nil {
+			logx.Error("tasks", "list", "marshal", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, tmpl := range templates {
+			params := make([]string, 0, len(tmpl.Params))
+			for _, p := range tmpl.Params {
+				spec := p.Name
+				if p.Type != "" {
+					spec += ":" + p.Type
+				}
+				if p.Default != nil {
+					spec += "=" + *p.Default
+				}
+				params = append(params, spec)
+			}
+			fmt.Printf("%s type=%s params=%s description=%q\n", tmpl.Name, tmpl.Type, strings.Join(params, ","), tmpl.Description)
+		}
+	}
+
+	logx.Info("tasks", "list", "ok", logx.Field{Key: "dir", Value: cfg.TasksDir}, logx.Field{Key: "templates", Value: len(templates)})
+	return 0
+}
+
+// paramFlags collects repeated --param key=value flags.
+type paramFlags map[string]string
+
+func (p paramFlags) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+p[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("param must be key=value: %q", value)
+	}
+	p[strings.TrimSpace(key)] = val
+	return nil
+}
+
+// renderTemplate loads the named template from the configured library and renders it.
+func renderTemplate(cfg config.Config, name string, params paramFlags) (tasks.Rendered, error) {
+	lib, err := tasks.LoadDir(cfg.TasksDir)
+	if err != nil {
+		return tasks.Rendered{}, err
+	}
+	tmpl, err := lib.Get(name)
+	if err != nil {
+		return tasks.Rendered{}, err
+	}
+	return tmpl.Render(params)
+}
+
+// flagsSet reports which flags were given explicitly on the command line.
+func flagsSet(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"flag"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/hatchet"
 	"github.com/alejg/win-automation/internal/logx"
 	"github.com/alejg/win-automation/internal/metrics"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
 )
 
 func cmdWorker(ctx context.Context, cfg config.Config, args []string) int {
@@ -20,18 +24,62 @@ func cmdWorker(ctx context.Context, cfg config.Config, args []string) int {
 		return 2
 	}
 
-	logx.Info("worker", "start", "starting worker")
+	client, err := hatchet.NewSDKClient(cfg)
+	if err != nil {
+		logx.Error("worker", "start", "client init failed", err)
+		return 2
+	}
+	w := hatchet.NewWorker(cfg)
+	hw, err := client.NewWorker(cfg.HatchetWorkerName,
+		sdk.WithWorkflows(w.HatchetWorkflows(client)...),
+		sdk.WithSlots(cfg.HatchetWorkerConcurrency),
+	)
+	if err != nil {
+		logx.Error("worker", "start", "register failed", err)
+		return 1
+	}
+
+	ctx, stop := signalContext(ctx)
+	defer stop()
+
+	logx.Info("worker", "start", "starting worker",
+		logx.Field{Key: "name", Value: cfg.HatchetWorkerName},
+		logx.Field{Key: "slots", Value: cfg.HatchetWorkerConcurrency},
+	)
 
 	if *enableMetrics {
-		go emitMetricsLoop(ctx, *metricsInterval)
+		queue, err := hatchet.NewQueueMonitor(cfg)
+		if err != nil {
+			// Queue depth is optional; the worker's own metrics still emit.
+			logx.Warn("worker", "metrics", "queue depth unavailable", logx.Field{Key: "err", Value: err.Error()})
+		}
+		go emitMetricsLoop(ctx, *metricsInterval, queue)
+	}
+	if cfg.HatchetScheduler == hatchet.SchedulerLocal {
+		go hatchet.RunLocalScheduler(ctx, hatchet.FileSchedules{Path: cfg.HatchetScheduleFile}, func(ctx context.Context, s hatchet.Schedule) error {
+			return hatchet.StartScheduleTick(ctx, client, s)
+		})
 	}
 
-	<-ctx.Done()
+	if err := hw.StartBlocking(ctx); err != nil {
+		logx.Error("worker", "stop", "worker failed", err)
+		return 1
+	}
 	logx.Info("worker", "stop", "worker stopped")
 	return 0
 }
 
-func emitMetricsLoop(ctx context.Context, interval time.Duration) {
+// signalContext detaches ctx from its deadline, which main sets to cfg.Timeout,
+// and ends it on SIGINT or SIGTERM instead. The worker runs under it: a worker
+// that stopped on its own would exit 0 and never be restarted by systemd's
+// Restart=on-failure. So does aloha run, bounded by its lock wait and budget.
+func signalContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.WithoutCancel(ctx), os.Interrupt, syscall.SIGTERM)
+}
+
+// emitMetricsLoop emits the metrics every interval, with the queue depth of
+// every job type when queue is set.
+func emitMetricsLoop(ctx context.Context, interval time.Duration, queue *hatchet.QueueMonitor) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -51,7 +99,24 @@ func emitMetricsLoop(ctx context.Context, interval time.Duration) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			if queue != nil {
+				updateQueueDepths(ctx, queue, interval)
+			}
 			metrics.DefaultMetrics.Emit(w)
 		}
 	}
 }
+
+// updateQueueDepths refreshes the jobs_queued gauges. On failure the gauges
+// keep their previous values.
+func updateQueueDepths(ctx context.Context, queue *hatchet.QueueMonitor, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	depths, err := queue.QueueDepths(ctx)
+	if err != nil {
+		logx.Warn("worker", "metrics", "queue depth failed", logx.Field{Key: "err", Value: err.Error()})
+	}
+	for jobType, n := range depths {
+		metrics.DefaultMetrics.SetQueued(string(jobType), int64(n))
+	}
+}
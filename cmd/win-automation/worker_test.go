@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalContext(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	ctx, stop := signalContext(parent)
+	defer stop()
+
+	<-parent.Done()
+	select {
+	case <-ctx.Done():
+		t.Fatal("signal context ended with the command timeout")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("signal context not cancelled by SIGTERM")
+	}
+}
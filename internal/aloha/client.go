@@ -42,7 +42,7 @@ func (c *Client) ServerHealth(ctx context.Context) (string, error) {
 
 	b, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return string(b), fmt.Errorf("aloha server health returned http %d", resp.StatusCode)
+		return string(b), &StatusError{Endpoint: "server health", Status: resp.StatusCode}
 	}
 	return string(b), nil
 }
@@ -58,6 +58,17 @@ func (c *Client) ClientRootStatus(ctx context.Context) (int, error) {
 	return resp.StatusCode, nil
 }
 
+// StatusError is a non-2xx response from the Aloha server or client, returned
+// once the request's own retries are spent.
+type StatusError struct {
+	Endpoint string
+	Status   int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("aloha %s returned http %d", e.Endpoint, e.Status)
+}
+
 type RunTaskRequest struct {
 	Task           string `json:"task"`
 	SelectedScreen int    `json:"selected_screen"`
@@ -99,7 +110,7 @@ func (c *Client) RunTask(ctx context.Context, req RunTaskRequest) (RunTaskRespon
 
 	b, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return RunTaskResponse{Raw: string(b)}, fmt.Errorf("aloha client returned http %d", resp.StatusCode)
+		return RunTaskResponse{Raw: string(b)}, &StatusError{Endpoint: "client", Status: resp.StatusCode}
 	}
 	return RunTaskResponse{Raw: string(b)}, nil
 }
@@ -0,0 +1,61 @@
+package aloha
+
+import (
+	"strings"
+
+	"github.com/alejg/win-automation/internal/component"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// InstallDir holds the Aloha start scripts on Windows.
+const InstallDir = `C:\ProgramData\win-automation\aloha`
+
+// Scheduled tasks that run the start scripts when the desktop user logs on.
+const (
+	ServerTask = "WinAutomation-Aloha-Server"
+	ClientTask = "WinAutomation-Aloha-Client"
+)
+
+// Firewall rules for the Aloha server and client ports.
+var firewallRules = []component.FirewallRule{
+	{Name: "Aloha-7887", Port: 7887},
+	{Name: "Aloha-7888", Port: 7888},
+}
+
+// Component returns a start script and logon task for each configured start command
+// (aloha.server_start_cmd, aloha.client_start_cmd) plus the firewall rules. The
+// scripts run in the interactive session of cfg.WindowsSSHUser, where Aloha can see
+// the desktop.
+func Component(cfg config.Config) component.Component {
+	c := component.Component{Name: "aloha", Dir: InstallDir, Firewall: firewallRules}
+	for _, start := range []struct {
+		file, task, setting, command string
+	}{
+		{"start-server.ps1", ServerTask, "aloha.server_start_cmd", cfg.AlohaServerStartCmd},
+		{"start-client.ps1", ClientTask, "aloha.client_start_cmd", cfg.AlohaClientStartCmd},
+	} {
+		if strings.TrimSpace(start.command) == "" {
+			continue
+		}
+		script := "# Generated by win-automation from " + start.setting + "; aloha repair reverts local edits.\r\n" +
+			start.command + "\r\n"
+		c.Files = append(c.Files, component.File{Name: start.file, Data: []byte(script)})
+		c.Tasks = append(c.Tasks, component.Task{
+			Name:     start.task,
+			Register: win.ScheduledTaskRegisterLogon(start.task, cfg.WindowsSSHUser, InstallDir+`\`+start.file),
+		})
+	}
+	return c
+}
+
+// Removal returns everything aloha install may have placed on Windows, whichever
+// start commands are configured now.
+func Removal() component.Component {
+	return component.Component{
+		Name:     "aloha",
+		Dir:      InstallDir,
+		Tasks:    []component.Task{{Name: ServerTask}, {Name: ClientTask}},
+		Firewall: firewallRules,
+	}
+}
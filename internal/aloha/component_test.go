@@ -0,0 +1,30 @@
+package aloha
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+func TestComponent(t *testing.T) {
+	cfg := config.Config{WindowsSSHUser: "desk", AlohaServerStartCmd: "Start-Process C:\\Aloha\\server.exe"}
+	c := Component(cfg)
+
+	if len(c.Files) != 1 || c.Files[0].Name != "start-server.ps1" {
+		t.Fatalf("Files = %+v, want only start-server.ps1", c.Files)
+	}
+	if !strings.Contains(string(c.Files[0].Data), cfg.AlohaServerStartCmd) {
+		t.Errorf("start-server.ps1 = %q, want the start command", c.Files[0].Data)
+	}
+	if len(c.Tasks) != 1 || c.Tasks[0].Name != ServerTask || !strings.Contains(c.Tasks[0].Register, "desk") {
+		t.Errorf("Tasks = %+v, want %s for user desk", c.Tasks, ServerTask)
+	}
+	if len(c.Firewall) != 2 {
+		t.Errorf("Firewall = %+v, want both Aloha ports", c.Firewall)
+	}
+
+	if r := Removal(); len(r.Tasks) != 2 || r.Dir != InstallDir {
+		t.Errorf("Removal() = %+v, want both tasks and %s", r, InstallDir)
+	}
+}
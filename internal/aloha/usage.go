@@ -0,0 +1,232 @@
+package aloha
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+// Usage outcomes recorded in the ledger.
+const (
+	OutcomeSucceeded      = "succeeded"
+	OutcomeFailed         = "failed"
+	OutcomeVerifyFailed   = "verify_failed"
+	OutcomeBudgetExceeded = "budget_exceeded"
+)
+
+// Usage is the consumption reported by Aloha for one or more task runs.
+type Usage struct {
+	Steps        int     `json:"steps"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	TotalTokens  int     `json:"total_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+	// StepsEstimated is set when Aloha did not report a step count and the
+	// requested max_steps was charged instead.
+	StepsEstimated bool `json:"steps_estimated,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.Steps += other.Steps
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+	u.StepsEstimated = u.StepsEstimated || other.StepsEstimated
+}
+
+// ParseUsage extracts step, token and cost fields from a run_task response. The
+// fields are looked up at the top level and under "usage"; absent fields stay zero
+// and ok reports whether a step count was found.
+func ParseUsage(raw string) (usage Usage, ok bool) {
+	var body map[string]any
+	if err := json.Unmarshal([]byte(raw), &body); err != nil {
+		return Usage{}, false
+	}
+	scopes := []map[string]any{body}
+	if nested, isMap := body["usage"].(map[string]any); isMap {
+		scopes = append(scopes, nested)
+	}
+
+	for _, scope := range scopes {
+		for _, key := range []string{"steps", "num_steps", "step_count", "steps_taken"} {
+			switch v := scope[key].(type) {
+			case float64:
+				usage.Steps, ok = int(v), true
+			case []any:
+				usage.Steps, ok = len(v), true
+			}
+		}
+		usage.InputTokens = firstInt(scope, usage.InputTokens, "input_tokens", "prompt_tokens")
+		usage.OutputTokens = firstInt(scope, usage.OutputTokens, "output_tokens", "completion_tokens")
+		usage.TotalTokens = firstInt(scope, usage.TotalTokens, "total_tokens")
+		if v, isNum := firstNumber(scope, "cost_usd", "cost", "total_cost"); isNum {
+			usage.CostUSD = v
+		}
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return usage, ok
+}
+
+func firstInt(scope map[string]any, current int, keys ...string) int {
+	if v, ok := firstNumber(scope, keys...); ok {
+		return int(v)
+	}
+	return current
+}
+
+func firstNumber(scope map[string]any, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if v, ok := scope[key].(float64); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// UsageRecord is one ledger line: a finished Aloha task including all retries.
+type UsageRecord struct {
+	Time      time.Time     `json:"time"`
+	Namespace string        `json:"namespace"`
+	JobID     string        `json:"job_id,omitempty"`
+	TraceID   string        `json:"trace_id"`
+	Template  string        `json:"template,omitempty"`
+	Outcome   string        `json:"outcome"`
+	Duration  time.Duration `json:"duration"`
+	Usage
+}
+
+// UsageLedgerPath returns the local JSONL file that accumulates Aloha usage. It
+// only holds this host's runs, so the daily step budget read from it is per host.
+func UsageLedgerPath(cfg config.Config) string {
+	return filepath.Join(cfg.ArtifactOutDir, "usage", "aloha.jsonl")
+}
+
+var ledgerMu sync.Mutex
+
+// AppendUsage appends rec to the ledger at path.
+func AppendUsage(path string, rec UsageRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadUsage reads ledger records at or after since. A missing ledger is empty.
+func LoadUsage(path string, since time.Time) ([]UsageRecord, error) {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if !rec.Time.Before(since) {
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// StepsUsedToday sums the steps charged to namespace since midnight UTC.
+func StepsUsedToday(path, namespace string, now time.Time) (int, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	records, err := LoadUsage(path, day)
+	if err != nil {
+		return 0, err
+	}
+	steps := 0
+	for _, rec := range records {
+		if rec.Namespace == namespace {
+			steps += rec.Steps
+		}
+	}
+	return steps, nil
+}
+
+// UsageSummary aggregates ledger records sharing a template, trace ID and outcome.
+type UsageSummary struct {
+	Template string        `json:"template"`
+	TraceID  string        `json:"trace_id"`
+	Outcome  string        `json:"outcome"`
+	Runs     int           `json:"runs"`
+	Duration time.Duration `json:"duration"`
+	Usage
+}
+
+// SummarizeUsage groups records by template, trace ID and outcome, ordered by
+// descending step count.
+func SummarizeUsage(records []UsageRecord) []UsageSummary {
+	type key struct{ template, traceID, outcome string }
+	groups := map[key]*UsageSummary{}
+	for _, rec := range records {
+		k := key{rec.Template, rec.TraceID, rec.Outcome}
+		sum, ok := groups[k]
+		if !ok {
+			sum = &UsageSummary{Template: rec.Template, TraceID: rec.TraceID, Outcome: rec.Outcome}
+			groups[k] = sum
+		}
+		sum.Runs++
+		sum.Duration += rec.Duration
+		sum.Usage.Add(rec.Usage)
+	}
+
+	out := make([]UsageSummary, 0, len(groups))
+	for _, sum := range groups {
+		out = append(out, *sum)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Steps != out[j].Steps {
+			return out[i].Steps > out[j].Steps
+		}
+		if out[i].Template != out[j].Template {
+			return out[i].Template < out[j].Template
+		}
+		if out[i].TraceID != out[j].TraceID {
+			return out[i].TraceID < out[j].TraceID
+		}
+		return out[i].Outcome < out[j].Outcome
+	})
+	return out
+}
@@ -24,17 +24,22 @@ type Manifest struct {
 	TraceID   string     `json:"trace_id"`
 	CreatedAt time.Time  `json:"created_at"`
 	Artifacts []Artifact `json:"artifacts"`
+	// Metadata holds job context that is not itself an artifact (e.g. the display
+	// layout an Aloha run was validated against).
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 // WriteManifest writes a manifest.json file to the given root directory.
 func WriteManifest(root string, jobID, traceID string, artifacts []Artifact) error {
-	m := Manifest{
+	return writeManifest(root, Manifest{
 		JobID:     jobID,
 		TraceID:   traceID,
 		CreatedAt: time.Now().UTC(),
 		Artifacts: artifacts,
-	}
+	})
+}
 
+func writeManifest(root string, m Manifest) error {
 	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return err
@@ -93,3 +98,97 @@ func BuildArtifact(root, relPath, artifactType string) (Artifact, error) {
 		SHA256:    hash,
 	}, nil
 }
+
+// Recorder writes artifact files under a job root and tracks them for the manifest.
+// A nil Recorder discards everything, so callers can record unconditionally.
+type Recorder struct {
+	root      string
+	jobID     string
+	traceID   string
+	artifacts []Artifact
+	metadata  map[string]any
+}
+
+// NewRecorder returns a Recorder rooted at <outDir>/<jobID>.
+func NewRecorder(outDir, jobID, traceID string) *Recorder {
+	return &Recorder{
+		root:    filepath.Join(outDir, jobID),
+		jobID:   jobID,
+		traceID: traceID,
+	}
+}
+
+// Root returns the job artifact directory.
+func (r *Recorder) Root() string {
+	if r == nil {
+		return ""
+	}
+	return r.root
+}
+
+// JobID returns the job the artifacts belong to.
+func (r *Recorder) JobID() string {
+	if r == nil {
+		return ""
+	}
+	return r.jobID
+}
+
+// Write stores data at relPath below the job root and records it as an artifact.
+func (r *Recorder) Write(relPath, artifactType string, data []byte) error {
+	if r == nil {
+		return nil
+	}
+	fullPath := filepath.Join(r.root, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return err
+	}
+	art, err := BuildArtifact(r.root, filepath.ToSlash(relPath), artifactType)
+	if err != nil {
+		return err
+	}
+	r.artifacts = append(r.artifacts, art)
+	return nil
+}
+
+// Add records a file already placed at relPath below the job root.
+func (r *Recorder) Add(relPath, artifactType string) error {
+	if r == nil {
+		return nil
+	}
+	art, err := BuildArtifact(r.root, filepath.ToSlash(relPath), artifactType)
+	if err != nil {
+		return err
+	}
+	r.artifacts = append(r.artifacts, art)
+	return nil
+}
+
+// SetMetadata records a manifest metadata entry, replacing any previous value.
+func (r *Recorder) SetMetadata(key string, value any) {
+	if r == nil {
+		return
+	}
+	if r.metadata == nil {
+		r.metadata = map[string]any{}
+	}
+	r.metadata[key] = value
+}
+
+// Close writes manifest.json for the recorded artifacts. Nothing is written when
+// no artifacts were recorded.
+func (r *Recorder) Close() error {
+	if r == nil || len(r.artifacts) == 0 {
+		return nil
+	}
+	return writeManifest(r.root, Manifest{
+		JobID:     r.jobID,
+		TraceID:   r.traceID,
+		CreatedAt: time.Now().UTC(),
+		Artifacts: r.artifacts,
+		Metadata:  r.metadata,
+	})
+}
@@ -0,0 +1,199 @@
+// Package component installs, repairs and removes what win-automation places on
+// Windows for a service: files in an install directory, scheduled tasks and
+// firewall rules.
+package component
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// The SSH calls are replaced in tests that run without a Windows host.
+var (
+	runSSH     = sshx.Run
+	uploadFile = sshx.Upload
+)
+
+// Component describes the pieces of one service on Windows.
+type Component struct {
+	Name     string
+	Dir      string // removed with everything in it on uninstall
+	Files    []File
+	Tasks    []Task
+	Firewall []FirewallRule
+}
+
+// File is uploaded to Dir\Name.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Task is a scheduled task. Register is the remote command that (re)creates it and
+// may rely on the component's files being in place.
+type Task struct {
+	Name     string
+	Register string
+}
+
+// FirewallRule allows inbound TCP on Port.
+type FirewallRule struct {
+	Name string
+	Port int
+}
+
+// Kinds of Change.
+const (
+	KindFile     = "file"
+	KindTask     = "task"
+	KindFirewall = "firewall"
+	KindDir      = "dir"
+)
+
+// Change states.
+const (
+	StateOK         = "ok"         // matched the expected state; nothing done
+	StateInstalled  = "installed"  // created or uploaded
+	StateDrifted    = "drifted"    // differed from the expected content and was replaced
+	StateMissing    = "missing"    // absent and was recreated
+	StateRegistered = "registered" // task (re)registered
+	StateRemoved    = "removed"
+)
+
+// Change reports what an operation did to one piece of a component.
+type Change struct {
+	Kind  string
+	Name  string
+	State string
+}
+
+// Install uploads the files, registers the tasks and ensures the firewall rules.
+func Install(ctx context.Context, cfg config.Config, c Component, report func(Change)) error {
+	if err := ensureDir(ctx, cfg, c.Dir); err != nil {
+		return err
+	}
+	for _, f := range c.Files {
+		if err := upload(ctx, cfg, f.Data, c.path(f)); err != nil {
+			return fmt.Errorf("upload %s: %w", f.Name, err)
+		}
+		report(Change{Kind: KindFile, Name: f.Name, State: StateInstalled})
+	}
+	return registerAndOpen(ctx, cfg, c, report)
+}
+
+// Repair compares each installed file with the expected content by SHA-256 and
+// re-uploads the ones that drifted or are missing, then re-registers the tasks and
+// ensures the firewall rules.
+func Repair(ctx context.Context, cfg config.Config, c Component, report func(Change)) error {
+	if err := ensureDir(ctx, cfg, c.Dir); err != nil {
+		return err
+	}
+	for _, f := range c.Files {
+		res, err := runSSH(ctx, cfg, win.FileSHA256(c.path(f)))
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", f.Name, err)
+		}
+		state := StateOK
+		switch remote := strings.TrimSpace(res.Stdout); {
+		case remote == "":
+			state = StateMissing
+		case remote != Hash(f.Data):
+			state = StateDrifted
+		}
+		if state != StateOK {
+			if err := upload(ctx, cfg, f.Data, c.path(f)); err != nil {
+				return fmt.Errorf("upload %s: %w", f.Name, err)
+			}
+		}
+		report(Change{Kind: KindFile, Name: f.Name, State: state})
+	}
+	return registerAndOpen(ctx, cfg, c, report)
+}
+
+// Uninstall stops and removes the tasks, deletes the firewall rules and removes Dir.
+// Pieces that are already gone are not an error, so it can be rerun.
+func Uninstall(ctx context.Context, cfg config.Config, c Component, report func(Change)) error {
+	for _, t := range c.Tasks {
+		if res, err := runSSH(ctx, cfg, win.ScheduledTaskUnregister(t.Name)); err != nil {
+			return fmt.Errorf("remove task %s: %w: %s", t.Name, err, strings.TrimSpace(res.Stderr))
+		}
+		report(Change{Kind: KindTask, Name: t.Name, State: StateRemoved})
+	}
+	for _, rule := range c.Firewall {
+		if res, err := runSSH(ctx, cfg, win.FirewallRuleRemove(rule.Name)); err != nil {
+			return fmt.Errorf("remove firewall rule %s: %w: %s", rule.Name, err, strings.TrimSpace(res.Stderr))
+		}
+		report(Change{Kind: KindFirewall, Name: rule.Name, State: StateRemoved})
+	}
+	if c.Dir != "" {
+		if res, err := runSSH(ctx, cfg, win.RemoveDirectory(c.Dir)); err != nil {
+			return fmt.Errorf("remove %s: %w: %s", c.Dir, err, strings.TrimSpace(res.Stderr))
+		}
+		report(Change{Kind: KindDir, Name: c.Dir, State: StateRemoved})
+	}
+	return nil
+}
+
+// Hash returns the lowercase hex SHA-256 of data, as win.FileSHA256 prints it.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func registerAndOpen(ctx context.Context, cfg config.Config, c Component, report func(Change)) error {
+	for _, t := range c.Tasks {
+		if res, err := runSSH(ctx, cfg, t.Register); err != nil {
+			return fmt.Errorf("register task %s: %w: %s", t.Name, err, strings.TrimSpace(res.Stderr))
+		}
+		report(Change{Kind: KindTask, Name: t.Name, State: StateRegistered})
+	}
+	for _, rule := range c.Firewall {
+		res, err := runSSH(ctx, cfg, win.FirewallRuleEnsure(rule.Name, rule.Port))
+		if err != nil {
+			return fmt.Errorf("firewall rule %s: %w: %s", rule.Name, err, strings.TrimSpace(res.Stderr))
+		}
+		state := StateOK
+		if out := strings.TrimSpace(res.Stdout); out == "created" || out == "enabled" {
+			state = StateInstalled
+		}
+		report(Change{Kind: KindFirewall, Name: rule.Name, State: state})
+	}
+	return nil
+}
+
+func (c Component) path(f File) string {
+	return c.Dir + `\` + f.Name
+}
+
+func ensureDir(ctx context.Context, cfg config.Config, dir string) error {
+	cmd := fmt.Sprintf("New-Item -ItemType Directory -Force -Path '%s' | Out-Null", strings.ReplaceAll(dir, "'", "''"))
+	if _, err := runSSH(ctx, cfg, win.PowerShellCommand(cmd)); err != nil {
+		return fmt.Errorf("prepare %s: %w", dir, err)
+	}
+	return nil
+}
+
+func upload(ctx context.Context, cfg config.Config, data []byte, remotePath string) error {
+	tmp, err := os.CreateTemp("", "component-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return uploadFile(ctx, cfg, tmp.Name(), remotePath)
+}
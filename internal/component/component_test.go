@@ -0,0 +1,108 @@
+package component
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+func TestHash(t *testing.T) {
+	// sha256("abc"), lowercase like Get-FileHash output after ToLower().
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := Hash([]byte("abc")); got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+}
+
+// stubWindows answers scripts from replies (exit 0 with empty output when
+// absent) and records the uploaded remote paths with their content.
+func stubWindows(t *testing.T, replies map[string]sshx.Result) map[string]string {
+	t.Helper()
+	uploads := map[string]string{}
+	origRun, origUpload := runSSH, uploadFile
+	runSSH = func(_ context.Context, _ config.Config, script string) (sshx.Result, error) {
+		res := replies[script]
+		if res.ExitCode != 0 {
+			return res, &sshx.ExitError{Code: res.ExitCode}
+		}
+		return res, nil
+	}
+	uploadFile = func(_ context.Context, _ config.Config, local, remote string) error {
+		data, err := os.ReadFile(local)
+		uploads[remote] = string(data)
+		return err
+	}
+	t.Cleanup(func() { runSSH, uploadFile = origRun, origUpload })
+	return uploads
+}
+
+func testComponent() Component {
+	return Component{
+		Name: "svc",
+		Dir:  `C:\svc`,
+		Files: []File{
+			{Name: "same.js", Data: []byte("same")},
+			{Name: "drifted.js", Data: []byte("new")},
+			{Name: "missing.js", Data: []byte("missing")},
+		},
+		Tasks:    []Task{{Name: "svc-task", Register: "register svc-task"}},
+		Firewall: []FirewallRule{{Name: "svc-rule", Port: 8080}},
+	}
+}
+
+func TestRepair(t *testing.T) {
+	c := testComponent()
+	uploads := stubWindows(t, map[string]sshx.Result{
+		win.FileSHA256(`C:\svc\same.js`):         {Stdout: Hash([]byte("same")) + "\r\n"},
+		win.FileSHA256(`C:\svc\drifted.js`):      {Stdout: Hash([]byte("old")) + "\r\n"},
+		win.FirewallRuleEnsure("svc-rule", 8080): {Stdout: "created\r\n"},
+	})
+
+	var changes []Change
+	if err := Repair(context.Background(), config.Config{}, c, func(ch Change) { changes = append(changes, ch) }); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	want := []Change{
+		{Kind: KindFile, Name: "same.js", State: StateOK},
+		{Kind: KindFile, Name: "drifted.js", State: StateDrifted},
+		{Kind: KindFile, Name: "missing.js", State: StateMissing},
+		{Kind: KindTask, Name: "svc-task", State: StateRegistered},
+		{Kind: KindFirewall, Name: "svc-rule", State: StateInstalled},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	wantUploads := map[string]string{`C:\svc\drifted.js`: "new", `C:\svc\missing.js`: "missing"}
+	if !reflect.DeepEqual(uploads, wantUploads) {
+		t.Errorf("uploads = %v, want %v", uploads, wantUploads)
+	}
+}
+
+func TestRepair_HashFailure(t *testing.T) {
+	c := testComponent()
+	stubWindows(t, map[string]sshx.Result{win.FileSHA256(`C:\svc\same.js`): {ExitCode: 255}})
+	if err := Repair(context.Background(), config.Config{}, c, func(Change) {}); err == nil {
+		t.Error("Repair() error = nil, want hash error")
+	}
+}
+
+func TestUninstall_DirNotRemoved(t *testing.T) {
+	c := testComponent()
+	stubWindows(t, map[string]sshx.Result{win.RemoveDirectory(`C:\svc`): {ExitCode: 1, Stderr: `C:\svc still exists`}})
+
+	var changes []Change
+	err := Uninstall(context.Background(), config.Config{}, c, func(ch Change) { changes = append(changes, ch) })
+	if err == nil {
+		t.Fatal("Uninstall() error = nil, want error for a directory left behind")
+	}
+	for _, ch := range changes {
+		if ch.Kind == KindDir {
+			t.Errorf("reported %+v although the directory was not removed", ch)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,17 +20,34 @@ type Config struct {
 	WindowsSSHUser         string
 	WindowsSSHIdentityFile string
 
-	AlohaServerURL      string
-	AlohaClientURL      string
-	AlohaServerStartCmd string
-	AlohaClientStartCmd string
-
-	PlaywrightHost string
-	PlaywrightPort int
+	AlohaServerURL       string
+	AlohaClientURL       string
+	AlohaServerStartCmd  string
+	AlohaClientStartCmd  string
+	AlohaTaskBudget      time.Duration // Wall-clock budget per Aloha task, verification included (default 15m)
+	AlohaDailyStepBudget int           // Max Aloha steps per UTC day and namespace on this host; 0 disables (default 0)
+
+	PlaywrightHost        string
+	PlaywrightPort        int
+	PlaywrightWSPath      string   // WebSocket path secret (env or ws_path_file; never logged)
+	PlaywrightWSPathFile  string   // File holding the ws path; written by install and rotate-secret
+	PlaywrightBrowser     string   // Default browser for scripts and health checks (default chromium)
+	PlaywrightBrowsers    []string // Browser servers installed and supervised on Windows (default [PlaywrightBrowser])
+	PlaywrightVersion     string   // Client version the Windows server must match (major.minor[.patch]); empty detects the local package
+	PlaywrightCacheDir    string   // Local directory with the Node.js installer and package/browser archives for install --bootstrap
+	PlaywrightTrace       string   // Trace policy for runs: off, on or retain-on-failure (default off)
+	PlaywrightVideo       string   // Video policy for runs (default off)
+	PlaywrightScreenshot  string   // Final screenshot policy for runs (default off)
+	PlaywrightMaxSessions int      // Playwright jobs one worker runs at once (default 4)
 
 	ArtifactOutDir        string
 	ArtifactRetentionDays int
 
+	TasksDir string // Directory of named task templates (*.json)
+
+	DesktopLockTimeout time.Duration // Max wait for the exclusive desktop lease (default 10m)
+	DesktopLockTTL     time.Duration // Lease expiry when not renewed (default 5m)
+
 	Timeout time.Duration
 
 	// Hatchet-lite configuration
@@ -41,6 +62,16 @@ type Config struct {
 	HatchetJobTimeout        time.Duration // Default job timeout (default 10m)
 	HatchetRetryMax          int           // Max retry attempts (default 3)
 	HatchetRetryBackoff      time.Duration // Backoff between retries (default 5s)
+	HatchetScheduler         string        // Where schedules run: hatchet (cron triggers) or local (the worker's scheduler) (default hatchet)
+	HatchetScheduleFile      string        // Schedule file for the local scheduler (default ./schedules.json)
+	HatchetDedupWindow       time.Duration // How long an idempotency key returns its job instead of enqueueing (default 24h)
+	HatchetIdempotencyStore  string        // Where idempotency keys live: hatchet (run metadata) or local (a file) (default hatchet)
+	HatchetIdempotencyFile   string        // Key file for the local idempotency store (default ./idempotency.json)
+
+	// Job concurrency limits, keyed by job type; unset types are bound only by
+	// HatchetWorkerConcurrency.
+	HatchetConcurrency       map[string]int              // Max running jobs of the type, e.g. {"aloha.run": 1}
+	HatchetConcurrencyGroups map[string]ConcurrencyGroup // Max running jobs of the type sharing a payload field value
 }
 
 func LoadFromEnv() (Config, error) {
@@ -80,10 +111,12 @@ type fileConfig struct {
 		SSHIdentityFile *string `json:"ssh_identity_file"`
 	} `json:"windows"`
 	Aloha struct {
-		ServerURL      *string `json:"server_url"`
-		ClientURL      *string `json:"client_url"`
-		ServerStartCmd *string `json:"server_start_cmd"`
-		ClientStartCmd *string `json:"client_start_cmd"`
+		ServerURL       *string `json:"server_url"`
+		ClientURL       *string `json:"client_url"`
+		ServerStartCmd  *string `json:"server_start_cmd"`
+		ClientStartCmd  *string `json:"client_start_cmd"`
+		TaskBudget      *string `json:"task_budget"`
+		DailyStepBudget *int    `json:"daily_step_budget"`
 	} `json:"aloha"`
 	Hatchet struct {
 		HTTPURL           *string `json:"http_url"`
@@ -96,15 +129,39 @@ type fileConfig struct {
 		JobTimeout        *string `json:"job_timeout"`
 		RetryMax          *int    `json:"retry_max"`
 		RetryBackoff      *string `json:"retry_backoff"`
+		Scheduler         *string `json:"scheduler"`
+		ScheduleFile      *string `json:"schedule_file"`
+		DedupWindow       *string `json:"dedup_window"`
+		IdempotencyStore  *string `json:"idempotency_store"`
+		IdempotencyFile   *string `json:"idempotency_file"`
+
+		Concurrency       *map[string]int              `json:"concurrency"`
+		ConcurrencyGroups *map[string]ConcurrencyGroup `json:"concurrency_groups"`
 	} `json:"hatchet"`
 	Playwright struct {
-		Host *string `json:"host"`
-		Port *int    `json:"port"`
+		Host        *string   `json:"host"`
+		Port        *int      `json:"port"`
+		WSPathFile  *string   `json:"ws_path_file"`
+		Version     *string   `json:"version"`
+		Browser     *string   `json:"browser"`
+		Browsers    *[]string `json:"browsers"`
+		CacheDir    *string   `json:"cache_dir"`
+		Trace       *string   `json:"trace"`
+		Video       *string   `json:"video"`
+		Screenshot  *string   `json:"screenshot"`
+		MaxSessions *int      `json:"max_sessions"`
 	} `json:"playwright"`
 	Artifacts struct {
 		OutDir        *string `json:"out_dir"`
 		RetentionDays *int    `json:"retention_days"`
 	} `json:"artifacts"`
+	Tasks struct {
+		Dir *string `json:"dir"`
+	} `json:"tasks"`
+	Desktop struct {
+		LockTimeout *string `json:"lock_timeout"`
+		LockTTL     *string `json:"lock_ttl"`
+	} `json:"desktop"`
 	Timeout *string `json:"timeout"`
 }
 
@@ -117,10 +174,19 @@ func defaultConfig() Config {
 		AlohaClientURL:        "http://127.0.0.1:7888",
 		AlohaServerStartCmd:   "",
 		AlohaClientStartCmd:   "",
+		AlohaTaskBudget:       15 * time.Minute,
 		PlaywrightHost:        "127.0.0.1",
 		PlaywrightPort:        9323,
+		PlaywrightBrowser:     "chromium",
+		PlaywrightTrace:       "off",
+		PlaywrightVideo:       "off",
+		PlaywrightScreenshot:  "off",
+		PlaywrightMaxSessions: 4,
 		ArtifactOutDir:        "./artifacts",
 		ArtifactRetentionDays: 7,
+		TasksDir:              "./tasks",
+		DesktopLockTimeout:    10 * time.Minute,
+		DesktopLockTTL:        5 * time.Minute,
 		Timeout:               10 * time.Second,
 
 		HatchetHTTPURL:           "http://127.0.0.1:8888",
@@ -134,6 +200,11 @@ func defaultConfig() Config {
 		HatchetJobTimeout:        10 * time.Minute,
 		HatchetRetryMax:          3,
 		HatchetRetryBackoff:      5 * time.Second,
+		HatchetScheduler:         "hatchet",
+		HatchetScheduleFile:      "./schedules.json",
+		HatchetDedupWindow:       24 * time.Hour,
+		HatchetIdempotencyStore:  "hatchet",
+		HatchetIdempotencyFile:   "./idempotency.json",
 	}
 }
 
@@ -175,6 +246,16 @@ func applyFileConfig(cfg *Config, fileCfg fileConfig) error {
 	if fileCfg.Aloha.ClientStartCmd != nil {
 		cfg.AlohaClientStartCmd = *fileCfg.Aloha.ClientStartCmd
 	}
+	if fileCfg.Aloha.TaskBudget != nil {
+		d, err := time.ParseDuration(*fileCfg.Aloha.TaskBudget)
+		if err != nil {
+			return configError("aloha.task_budget", "must be a duration (e.g. 15m)")
+		}
+		cfg.AlohaTaskBudget = d
+	}
+	if fileCfg.Aloha.DailyStepBudget != nil {
+		cfg.AlohaDailyStepBudget = *fileCfg.Aloha.DailyStepBudget
+	}
 
 	if fileCfg.Hatchet.HTTPURL != nil {
 		cfg.HatchetHTTPURL = *fileCfg.Hatchet.HTTPURL
@@ -214,6 +295,31 @@ func applyFileConfig(cfg *Config, fileCfg fileConfig) error {
 		}
 		cfg.HatchetRetryBackoff = d
 	}
+	if fileCfg.Hatchet.Scheduler != nil {
+		cfg.HatchetScheduler = *fileCfg.Hatchet.Scheduler
+	}
+	if fileCfg.Hatchet.ScheduleFile != nil {
+		cfg.HatchetScheduleFile = *fileCfg.Hatchet.ScheduleFile
+	}
+	if fileCfg.Hatchet.DedupWindow != nil {
+		d, err := time.ParseDuration(*fileCfg.Hatchet.DedupWindow)
+		if err != nil {
+			return configError("hatchet.dedup_window", "must be a duration (e.g. 24h)")
+		}
+		cfg.HatchetDedupWindow = d
+	}
+	if fileCfg.Hatchet.IdempotencyStore != nil {
+		cfg.HatchetIdempotencyStore = *fileCfg.Hatchet.IdempotencyStore
+	}
+	if fileCfg.Hatchet.IdempotencyFile != nil {
+		cfg.HatchetIdempotencyFile = *fileCfg.Hatchet.IdempotencyFile
+	}
+	if fileCfg.Hatchet.Concurrency != nil {
+		cfg.HatchetConcurrency = *fileCfg.Hatchet.Concurrency
+	}
+	if fileCfg.Hatchet.ConcurrencyGroups != nil {
+		cfg.HatchetConcurrencyGroups = *fileCfg.Hatchet.ConcurrencyGroups
+	}
 
 	if fileCfg.Playwright.Host != nil {
 		cfg.PlaywrightHost = *fileCfg.Playwright.Host
@@ -221,6 +327,33 @@ func applyFileConfig(cfg *Config, fileCfg fileConfig) error {
 	if fileCfg.Playwright.Port != nil {
 		cfg.PlaywrightPort = *fileCfg.Playwright.Port
 	}
+	if fileCfg.Playwright.WSPathFile != nil {
+		cfg.PlaywrightWSPathFile = *fileCfg.Playwright.WSPathFile
+	}
+	if fileCfg.Playwright.Version != nil {
+		cfg.PlaywrightVersion = *fileCfg.Playwright.Version
+	}
+	if fileCfg.Playwright.Browser != nil {
+		cfg.PlaywrightBrowser = *fileCfg.Playwright.Browser
+	}
+	if fileCfg.Playwright.Browsers != nil {
+		cfg.PlaywrightBrowsers = *fileCfg.Playwright.Browsers
+	}
+	if fileCfg.Playwright.CacheDir != nil {
+		cfg.PlaywrightCacheDir = *fileCfg.Playwright.CacheDir
+	}
+	if fileCfg.Playwright.Trace != nil {
+		cfg.PlaywrightTrace = *fileCfg.Playwright.Trace
+	}
+	if fileCfg.Playwright.Video != nil {
+		cfg.PlaywrightVideo = *fileCfg.Playwright.Video
+	}
+	if fileCfg.Playwright.Screenshot != nil {
+		cfg.PlaywrightScreenshot = *fileCfg.Playwright.Screenshot
+	}
+	if fileCfg.Playwright.MaxSessions != nil {
+		cfg.PlaywrightMaxSessions = *fileCfg.Playwright.MaxSessions
+	}
 
 	if fileCfg.Artifacts.OutDir != nil {
 		cfg.ArtifactOutDir = *fileCfg.Artifacts.OutDir
@@ -229,6 +362,25 @@ func applyFileConfig(cfg *Config, fileCfg fileConfig) error {
 		cfg.ArtifactRetentionDays = *fileCfg.Artifacts.RetentionDays
 	}
 
+	if fileCfg.Tasks.Dir != nil {
+		cfg.TasksDir = *fileCfg.Tasks.Dir
+	}
+
+	if fileCfg.Desktop.LockTimeout != nil {
+		d, err := time.ParseDuration(*fileCfg.Desktop.LockTimeout)
+		if err != nil {
+			return configError("desktop.lock_timeout", "must be a duration (e.g. 10m)")
+		}
+		cfg.DesktopLockTimeout = d
+	}
+	if fileCfg.Desktop.LockTTL != nil {
+		d, err := time.ParseDuration(*fileCfg.Desktop.LockTTL)
+		if err != nil {
+			return configError("desktop.lock_ttl", "must be a duration (e.g. 5m)")
+		}
+		cfg.DesktopLockTTL = d
+	}
+
 	if fileCfg.Timeout != nil {
 		d, err := time.ParseDuration(*fileCfg.Timeout)
 		if err != nil {
@@ -270,6 +422,20 @@ func applyEnvOverrides(cfg *Config) error {
 	if v := os.Getenv("WIN_AUTOMATION_ALOHA_CLIENT_START_CMD"); v != "" {
 		cfg.AlohaClientStartCmd = v
 	}
+	if v := os.Getenv("WIN_AUTOMATION_ALOHA_TASK_BUDGET"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_ALOHA_TASK_BUDGET must be a duration (e.g. 15m): %w", err)
+		}
+		cfg.AlohaTaskBudget = d
+	}
+	if v := os.Getenv("WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET must be an int: %w", err)
+		}
+		cfg.AlohaDailyStepBudget = n
+	}
 	if v := os.Getenv("WIN_AUTOMATION_TIMEOUT"); v != "" {
 		d, err := time.ParseDuration(v)
 		if err != nil {
@@ -327,6 +493,39 @@ func applyEnvOverrides(cfg *Config) error {
 		}
 		cfg.HatchetRetryBackoff = d
 	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_SCHEDULER"); v != "" {
+		cfg.HatchetScheduler = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_SCHEDULE_FILE"); v != "" {
+		cfg.HatchetScheduleFile = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_DEDUP_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_HATCHET_DEDUP_WINDOW must be a duration (e.g. 24h): %w", err)
+		}
+		cfg.HatchetDedupWindow = d
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_STORE"); v != "" {
+		cfg.HatchetIdempotencyStore = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_FILE"); v != "" {
+		cfg.HatchetIdempotencyFile = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_CONCURRENCY"); v != "" {
+		limits, err := parseConcurrency(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_HATCHET_CONCURRENCY must be type=max pairs (e.g. aloha.run=1,windows.exec=4): %w", err)
+		}
+		cfg.HatchetConcurrency = limits
+	}
+	if v := os.Getenv("WIN_AUTOMATION_HATCHET_CONCURRENCY_GROUPS"); v != "" {
+		groups, err := parseConcurrencyGroups(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_HATCHET_CONCURRENCY_GROUPS must be type=field:max pairs (e.g. aloha.run=screen:1): %w", err)
+		}
+		cfg.HatchetConcurrencyGroups = groups
+	}
 
 	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_HOST"); v != "" {
 		cfg.PlaywrightHost = v
@@ -338,6 +537,45 @@ func applyEnvOverrides(cfg *Config) error {
 		}
 		cfg.PlaywrightPort = p
 	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH_FILE"); v != "" {
+		cfg.PlaywrightWSPathFile = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH"); v != "" {
+		cfg.PlaywrightWSPath = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_VERSION"); v != "" {
+		cfg.PlaywrightVersion = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSER"); v != "" {
+		cfg.PlaywrightBrowser = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSERS"); v != "" {
+		cfg.PlaywrightBrowsers = nil
+		for _, browser := range strings.Split(v, ",") {
+			if browser = strings.TrimSpace(browser); browser != "" {
+				cfg.PlaywrightBrowsers = append(cfg.PlaywrightBrowsers, browser)
+			}
+		}
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_CACHE_DIR"); v != "" {
+		cfg.PlaywrightCacheDir = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_TRACE"); v != "" {
+		cfg.PlaywrightTrace = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_VIDEO"); v != "" {
+		cfg.PlaywrightVideo = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_SCREENSHOT"); v != "" {
+		cfg.PlaywrightScreenshot = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS must be an int: %w", err)
+		}
+		cfg.PlaywrightMaxSessions = n
+	}
 	if v := os.Getenv("WIN_AUTOMATION_ARTIFACT_OUT"); v != "" {
 		cfg.ArtifactOutDir = v
 	}
@@ -348,6 +586,34 @@ func applyEnvOverrides(cfg *Config) error {
 		}
 		cfg.ArtifactRetentionDays = n
 	}
+	if v := os.Getenv("WIN_AUTOMATION_TASKS_DIR"); v != "" {
+		cfg.TasksDir = v
+	}
+	if v := os.Getenv("WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT must be a duration (e.g. 10m): %w", err)
+		}
+		cfg.DesktopLockTimeout = d
+	}
+	if v := os.Getenv("WIN_AUTOMATION_DESKTOP_LOCK_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("WIN_AUTOMATION_DESKTOP_LOCK_TTL must be a duration (e.g. 5m): %w", err)
+		}
+		cfg.DesktopLockTTL = d
+	}
+
+	if cfg.PlaywrightWSPath == "" && cfg.PlaywrightWSPathFile != "" {
+		secret, err := ReadSecretFile(cfg.PlaywrightWSPathFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return configError("playwright.ws_path_file", fmt.Sprintf("unreadable: %v", err))
+		}
+		cfg.PlaywrightWSPath = secret
+	}
+	if len(cfg.PlaywrightBrowsers) == 0 {
+		cfg.PlaywrightBrowsers = []string{cfg.PlaywrightBrowser}
+	}
 
 	return nil
 }
@@ -400,12 +666,57 @@ func validateConfig(cfg Config) error {
 	if err := validateDuration("hatchet.retry_backoff", cfg.HatchetRetryBackoff); err != nil {
 		return err
 	}
+	if cfg.HatchetDedupWindow < time.Minute || cfg.HatchetDedupWindow > 30*24*time.Hour {
+		return configError("hatchet.dedup_window", "must be between 1m and 720h")
+	}
+	if err := validateDuration("desktop.lock_timeout", cfg.DesktopLockTimeout); err != nil {
+		return err
+	}
+	if err := validateDuration("desktop.lock_ttl", cfg.DesktopLockTTL); err != nil {
+		return err
+	}
+	if err := validateDuration("aloha.task_budget", cfg.AlohaTaskBudget); err != nil {
+		return err
+	}
+	if cfg.AlohaDailyStepBudget < 0 {
+		return configError("aloha.daily_step_budget", "must not be negative")
+	}
+	if err := validateWSPath("playwright.ws_path", cfg.PlaywrightWSPath); err != nil {
+		return err
+	}
+	if err := validateVersion("playwright.version", cfg.PlaywrightVersion); err != nil {
+		return err
+	}
+	for _, policy := range []struct{ field, value string }{
+		{"playwright.trace", cfg.PlaywrightTrace},
+		{"playwright.video", cfg.PlaywrightVideo},
+		{"playwright.screenshot", cfg.PlaywrightScreenshot},
+	} {
+		if !slices.Contains(playwrightPolicies, policy.value) {
+			return configError(policy.field, fmt.Sprintf("must be one of %s", strings.Join(playwrightPolicies, ", ")))
+		}
+	}
+	if err := validateBrowsers(cfg.PlaywrightBrowser, cfg.PlaywrightBrowsers); err != nil {
+		return err
+	}
+	if cfg.PlaywrightMaxSessions < 1 || cfg.PlaywrightMaxSessions > 32 {
+		return configError("playwright.max_sessions", "must be between 1 and 32")
+	}
 	if cfg.HatchetWorkerConcurrency < 1 || cfg.HatchetWorkerConcurrency > 100 {
 		return configError("hatchet.worker_concurrency", "must be between 1 and 100")
 	}
 	if cfg.HatchetRetryMax < 0 || cfg.HatchetRetryMax > 10 {
 		return configError("hatchet.retry_max", "must be between 0 and 10")
 	}
+	if cfg.HatchetScheduler != "hatchet" && cfg.HatchetScheduler != "local" {
+		return configError("hatchet.scheduler", "must be hatchet or local")
+	}
+	if cfg.HatchetIdempotencyStore != "hatchet" && cfg.HatchetIdempotencyStore != "local" {
+		return configError("hatchet.idempotency_store", "must be hatchet or local")
+	}
+	if err := validateConcurrency(cfg.HatchetConcurrency, cfg.HatchetConcurrencyGroups); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -431,6 +742,155 @@ func validateDuration(field string, value time.Duration) error {
 	return nil
 }
 
+// ConcurrencyGroup caps the running jobs of a type that share the value of
+// payload field Key, e.g. one job per screen.
+type ConcurrencyGroup struct {
+	Key     string `json:"key"`
+	MaxRuns int    `json:"max_runs"`
+}
+
+// hatchetJobTypes are the job types concurrency limits apply to.
+var hatchetJobTypes = []string{"windows.exec", "aloha.run", "playwright.run", "playwright.capture"}
+
+var payloadFieldPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// parseConcurrency parses comma-separated "type=max" pairs.
+func parseConcurrency(v string) (map[string]int, error) {
+	limits := map[string]int{}
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		jobType, limit, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q has no '='", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(jobType)] = n
+	}
+	return limits, nil
+}
+
+// parseConcurrencyGroups parses comma-separated "type=field:max" pairs.
+func parseConcurrencyGroups(v string) (map[string]ConcurrencyGroup, error) {
+	groups := map[string]ConcurrencyGroup{}
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		jobType, group, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q has no '='", pair)
+		}
+		key, limit, ok := strings.Cut(group, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q has no ':'", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, err
+		}
+		groups[strings.TrimSpace(jobType)] = ConcurrencyGroup{Key: strings.TrimSpace(key), MaxRuns: n}
+	}
+	return groups, nil
+}
+
+// validateConcurrency checks that limits name known job types and allow 1 to
+// 100 running jobs, and that group keys are payload field names.
+func validateConcurrency(limits map[string]int, groups map[string]ConcurrencyGroup) error {
+	for jobType, n := range limits {
+		if !slices.Contains(hatchetJobTypes, jobType) {
+			return configError("hatchet.concurrency", fmt.Sprintf("unknown job type %q (want %s)", jobType, strings.Join(hatchetJobTypes, ", ")))
+		}
+		if n < 1 || n > 100 {
+			return configError("hatchet.concurrency", fmt.Sprintf("%s must be between 1 and 100", jobType))
+		}
+	}
+	for jobType, group := range groups {
+		if !slices.Contains(hatchetJobTypes, jobType) {
+			return configError("hatchet.concurrency_groups", fmt.Sprintf("unknown job type %q (want %s)", jobType, strings.Join(hatchetJobTypes, ", ")))
+		}
+		if !payloadFieldPattern.MatchString(group.Key) {
+			return configError("hatchet.concurrency_groups", fmt.Sprintf("%s key %q must be a payload field name", jobType, group.Key))
+		}
+		if group.MaxRuns < 1 || group.MaxRuns > 100 {
+			return configError("hatchet.concurrency_groups", fmt.Sprintf("%s max_runs must be between 1 and 100", jobType))
+		}
+	}
+	return nil
+}
+
+// playwrightBrowsers are the engines and channels a browser server can run.
+var playwrightBrowsers = []string{"chromium", "msedge", "firefox", "webkit"}
+
+// playwrightPolicies decide when a run keeps its trace, video or final screenshot.
+var playwrightPolicies = []string{"off", "on", "retain-on-failure"}
+
+// validateBrowsers checks the server list and that it includes the default browser.
+func validateBrowsers(defaultBrowser string, browsers []string) error {
+	seen := map[string]bool{}
+	for _, browser := range browsers {
+		if !slices.Contains(playwrightBrowsers, browser) {
+			return configError("playwright.browsers", fmt.Sprintf("unknown browser %q (want %s)", browser, strings.Join(playwrightBrowsers, ", ")))
+		}
+		if seen[browser] {
+			return configError("playwright.browsers", fmt.Sprintf("duplicate browser %q", browser))
+		}
+		seen[browser] = true
+	}
+	if !seen[defaultBrowser] {
+		return configError("playwright.browser", fmt.Sprintf("%q must be one of playwright.browsers", defaultBrowser))
+	}
+	return nil
+}
+
+// validateVersion allows an empty version or major.minor[.patch].
+func validateVersion(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return configError(field, "must be major.minor or major.minor.patch")
+	}
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil || strings.HasPrefix(part, "-") || strings.HasPrefix(part, "+") {
+			return configError(field, "must be major.minor or major.minor.patch")
+		}
+	}
+	return nil
+}
+
+// validateWSPath allows an empty path (not configured yet) or unreserved URL characters.
+func validateWSPath(field, value string) error {
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r)) {
+			return configError(field, "must contain only letters, digits, '-', '.', '_' or '~'")
+		}
+	}
+	return nil
+}
+
+// ReadSecretFile returns the trimmed contents of a secret file.
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// WriteSecretFile stores secret at path with owner-only permissions.
+func WriteSecretFile(path, secret string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(secret+"\n"), 0o600)
+}
+
 func configError(field, reason string) error {
 	return fmt.Errorf("config error: %s %s", field, reason)
 }
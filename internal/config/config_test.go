@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,10 +28,24 @@ func TestLoadFromEnv_Defaults(t *testing.T) {
 		{"AlohaClientURL", cfg.AlohaClientURL, "http://127.0.0.1:7888"},
 		{"AlohaServerStartCmd", cfg.AlohaServerStartCmd, ""},
 		{"AlohaClientStartCmd", cfg.AlohaClientStartCmd, ""},
+		{"AlohaTaskBudget", cfg.AlohaTaskBudget, 15 * time.Minute},
+		{"AlohaDailyStepBudget", cfg.AlohaDailyStepBudget, 0},
 		{"PlaywrightHost", cfg.PlaywrightHost, "127.0.0.1"},
 		{"PlaywrightPort", cfg.PlaywrightPort, 9323},
+		{"PlaywrightWSPath", cfg.PlaywrightWSPath, ""},
+		{"PlaywrightWSPathFile", cfg.PlaywrightWSPathFile, ""},
+		{"PlaywrightBrowser", cfg.PlaywrightBrowser, "chromium"},
+		{"PlaywrightBrowsers", strings.Join(cfg.PlaywrightBrowsers, ","), "chromium"},
+		{"PlaywrightCacheDir", cfg.PlaywrightCacheDir, ""},
+		{"PlaywrightTrace", cfg.PlaywrightTrace, "off"},
+		{"PlaywrightVideo", cfg.PlaywrightVideo, "off"},
+		{"PlaywrightScreenshot", cfg.PlaywrightScreenshot, "off"},
+		{"PlaywrightMaxSessions", cfg.PlaywrightMaxSessions, 4},
 		{"ArtifactOutDir", cfg.ArtifactOutDir, "./artifacts"},
 		{"ArtifactRetentionDays", cfg.ArtifactRetentionDays, 7},
+		{"TasksDir", cfg.TasksDir, "./tasks"},
+		{"DesktopLockTimeout", cfg.DesktopLockTimeout, 10 * time.Minute},
+		{"DesktopLockTTL", cfg.DesktopLockTTL, 5 * time.Minute},
 		{"Timeout", cfg.Timeout, 10 * time.Second},
 		{"HatchetHTTPURL", cfg.HatchetHTTPURL, "http://127.0.0.1:8888"},
 		{"HatchetGRPCAddress", cfg.HatchetGRPCAddress, "localhost:7077"},
@@ -42,6 +58,13 @@ func TestLoadFromEnv_Defaults(t *testing.T) {
 		{"HatchetJobTimeout", cfg.HatchetJobTimeout, 10 * time.Minute},
 		{"HatchetRetryMax", cfg.HatchetRetryMax, 3},
 		{"HatchetRetryBackoff", cfg.HatchetRetryBackoff, 5 * time.Second},
+		{"HatchetScheduler", cfg.HatchetScheduler, "hatchet"},
+		{"HatchetScheduleFile", cfg.HatchetScheduleFile, "./schedules.json"},
+		{"HatchetDedupWindow", cfg.HatchetDedupWindow, 24 * time.Hour},
+		{"HatchetIdempotencyStore", cfg.HatchetIdempotencyStore, "hatchet"},
+		{"HatchetIdempotencyFile", cfg.HatchetIdempotencyFile, "./idempotency.json"},
+		{"HatchetConcurrency", len(cfg.HatchetConcurrency), 0},
+		{"HatchetConcurrencyGroups", len(cfg.HatchetConcurrencyGroups), 0},
 	}
 
 	for _, tt := range tests {
@@ -56,10 +79,22 @@ func TestLoadFromEnv_HatchetOverrides(t *testing.T) {
 
 	os.Setenv("WIN_AUTOMATION_ALOHA_SERVER_START_CMD", "aloha-server-start")
 	os.Setenv("WIN_AUTOMATION_ALOHA_CLIENT_START_CMD", "aloha-client-start")
+	os.Setenv("WIN_AUTOMATION_ALOHA_TASK_BUDGET", "5m")
+	os.Setenv("WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET", "500")
 	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_HOST", "playwright.local")
 	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_PORT", "12345")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_VERSION", "1.49")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSER", "msedge")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_CACHE_DIR", "/var/cache/win-automation")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_TRACE", "retain-on-failure")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_VIDEO", "on")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_SCREENSHOT", "retain-on-failure")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS", "8")
 	os.Setenv("WIN_AUTOMATION_ARTIFACT_OUT", "/tmp/artifacts")
 	os.Setenv("WIN_AUTOMATION_ARTIFACT_RETENTION_DAYS", "30")
+	os.Setenv("WIN_AUTOMATION_TASKS_DIR", "/etc/win-automation/tasks")
+	os.Setenv("WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT", "2m")
+	os.Setenv("WIN_AUTOMATION_DESKTOP_LOCK_TTL", "90s")
 
 	os.Setenv("WIN_AUTOMATION_HATCHET_HTTP_URL", "http://hatchet:9999")
 	os.Setenv("WIN_AUTOMATION_HATCHET_GRPC_ADDRESS", "hatchet:9999")
@@ -72,6 +107,11 @@ func TestLoadFromEnv_HatchetOverrides(t *testing.T) {
 	os.Setenv("WIN_AUTOMATION_HATCHET_JOB_TIMEOUT", "30m")
 	os.Setenv("WIN_AUTOMATION_HATCHET_RETRY_MAX", "5")
 	os.Setenv("WIN_AUTOMATION_HATCHET_RETRY_BACKOFF", "10s")
+	os.Setenv("WIN_AUTOMATION_HATCHET_SCHEDULER", "local")
+	os.Setenv("WIN_AUTOMATION_HATCHET_SCHEDULE_FILE", "/var/lib/win-automation/schedules.json")
+	os.Setenv("WIN_AUTOMATION_HATCHET_DEDUP_WINDOW", "6h")
+	os.Setenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_STORE", "local")
+	os.Setenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_FILE", "/var/lib/win-automation/idempotency.json")
 	defer clearEnv()
 
 	cfg, err := LoadFromEnv()
@@ -86,10 +126,23 @@ func TestLoadFromEnv_HatchetOverrides(t *testing.T) {
 	}{
 		{"AlohaServerStartCmd", cfg.AlohaServerStartCmd, "aloha-server-start"},
 		{"AlohaClientStartCmd", cfg.AlohaClientStartCmd, "aloha-client-start"},
+		{"AlohaTaskBudget", cfg.AlohaTaskBudget, 5 * time.Minute},
+		{"AlohaDailyStepBudget", cfg.AlohaDailyStepBudget, 500},
 		{"PlaywrightHost", cfg.PlaywrightHost, "playwright.local"},
 		{"PlaywrightPort", cfg.PlaywrightPort, 12345},
+		{"PlaywrightVersion", cfg.PlaywrightVersion, "1.49"},
+		{"PlaywrightBrowser", cfg.PlaywrightBrowser, "msedge"},
+		{"PlaywrightBrowsers", strings.Join(cfg.PlaywrightBrowsers, ","), "msedge"},
+		{"PlaywrightCacheDir", cfg.PlaywrightCacheDir, "/var/cache/win-automation"},
+		{"PlaywrightTrace", cfg.PlaywrightTrace, "retain-on-failure"},
+		{"PlaywrightVideo", cfg.PlaywrightVideo, "on"},
+		{"PlaywrightScreenshot", cfg.PlaywrightScreenshot, "retain-on-failure"},
+		{"PlaywrightMaxSessions", cfg.PlaywrightMaxSessions, 8},
 		{"ArtifactOutDir", cfg.ArtifactOutDir, "/tmp/artifacts"},
 		{"ArtifactRetentionDays", cfg.ArtifactRetentionDays, 30},
+		{"TasksDir", cfg.TasksDir, "/etc/win-automation/tasks"},
+		{"DesktopLockTimeout", cfg.DesktopLockTimeout, 2 * time.Minute},
+		{"DesktopLockTTL", cfg.DesktopLockTTL, 90 * time.Second},
 		{"HatchetHTTPURL", cfg.HatchetHTTPURL, "http://hatchet:9999"},
 		{"HatchetGRPCAddress", cfg.HatchetGRPCAddress, "hatchet:9999"},
 		{"HatchetHealthURL", cfg.HatchetHealthURL, "http://hatchet:9998"},
@@ -101,6 +154,11 @@ func TestLoadFromEnv_HatchetOverrides(t *testing.T) {
 		{"HatchetJobTimeout", cfg.HatchetJobTimeout, 30 * time.Minute},
 		{"HatchetRetryMax", cfg.HatchetRetryMax, 5},
 		{"HatchetRetryBackoff", cfg.HatchetRetryBackoff, 10 * time.Second},
+		{"HatchetScheduler", cfg.HatchetScheduler, "local"},
+		{"HatchetScheduleFile", cfg.HatchetScheduleFile, "/var/lib/win-automation/schedules.json"},
+		{"HatchetDedupWindow", cfg.HatchetDedupWindow, 6 * time.Hour},
+		{"HatchetIdempotencyStore", cfg.HatchetIdempotencyStore, "local"},
+		{"HatchetIdempotencyFile", cfg.HatchetIdempotencyFile, "/var/lib/win-automation/idempotency.json"},
 	}
 
 	for _, tt := range tests {
@@ -123,6 +181,10 @@ func TestLoadFromEnv_InvalidValues(t *testing.T) {
 		{"InvalidJobTimeout", "WIN_AUTOMATION_HATCHET_JOB_TIMEOUT", "bad", "must be a duration"},
 		{"InvalidRetryMax", "WIN_AUTOMATION_HATCHET_RETRY_MAX", "bad", "must be an int"},
 		{"InvalidRetryBackoff", "WIN_AUTOMATION_HATCHET_RETRY_BACKOFF", "bad", "must be a duration"},
+		{"InvalidDesktopLockTimeout", "WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT", "bad", "must be a duration"},
+		{"InvalidAlohaTaskBudget", "WIN_AUTOMATION_ALOHA_TASK_BUDGET", "bad", "must be a duration"},
+		{"InvalidAlohaDailyStepBudget", "WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET", "bad", "must be an int"},
+		{"InvalidPlaywrightMaxSessions", "WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS", "bad", "must be an int"},
 	}
 
 	for _, tt := range tests {
@@ -143,6 +205,173 @@ func TestLoadFromEnv_InvalidValues(t *testing.T) {
 	}
 }
 
+func TestLoadFromEnv_PlaywrightWSPath(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	path := filepath.Join(t.TempDir(), "ws-path")
+	if err := WriteSecretFile(path, "from-file"); err != nil {
+		t.Fatalf("WriteSecretFile() error = %v", err)
+	}
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH_FILE", path)
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+	if cfg.PlaywrightWSPath != "from-file" {
+		t.Errorf("PlaywrightWSPath = %q, want %q", cfg.PlaywrightWSPath, "from-file")
+	}
+
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH", "from-env")
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+	if cfg.PlaywrightWSPath != "from-env" {
+		t.Errorf("PlaywrightWSPath = %q, want env value to win", cfg.PlaywrightWSPath)
+	}
+
+	// A missing file means no secret yet; install generates one.
+	os.Unsetenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH")
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_WS_PATH_FILE", filepath.Join(t.TempDir(), "missing"))
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+	if cfg.PlaywrightWSPath != "" {
+		t.Errorf("PlaywrightWSPath = %q, want empty", cfg.PlaywrightWSPath)
+	}
+}
+
+func TestLoad_PlaywrightVersion(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	for _, v := range []string{"1.49", "1.49.1"} {
+		os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_VERSION", v)
+		if _, err := Load(""); err != nil {
+			t.Errorf("Load() with version %s error = %v", v, err)
+		}
+	}
+	for _, v := range []string{"latest", "1", "1.49.x", "v1.49"} {
+		os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_VERSION", v)
+		if _, err := Load(""); err == nil || !contains(err.Error(), "playwright.version") {
+			t.Errorf("Load() with version %s error = %v, want playwright.version error", v, err)
+		}
+	}
+}
+
+func TestLoad_PlaywrightBrowsers(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSERS", "chromium, msedge,firefox")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := strings.Join(cfg.PlaywrightBrowsers, ","); got != "chromium,msedge,firefox" || cfg.PlaywrightBrowser != "chromium" {
+		t.Errorf("browsers = %s, default = %s", got, cfg.PlaywrightBrowser)
+	}
+
+	for _, tt := range []struct{ browser, browsers string }{
+		{"msedge", "chromium"},
+		{"chromium", "chromium,opera"},
+		{"chromium", "chromium,chromium"},
+	} {
+		os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSER", tt.browser)
+		os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_BROWSERS", tt.browsers)
+		if _, err := Load(""); err == nil {
+			t.Errorf("Load() with browser %s and browsers %s error = nil, want error", tt.browser, tt.browsers)
+		}
+	}
+}
+
+func TestLoad_PlaywrightPolicies(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	for _, env := range []string{"WIN_AUTOMATION_PLAYWRIGHT_TRACE", "WIN_AUTOMATION_PLAYWRIGHT_VIDEO", "WIN_AUTOMATION_PLAYWRIGHT_SCREENSHOT"} {
+		os.Setenv(env, "always")
+		if _, err := Load(""); err == nil {
+			t.Errorf("Load() with %s=always error = nil, want error", env)
+		}
+		os.Unsetenv(env)
+	}
+}
+
+func TestLoad_PlaywrightMaxSessions(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	for _, value := range []string{"0", "33"} {
+		os.Setenv("WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS", value)
+		if _, err := Load(""); err == nil {
+			t.Errorf("Load() with max sessions %s error = nil, want error", value)
+		}
+	}
+}
+
+func TestLoad_HatchetScheduler(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("WIN_AUTOMATION_HATCHET_SCHEDULER", "cron")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "hatchet.scheduler") {
+		t.Errorf("Load() with scheduler cron error = %v, want hatchet.scheduler error", err)
+	}
+}
+
+func TestLoad_HatchetIdempotency(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_STORE", "redis")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "hatchet.idempotency_store") {
+		t.Errorf("Load() with idempotency store redis error = %v, want hatchet.idempotency_store error", err)
+	}
+	os.Setenv("WIN_AUTOMATION_HATCHET_IDEMPOTENCY_STORE", "local")
+	os.Setenv("WIN_AUTOMATION_HATCHET_DEDUP_WINDOW", "10s")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "hatchet.dedup_window") {
+		t.Errorf("Load() with dedup window 10s error = %v, want hatchet.dedup_window error", err)
+	}
+}
+
+func TestLoad_HatchetConcurrency(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("WIN_AUTOMATION_HATCHET_CONCURRENCY", "aloha.run=1, windows.exec=4")
+	os.Setenv("WIN_AUTOMATION_HATCHET_CONCURRENCY_GROUPS", "aloha.run=screen:1")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.HatchetConcurrency["aloha.run"] != 1 || cfg.HatchetConcurrency["windows.exec"] != 4 || len(cfg.HatchetConcurrency) != 2 {
+		t.Errorf("HatchetConcurrency = %v", cfg.HatchetConcurrency)
+	}
+	if got := cfg.HatchetConcurrencyGroups["aloha.run"]; got != (ConcurrencyGroup{Key: "screen", MaxRuns: 1}) {
+		t.Errorf("HatchetConcurrencyGroups[aloha.run] = %+v", got)
+	}
+
+	for env, value := range map[string]string{
+		"WIN_AUTOMATION_HATCHET_CONCURRENCY":        "shell.run=1",
+		"WIN_AUTOMATION_HATCHET_CONCURRENCY_GROUPS": "aloha.run=input.screen:1",
+	} {
+		clearEnv()
+		os.Setenv(env, value)
+		if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "hatchet.concurrency") {
+			t.Errorf("Load() with %s=%s error = %v, want hatchet.concurrency error", env, value, err)
+		}
+	}
+	clearEnv()
+	os.Setenv("WIN_AUTOMATION_HATCHET_CONCURRENCY", "windows.exec=four")
+	if _, err := Load(""); err == nil {
+		t.Error("Load() with a non-numeric limit error = nil, want error")
+	}
+}
+
 func clearEnv() {
 	envVars := []string{
 		"WIN_AUTOMATION_WINDOWS_SSH_HOST",
@@ -153,6 +382,8 @@ func clearEnv() {
 		"WIN_AUTOMATION_ALOHA_CLIENT_URL",
 		"WIN_AUTOMATION_ALOHA_SERVER_START_CMD",
 		"WIN_AUTOMATION_ALOHA_CLIENT_START_CMD",
+		"WIN_AUTOMATION_ALOHA_TASK_BUDGET",
+		"WIN_AUTOMATION_ALOHA_DAILY_STEP_BUDGET",
 		"WIN_AUTOMATION_TIMEOUT",
 		"WIN_AUTOMATION_HATCHET_HTTP_URL",
 		"WIN_AUTOMATION_HATCHET_GRPC_ADDRESS",
@@ -165,10 +396,30 @@ func clearEnv() {
 		"WIN_AUTOMATION_HATCHET_JOB_TIMEOUT",
 		"WIN_AUTOMATION_HATCHET_RETRY_MAX",
 		"WIN_AUTOMATION_HATCHET_RETRY_BACKOFF",
+		"WIN_AUTOMATION_HATCHET_SCHEDULER",
+		"WIN_AUTOMATION_HATCHET_SCHEDULE_FILE",
+		"WIN_AUTOMATION_HATCHET_DEDUP_WINDOW",
+		"WIN_AUTOMATION_HATCHET_IDEMPOTENCY_STORE",
+		"WIN_AUTOMATION_HATCHET_IDEMPOTENCY_FILE",
+		"WIN_AUTOMATION_HATCHET_CONCURRENCY",
+		"WIN_AUTOMATION_HATCHET_CONCURRENCY_GROUPS",
 		"WIN_AUTOMATION_PLAYWRIGHT_HOST",
 		"WIN_AUTOMATION_PLAYWRIGHT_PORT",
+		"WIN_AUTOMATION_PLAYWRIGHT_WS_PATH",
+		"WIN_AUTOMATION_PLAYWRIGHT_WS_PATH_FILE",
+		"WIN_AUTOMATION_PLAYWRIGHT_VERSION",
+		"WIN_AUTOMATION_PLAYWRIGHT_BROWSER",
+		"WIN_AUTOMATION_PLAYWRIGHT_BROWSERS",
+		"WIN_AUTOMATION_PLAYWRIGHT_CACHE_DIR",
+		"WIN_AUTOMATION_PLAYWRIGHT_TRACE",
+		"WIN_AUTOMATION_PLAYWRIGHT_VIDEO",
+		"WIN_AUTOMATION_PLAYWRIGHT_SCREENSHOT",
+		"WIN_AUTOMATION_PLAYWRIGHT_MAX_SESSIONS",
 		"WIN_AUTOMATION_ARTIFACT_OUT",
 		"WIN_AUTOMATION_ARTIFACT_RETENTION_DAYS",
+		"WIN_AUTOMATION_TASKS_DIR",
+		"WIN_AUTOMATION_DESKTOP_LOCK_TIMEOUT",
+		"WIN_AUTOMATION_DESKTOP_LOCK_TTL",
 	}
 	for _, v := range envVars {
 		os.Unsetenv(v)
@@ -0,0 +1,93 @@
+package desktop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/win"
+	"github.com/google/uuid"
+)
+
+// displaysDir is where the interactive-session query drops its JSON result.
+const displaysDir = `C:\ProgramData\win-automation\displays`
+
+// Display describes one monitor of the interactive session.
+type Display struct {
+	Index   int     `json:"index"`
+	Name    string  `json:"name"` // device name, e.g. \\.\DISPLAY1
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Scale   float64 `json:"scale"` // effective DPI / 96
+	Primary bool    `json:"primary"`
+}
+
+// ListDisplays enumerates the monitors of the logged-on user's desktop, which is what
+// Aloha sees. Physical resolutions are reported regardless of DPI scaling.
+func ListDisplays(ctx context.Context, cfg config.Config) ([]Display, error) {
+	id := uuid.NewString()[:8]
+	out := displaysDir + `\` + id + ".json"
+	res, err := runSSH(ctx, cfg, win.DisplaysQuery(out, "WinAutomation-Displays-"+id))
+	if err != nil {
+		if msg := strings.TrimSpace(res.Stderr); msg != "" {
+			return nil, fmt.Errorf("list displays: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("list displays: %w", err)
+	}
+	return parseDisplays(res.Stdout)
+}
+
+func parseDisplays(out string) ([]Display, error) {
+	// Set-Content -Encoding UTF8 writes a BOM under Windows PowerShell.
+	out = strings.TrimPrefix(strings.TrimSpace(out), "\ufeff")
+	var displays []Display
+	if err := json.Unmarshal([]byte(out), &displays); err != nil {
+		return nil, fmt.Errorf("parse displays: %w", err)
+	}
+	if len(displays) == 0 {
+		return nil, fmt.Errorf("no displays reported")
+	}
+	return displays, nil
+}
+
+// ResolveScreen maps a screen selector to a display index. The selector is an index,
+// "primary", or a device name with or without the \\.\ prefix (case-insensitive).
+// An empty selector means index 0.
+func ResolveScreen(displays []Display, selector string) (int, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		selector = "0"
+	}
+	if n, err := strconv.Atoi(selector); err == nil {
+		for _, d := range displays {
+			if d.Index == n {
+				return n, nil
+			}
+		}
+		return 0, fmt.Errorf("selected screen %d out of range: %d display(s) attached", n, len(displays))
+	}
+	if strings.EqualFold(selector, "primary") {
+		for _, d := range displays {
+			if d.Primary {
+				return d.Index, nil
+			}
+		}
+		return 0, fmt.Errorf("no primary display reported")
+	}
+	want := strings.TrimPrefix(strings.ToUpper(selector), `\\.\`)
+	for _, d := range displays {
+		if strings.TrimPrefix(strings.ToUpper(d.Name), `\\.\`) == want {
+			return d.Index, nil
+		}
+	}
+	names := make([]string, 0, len(displays))
+	for _, d := range displays {
+		names = append(names, d.Name)
+	}
+	return 0, fmt.Errorf("unknown screen %q (available: %s)", selector, strings.Join(names, ", "))
+}
@@ -0,0 +1,36 @@
+package desktop
+
+import "testing"
+
+func TestResolveScreen(t *testing.T) {
+	displays, err := parseDisplays("\ufeff" + `[{"index":0,"name":"\\\\.\\DISPLAY1","width":2560,"height":1440,"scale":1.5,"primary":false},{"index":1,"name":"\\\\.\\DISPLAY2","width":1920,"height":1080,"scale":1,"primary":true}]`)
+	if err != nil {
+		t.Fatalf("parseDisplays: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		want     int
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"1", 1, false},
+		{"2", 0, true},
+		{"primary", 1, false},
+		{"PRIMARY", 1, false},
+		{`\\.\DISPLAY1`, 0, false},
+		{"display2", 1, false},
+		{"DISPLAY3", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveScreen(displays, tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveScreen(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ResolveScreen(%q) = %d, want %d", tt.selector, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+	"github.com/google/uuid"
+)
+
+// LockDir is the Windows directory holding the desktop lock and its wait queue.
+const LockDir = `C:\ProgramData\win-automation\locks`
+
+const (
+	pollInterval   = 2 * time.Second
+	staleTicketAge = 30 * time.Second
+)
+
+// ErrTimeout is returned when the desktop lock could not be acquired in time.
+var ErrTimeout = errors.New("desktop lock timeout")
+
+// ErrLeaseLost is returned when a held lease could not be renewed: another
+// holder took the lock, or renewals failed until the lease expired.
+var ErrLeaseLost = errors.New("desktop lock lost")
+
+// runSSH is replaced in tests that run without a Windows host.
+var runSSH = sshx.Run
+
+// Status describes a pending acquisition.
+type Status struct {
+	Position int    // 1-based position in the wait queue
+	Holder   string // current lock holder, if any
+}
+
+// Lease is an exclusive hold on the interactive Windows desktop. It is renewed in
+// the background until Release is called; an abandoned lease expires after
+// cfg.DesktopLockTTL. Work done under the lease should run in its Context, which
+// ends when the lease is lost.
+type Lease struct {
+	cfg    config.Config
+	holder string
+	stop   chan struct{}
+	once   sync.Once
+	done   chan struct{}
+	// lost is closed when renewal fails for good; lostErr is set before.
+	lost    chan struct{}
+	lostErr error
+}
+
+// Acquire queues for the desktop lock and blocks until it is held, ctx is done or
+// cfg.DesktopLockTimeout elapses. onWait, when set, is called whenever the queue
+// position or holder changes.
+func Acquire(ctx context.Context, cfg config.Config, holder string, onWait func(Status)) (*Lease, error) {
+	acquireCtx, cancel := context.WithTimeout(ctx, cfg.DesktopLockTimeout)
+	defer cancel()
+
+	ticket := fmt.Sprintf("%013d-%s", time.Now().UnixMilli(), uuid.NewString()[:8])
+	ttl := int(cfg.DesktopLockTTL / time.Second)
+	stale := int(staleTicketAge / time.Second)
+
+	var last Status
+	for {
+		res, err := runSSH(acquireCtx, cfg, win.DesktopLockTry(LockDir, ticket, holder, ttl, stale))
+		if err == nil {
+			out := strings.TrimSpace(res.Stdout)
+			if out == "acquired" {
+				lease := &Lease{cfg: cfg, holder: holder, stop: make(chan struct{}), done: make(chan struct{}), lost: make(chan struct{})}
+				go lease.renewLoop()
+				return lease, nil
+			}
+			if status, ok := parseStatus(out); ok && status != last {
+				last = status
+				if onWait != nil {
+					onWait(status)
+				}
+			}
+		} else if acquireCtx.Err() == nil {
+			dropTicket(cfg, ticket, holder)
+			return nil, fmt.Errorf("desktop lock: %w: %s", err, strings.TrimSpace(res.Stderr))
+		}
+
+		select {
+		case <-acquireCtx.Done():
+			dropTicket(cfg, ticket, holder)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%w after %s (position %d, holder %q)", ErrTimeout, cfg.DesktopLockTimeout, last.Position, last.Holder)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Holder returns the identity recorded in the lock file.
+func (l *Lease) Holder() string {
+	if l == nil {
+		return ""
+	}
+	return l.holder
+}
+
+// Lost returns a channel that is closed when the lease is lost. A nil Lease is
+// never lost, so its channel is nil.
+func (l *Lease) Lost() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.lost
+}
+
+// Err returns an error wrapping ErrLeaseLost once the lease is lost, else nil.
+func (l *Lease) Err() error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case <-l.lost:
+		return l.lostErr
+	default:
+		return nil
+	}
+}
+
+// Context returns a copy of parent that is cancelled, with Err as the cause,
+// when the lease is lost. For a nil Lease it is only cancelled with parent.
+func (l *Lease) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if l == nil {
+		return context.WithCancel(parent)
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	go func() {
+		select {
+		case <-l.lost:
+			cancel(l.lostErr)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// Release stops renewal and removes the lock. It returns Err when the lease was
+// lost while held. It is safe to call on a nil Lease and more than once.
+func (l *Lease) Release() error {
+	if l == nil {
+		return nil
+	}
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+		ctx, cancel := context.WithTimeout(context.Background(), l.cfg.Timeout)
+		defer cancel()
+		// The release script only removes a lock this holder still owns.
+		_, err = runSSH(ctx, l.cfg, win.DesktopLockRelease(LockDir, "", l.holder))
+		err = errors.Join(l.Err(), err)
+	})
+	return err
+}
+
+// renewLoop extends the lease every third of its TTL. The lease is lost when
+// the lock names another holder (the renew script exits 1), or when renewals
+// keep failing until the TTL since the last one has passed.
+func (l *Lease) renewLoop() {
+	defer close(l.done)
+	interval := l.cfg.DesktopLockTTL / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.cfg.Timeout)
+			_, err := runSSH(ctx, l.cfg, win.DesktopLockRenew(LockDir, l.holder, int(l.cfg.DesktopLockTTL/time.Second)))
+			cancel()
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			var exitErr *sshx.ExitError
+			if errors.As(err, &exitErr) && exitErr.Code == 1 {
+				l.lostErr = fmt.Errorf("%w: held by another holder", ErrLeaseLost)
+			} else if time.Since(renewed) >= l.cfg.DesktopLockTTL {
+				l.lostErr = fmt.Errorf("%w: not renewed for %s: %w", ErrLeaseLost, l.cfg.DesktopLockTTL, err)
+			} else {
+				continue
+			}
+			close(l.lost)
+			return
+		}
+	}
+}
+
+func dropTicket(cfg config.Config, ticket, holder string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	_, _ = runSSH(ctx, cfg, win.DesktopLockRelease(LockDir, ticket, holder))
+}
+
+func parseStatus(out string) (Status, bool) {
+	rest, ok := strings.CutPrefix(out, "waiting ")
+	if !ok {
+		return Status{}, false
+	}
+	var status Status
+	for _, field := range strings.SplitN(rest, " ", 2) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "position":
+			status.Position, _ = strconv.Atoi(value)
+		case "holder":
+			status.Holder = value
+		}
+	}
+	return status, true
+}
@@ -0,0 +1,132 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		out    string
+		want   Status
+		wantOK bool
+	}{
+		{"waiting position=2 holder=job:abc", Status{Position: 2, Holder: "job:abc"}, true},
+		{"waiting position=1 holder=", Status{Position: 1}, true},
+		{"waiting position=3 holder=cli:my host:42", Status{Position: 3, Holder: "cli:my host:42"}, true},
+		{"acquired", Status{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseStatus(tt.out)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseStatus(%q) = %+v, %v; want %+v, %v", tt.out, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// stubLock answers the lock scripts: acquire succeeds at once, and renew
+// exits with renewCode. It returns the scripts run, guarded by the returned mutex.
+func stubLock(t *testing.T, cfg config.Config, holder string, renewCode int) (*sync.Mutex, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var scripts []string
+	renew := win.DesktopLockRenew(LockDir, holder, int(cfg.DesktopLockTTL/time.Second))
+	orig := runSSH
+	runSSH = func(_ context.Context, _ config.Config, script string) (sshx.Result, error) {
+		mu.Lock()
+		scripts = append(scripts, script)
+		mu.Unlock()
+		if script == renew && renewCode != 0 {
+			return sshx.Result{ExitCode: renewCode}, &sshx.ExitError{Code: renewCode}
+		}
+		return sshx.Result{Stdout: "acquired\n"}, nil
+	}
+	t.Cleanup(func() { runSSH = orig })
+	return &mu, &scripts
+}
+
+func lockConfig() config.Config {
+	return config.Config{Timeout: time.Second, DesktopLockTimeout: time.Second, DesktopLockTTL: 3 * time.Second}
+}
+
+func TestLease_LostWhenRenewRejected(t *testing.T) {
+	cfg := lockConfig()
+	stubLock(t, cfg, "job:a", 1)
+	lease, err := Acquire(context.Background(), cfg, "job:a", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	ctx, cancel := lease.Context(context.Background())
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease context not cancelled after renew was rejected")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrLeaseLost) {
+		t.Errorf("context.Cause() = %v, want ErrLeaseLost", cause)
+	}
+	if err := lease.Err(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Err() = %v, want ErrLeaseLost", err)
+	}
+	if err := lease.Release(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release() = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestLease_Release(t *testing.T) {
+	cfg := lockConfig()
+	mu, scripts := stubLock(t, cfg, "job:b", 0)
+	lease, err := Acquire(context.Background(), cfg, "job:b", nil)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	ctx, cancel := lease.Context(context.Background())
+	defer cancel()
+
+	if err := lease.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := lease.Release(); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+	if ctx.Err() != nil || lease.Err() != nil {
+		t.Errorf("lease context err = %v, Err() = %v; want nil after a normal release", ctx.Err(), lease.Err())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	release := win.DesktopLockRelease(LockDir, "", "job:b")
+	if n := len(*scripts); n == 0 || (*scripts)[n-1] != release {
+		t.Errorf("last script run was not the release of job:b")
+	}
+}
+
+func TestLease_NilIsSafe(t *testing.T) {
+	var lease *Lease
+	if err := lease.Release(); err != nil {
+		t.Errorf("nil Release() = %v", err)
+	}
+	if err := lease.Err(); err != nil {
+		t.Errorf("nil Err() = %v", err)
+	}
+	if lease.Lost() != nil {
+		t.Error("nil Lost() is not nil")
+	}
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := lease.Context(parent)
+	defer cancel()
+	if ctx.Err() != nil {
+		t.Fatalf("nil Context() err = %v before parent is cancelled", ctx.Err())
+	}
+	cancelParent()
+	<-ctx.Done()
+}
@@ -0,0 +1,213 @@
+// Package expr compiles and evaluates the CEL expressions used for workflow
+// conditions.
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/ext"
+)
+
+// FieldType is the type of a step output field.
+type FieldType int
+
+const (
+	Dyn FieldType = iota
+	String
+	Int
+	StringList
+)
+
+func (t FieldType) celType() *cel.Type {
+	switch t {
+	case String:
+		return cel.StringType
+	case Int:
+		return cel.IntType
+	case StringList:
+		return cel.ListType(cel.StringType)
+	default:
+		return cel.DynType
+	}
+}
+
+// Env is a CEL environment with a fixed set of variables.
+type Env struct {
+	env *cel.Env
+	// vars are the declared variables by qualified name, e.g. steps.install.exit_code.
+	vars map[string]FieldType
+}
+
+// Program is a compiled boolean expression.
+type Program struct {
+	source string
+	prg    cel.Program
+	refs   [][]string
+	vars   map[string]FieldType
+}
+
+// StepsEnv declares steps.<id>.<field> for every output field of every step,
+// given as fields by step id, e.g.
+// steps.install.exit_code == 0 && steps.check.stdout.contains("OK").
+// Expressions reading an undeclared step or field, or comparing a field with a
+// value of another type, fail to compile.
+func StepsEnv(fields map[string]map[string]FieldType) (*Env, error) {
+	vars := map[string]FieldType{}
+	var opts []cel.EnvOption
+	for id, stepFields := range fields {
+		for field, t := range stepFields {
+			name := "steps." + id + "." + field
+			vars[name] = t
+			opts = append(opts, cel.Variable(name, t.celType()))
+		}
+	}
+	env, err := cel.NewEnv(append(opts, ext.Strings())...)
+	if err != nil {
+		return nil, err
+	}
+	return &Env{env: env, vars: vars}, nil
+}
+
+// Compile parses and type-checks source, which must evaluate to a bool.
+func (e *Env) Compile(source string) (*Program, error) {
+	checked, issues := e.env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, issues.Err())
+	}
+	if out := checked.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("invalid expression %q: result is %s, want bool", source, out)
+	}
+	prg, err := e.env.Program(checked)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Program{source: source, prg: prg, refs: selectPaths(checked.NativeRep().Expr()), vars: e.vars}, nil
+}
+
+// Source returns the expression text.
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Refs returns the variable paths the expression reads, such as
+// steps.install.exit_code, sorted.
+func (p *Program) Refs() []string {
+	refs := make([]string, 0, len(p.refs))
+	for _, path := range p.refs {
+		refs = append(refs, strings.Join(path, "."))
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Eval evaluates the expression against vars, which nest the step outputs as
+// {"steps": {id: output}}. Numbers decoded from JSON are converted to the
+// declared int fields; a missing step or field fails the evaluation.
+func (p *Program) Eval(vars map[string]any) (bool, error) {
+	activation := make(map[string]any, len(p.vars))
+	for name, t := range p.vars {
+		value, ok := lookup(vars, strings.Split(name, "."))
+		if !ok {
+			continue
+		}
+		if n, isFloat := value.(float64); isFloat && t == Int && n == float64(int64(n)) {
+			value = int64(n)
+		}
+		activation[name] = value
+	}
+	out, _, err := p.prg.Eval(activation)
+	if err != nil {
+		return false, fmt.Errorf("evaluate %q: %w", p.source, err)
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluate %q: result is %s, want bool", p.source, out.Type().TypeName())
+	}
+	return result, nil
+}
+
+// Values returns the value of every path in Refs, looked up in vars, for
+// explaining a result. Missing paths are left out.
+func (p *Program) Values(vars map[string]any) map[string]any {
+	values := map[string]any{}
+	for _, path := range p.refs {
+		if value, ok := lookup(vars, path); ok {
+			values[strings.Join(path, ".")] = value
+		}
+	}
+	return values
+}
+
+// selectPaths returns the longest a.b.c field chains rooted at a variable.
+func selectPaths(root ast.Expr) [][]string {
+	var paths [][]string
+	seen := map[string]bool{}
+	ast.PreOrderVisit(root, ast.NewExprVisitor(func(e ast.Expr) {
+		if e.Kind() != ast.SelectKind && e.Kind() != ast.IdentKind {
+			return
+		}
+		path, ok := selectPath(e)
+		if !ok {
+			return
+		}
+		key := strings.Join(path, ".")
+		// Pre-order visits a chain before its operands; skip those prefixes.
+		for other := range seen {
+			if strings.HasPrefix(other, key+".") {
+				return
+			}
+		}
+		if !seen[key] {
+			seen[key] = true
+			paths = append(paths, path)
+		}
+	}))
+	return paths
+}
+
+func selectPath(e ast.Expr) ([]string, bool) {
+	switch e.Kind() {
+	case ast.IdentKind:
+		// The checker rewrites a declared field chain into one qualified name.
+		return strings.Split(e.AsIdent(), "."), true
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		if sel.IsTestOnly() {
+			return nil, false
+		}
+		path, ok := selectPath(sel.Operand())
+		if !ok {
+			return nil, false
+		}
+		return append(path, sel.FieldName()), true
+	default:
+		return nil, false
+	}
+}
+
+func lookup(vars map[string]any, path []string) (any, bool) {
+	var value any = vars
+	for _, key := range path {
+		switch m := value.(type) {
+		case map[string]any:
+			v, ok := m[key]
+			if !ok {
+				return nil, false
+			}
+			value = v
+		case map[string]map[string]any:
+			v, ok := m[key]
+			if !ok {
+				return nil, false
+			}
+			value = v
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
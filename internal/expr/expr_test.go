@@ -0,0 +1,92 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testFields = map[string]map[string]FieldType{
+	"install": {"stdout": String, "exit_code": Int},
+	"check":   {"stdout": String, "exit_code": Int},
+	"shot":    {"files": StringList, "usage": Dyn},
+}
+
+func TestStepsEnv(t *testing.T) {
+	env, err := StepsEnv(testFields)
+	if err != nil {
+		t.Fatalf("StepsEnv() error = %v", err)
+	}
+	prg, err := env.Compile(`steps.install.exit_code == 0 && steps.check.stdout.contains("OK")`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if got, want := prg.Refs(), []string{"steps.check.stdout", "steps.install.exit_code"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Refs() = %v, want %v", got, want)
+	}
+
+	// Outputs decoded from JSON carry numbers as float64.
+	vars := map[string]any{"steps": map[string]any{
+		"install": map[string]any{"exit_code": float64(0)},
+		"check":   map[string]any{"stdout": "status: OK\r\n"},
+	}}
+	if ok, err := prg.Eval(vars); err != nil || !ok {
+		t.Errorf("Eval() = %v, %v; want true", ok, err)
+	}
+
+	vars["steps"].(map[string]any)["install"] = map[string]any{"exit_code": float64(1603)}
+	if ok, err := prg.Eval(vars); err != nil || ok {
+		t.Errorf("Eval() = %v, %v; want false", ok, err)
+	}
+	values := prg.Values(vars)
+	if values["steps.install.exit_code"] != float64(1603) || values["steps.check.stdout"] != "status: OK\r\n" {
+		t.Errorf("Values() = %v", values)
+	}
+
+	delete(vars["steps"].(map[string]any), "install")
+	if _, err := prg.Eval(vars); err == nil {
+		t.Error("Eval() without the install output error = nil, want error")
+	}
+}
+
+func TestStepsEnv_ListsAndDyn(t *testing.T) {
+	env, err := StepsEnv(testFields)
+	if err != nil {
+		t.Fatalf("StepsEnv() error = %v", err)
+	}
+	prg, err := env.Compile(`"page.png" in steps.shot.files && steps.shot.usage.input_tokens < 100`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	vars := map[string]any{"steps": map[string]any{
+		"shot": map[string]any{"files": []any{"page.png"}, "usage": map[string]any{"input_tokens": float64(42)}},
+	}}
+	if ok, err := prg.Eval(vars); err != nil || !ok {
+		t.Errorf("Eval() = %v, %v; want true", ok, err)
+	}
+	if got, want := prg.Refs(), []string{"steps.shot.files", "steps.shot.usage.input_tokens"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Refs() = %v, want %v", got, want)
+	}
+}
+
+func TestCompile_RejectsInvalidExpressions(t *testing.T) {
+	env, err := StepsEnv(testFields)
+	if err != nil {
+		t.Fatalf("StepsEnv() error = %v", err)
+	}
+	for _, source := range []string{
+		`steps.install.exit_code ==`,
+		`steps.install == 1`,
+		`"done"`,
+		`outputs.install.ok`,
+		`steps.install.exit_code == "0"`,
+		`steps.install.stdout > 1`,
+		`steps.install.missing == 1`,
+		`steps.deploy.exit_code == 0`,
+		`steps.install.stdout`,
+	} {
+		if _, err := env.Compile(source); err == nil || !strings.Contains(err.Error(), "invalid expression") {
+			t.Errorf("Compile(%q) error = %v, want invalid expression", source, err)
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alejg/win-automation/internal/aloha"
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/playwright"
+	"github.com/alejg/win-automation/internal/verify"
 )
 
 type Client struct {
@@ -58,33 +61,128 @@ func (c *Client) Config() config.Config {
 type JobType string
 
 const (
-	JobTypeWindowsExec JobType = "windows.exec"
-	JobTypeAlohaRun    JobType = "aloha.run"
+	JobTypeWindowsExec       JobType = "windows.exec"
+	JobTypeAlohaRun          JobType = "aloha.run"
+	JobTypePlaywrightRun     JobType = "playwright.run"
+	JobTypePlaywrightCapture JobType = "playwright.capture"
 )
 
 type WindowsExecInput struct {
 	Command string        `json:"command"`
 	Timeout time.Duration `json:"timeout,omitempty"`
+	// Interactive commands drive the desktop and hold the desktop lock while running.
+	Interactive bool `json:"interactive,omitempty"`
 }
 
+// WindowsExecOutput is the result of a windows.exec job. A command that ran and
+// exited non-zero completes the Hatchet run with this output, since a failed run
+// keeps none; readers treat its non-zero ExitCode as a failed job.
 type WindowsExecOutput struct {
 	Stdout   string `json:"stdout"`
 	Stderr   string `json:"stderr"`
 	ExitCode int    `json:"exit_code"`
 }
 
+// FailedExitCode returns the non-zero exit code recorded in the output of a
+// windows.exec job, and 0 for other job types and for successful commands.
+func FailedExitCode(jobType JobType, output map[string]any) int {
+	if jobType != JobTypeWindowsExec {
+		return 0
+	}
+	code, _ := output["exit_code"].(float64)
+	return int(code)
+}
+
 type AlohaRunInput struct {
 	Task           string `json:"task"`
 	SelectedScreen int    `json:"selected_screen,omitempty"`
 	TraceID        string `json:"trace_id,omitempty"`
 	MaxSteps       int    `json:"max_steps,omitempty"`
+	// Screen selects the display by index, "primary" or device name and takes
+	// precedence over SelectedScreen. It is resolved against the attached displays.
+	Screen string `json:"screen,omitempty"`
+
+	// Verify is a PowerShell postcondition that must exit 0 after the task.
+	Verify        string        `json:"verify,omitempty"`
+	VerifyRetries int           `json:"verify_retries,omitempty"`
+	VerifyDelay   time.Duration `json:"verify_delay,omitempty"`
+	// TaskRetries re-submits the task this many times when verification fails.
+	TaskRetries int `json:"task_retries,omitempty"`
+	// Budget bounds the wall-clock time of the whole run; 0 uses the configured default.
+	Budget time.Duration `json:"budget,omitempty"`
+	// Template names the task template the input was rendered from, for usage reports.
+	Template string `json:"template,omitempty"`
 }
 
 type AlohaRunOutput struct {
-	Raw string `json:"raw"`
+	Raw          string         `json:"raw"`
+	Attempts     int            `json:"attempts,omitempty"`
+	Screen       int            `json:"screen"`
+	Duration     time.Duration  `json:"duration"`
+	Usage        aloha.Usage    `json:"usage"`
+	Verification *verify.Result `json:"verification,omitempty"`
+}
+
+type PlaywrightRunInput struct {
+	// Script is the flow module source; it exports an async function receiving
+	// { browser, context, page, outDir, screenshot }.
+	Script     string `json:"script"`
+	ScriptName string `json:"script_name,omitempty"`
+	Browser    string `json:"browser,omitempty"` // chromium (default) or msedge
+	// Trace is the legacy switch for TracePolicy "on".
+	Trace bool `json:"trace,omitempty"`
+	// TracePolicy, VideoPolicy and ScreenshotPolicy are off, on or
+	// retain-on-failure; empty uses the configured default.
+	TracePolicy      string `json:"trace_policy,omitempty"`
+	VideoPolicy      string `json:"video_policy,omitempty"`
+	ScreenshotPolicy string `json:"screenshot_policy,omitempty"`
+	// Profile names persistent storage state (e.g. a logged-in session) to start
+	// from and save back; empty runs isolated.
+	Profile string `json:"profile,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// Policies returns the run's trace, video and screenshot policies.
+func (in PlaywrightRunInput) Policies() playwright.Policies {
+	trace := in.TracePolicy
+	if trace == "" && in.Trace {
+		trace = string(playwright.PolicyOn)
+	}
+	return playwright.Policies{
+		Trace:      playwright.Policy(trace),
+		Video:      playwright.Policy(in.VideoPolicy),
+		Screenshot: playwright.Policy(in.ScreenshotPolicy),
+	}
+}
+
+// PlaywrightCaptureInput captures one page. Screenshot, PDF and HAR are output
+// file names; at least one must be set.
+type PlaywrightCaptureInput struct {
+	URL        string `json:"url"`
+	WaitFor    string `json:"wait_for,omitempty"`
+	Screenshot string `json:"screenshot,omitempty"`
+	PDF        string `json:"pdf,omitempty"`
+	HAR        string `json:"har,omitempty"`
+	Viewport   string `json:"viewport,omitempty"` // WIDTHxHEIGHT
+	Browser    string `json:"browser,omitempty"`
+	// TracePolicy and VideoPolicy are off, on or retain-on-failure; empty uses
+	// the configured default.
+	TracePolicy string `json:"trace_policy,omitempty"`
+	VideoPolicy string `json:"video_policy,omitempty"`
+	Profile     string `json:"profile,omitempty"`
+	TraceID     string `json:"trace_id,omitempty"`
+}
+
+type PlaywrightRunOutput struct {
+	Browser string `json:"browser"`
+	// Files are artifact paths relative to ArtifactDir.
+	Files       []string      `json:"files"`
+	ArtifactDir string        `json:"artifact_dir"`
+	Duration    time.Duration `json:"duration"`
 }
 
 type JobRequest struct {
+	ID      string  `json:"id,omitempty"`
 	Type    JobType `json:"type"`
 	Payload any     `json:"payload"`
 
@@ -92,6 +190,12 @@ type JobRequest struct {
 	RetryMax     int           `json:"retry_max,omitempty"`
 	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
 	Idempotent   bool          `json:"idempotent,omitempty"`
+	TraceID      string        `json:"trace_id,omitempty"`
+	// IdempotencyKey deduplicates enqueues: it is run metadata, not input.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Priority orders the queue (low, normal or high): it is a run option, not
+	// input.
+	Priority string `json:"priority,omitempty"`
 }
 
 type JobStatus string
@@ -109,6 +213,7 @@ type JobResult struct {
 	Status    JobStatus `json:"status"`
 	Output    any       `json:"output,omitempty"`
 	Error     string    `json:"error,omitempty"`
+	Attempts  int       `json:"attempts,omitempty"`
 	StartedAt time.Time `json:"started_at,omitempty"`
 	EndedAt   time.Time `json:"ended_at,omitempty"`
 }
@@ -63,6 +63,9 @@ func TestJobTypes(t *testing.T) {
 	if JobTypeAlohaRun != "aloha.run" {
 		t.Errorf("JobTypeAlohaRun = %q, want %q", JobTypeAlohaRun, "aloha.run")
 	}
+	if JobTypePlaywrightRun != "playwright.run" {
+		t.Errorf("JobTypePlaywrightRun = %q, want %q", JobTypePlaywrightRun, "playwright.run")
+	}
 }
 
 func TestJobStatus(t *testing.T) {
@@ -0,0 +1,224 @@
+package hatchet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+// Idempotency stores select where enqueue remembers idempotency keys.
+const (
+	// IdempotencyHatchet finds keys in the run metadata of recent jobs.
+	IdempotencyHatchet = "hatchet"
+	// IdempotencyLocal keeps keys in a file, for Hatchet servers that cannot
+	// filter runs by metadata.
+	IdempotencyLocal = "local"
+)
+
+// IdempotencyKeyAuto asks enqueue to derive the key from the job's type and
+// payload.
+const IdempotencyKeyAuto = "auto"
+
+// IdempotencyMetadataKey is the run metadata key holding a job's idempotency key.
+const IdempotencyMetadataKey = "idempotency_key"
+
+// DeriveIdempotencyKey returns a key that is the same for every job of jobType
+// with payload, apart from its trace id, which is fresh on every enqueue.
+func DeriveIdempotencyKey(jobType JobType, payload any) (string, error) {
+	input, err := JobRequest{Payload: payload}.WorkflowInput()
+	if err != nil {
+		return "", err
+	}
+	delete(input, "trace_id")
+	// Maps marshal with sorted keys, so equal payloads hash the same.
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(string(jobType)+"\n"), data...))
+	return string(jobType) + ":" + hex.EncodeToString(sum[:16]), nil
+}
+
+// IdempotencyKeys remembers which job an idempotency key enqueued.
+type IdempotencyKeys interface {
+	// Find returns the latest job enqueued with key within the dedup window, or
+	// "" when there is none.
+	Find(ctx context.Context, key string) (string, error)
+	// Record notes that key enqueued jobID.
+	Record(ctx context.Context, key, jobID string) error
+	// Lock excludes other enqueues from Find to Record, where the store can;
+	// the returned func releases it.
+	Lock(ctx context.Context) (func(), error)
+}
+
+// NewIdempotencyKeys returns the store for cfg.HatchetIdempotencyStore.
+func NewIdempotencyKeys(cfg config.Config, client *sdk.Client) IdempotencyKeys {
+	if cfg.HatchetIdempotencyStore == IdempotencyLocal {
+		return &FileIdempotencyKeys{Path: cfg.HatchetIdempotencyFile, Window: cfg.HatchetDedupWindow}
+	}
+	return hatchetIdempotencyKeys{client: client, window: cfg.HatchetDedupWindow}
+}
+
+// hatchetIdempotencyKeys finds keys in run metadata, which enqueue sets when it
+// dispatches a job with a key, so recording is a no-op. Failed and cancelled
+// runs are not matched.
+type hatchetIdempotencyKeys struct {
+	client *sdk.Client
+	window time.Duration
+}
+
+func (h hatchetIdempotencyKeys) Find(ctx context.Context, key string) (string, error) {
+	limit := int64(1)
+	statuses := []rest.V1TaskStatus{rest.V1TaskStatusQUEUED, rest.V1TaskStatusRUNNING, rest.V1TaskStatusCOMPLETED}
+	runs, err := h.client.Runs().List(ctx, rest.V1WorkflowRunListParams{
+		Since:              time.Now().Add(-h.window),
+		Statuses:           &statuses,
+		Limit:              &limit,
+		AdditionalMetadata: &[]string{IdempotencyMetadataKey + ":" + key},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(runs.Rows) == 0 {
+		return "", nil
+	}
+	return runs.Rows[0].WorkflowRunExternalId.String(), nil
+}
+
+func (h hatchetIdempotencyKeys) Record(ctx context.Context, key, jobID string) error {
+	return nil
+}
+
+// Lock is a no-op: Hatchet has no lock to take, so two enqueues racing on a key
+// can both dispatch. Batches deduplicate their own keys before dispatching.
+func (h hatchetIdempotencyKeys) Lock(ctx context.Context) (func(), error) {
+	return func() {}, nil
+}
+
+// idempotencyRecord is a key's entry in the local store.
+type idempotencyRecord struct {
+	JobID      string    `json:"job_id"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// FileIdempotencyKeys keeps keys in a JSON file. Keys older than Window are
+// dropped on every write.
+type FileIdempotencyKeys struct {
+	Path   string
+	Window time.Duration
+
+	mu sync.Mutex
+}
+
+func (f *FileIdempotencyKeys) Find(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	record, ok := records[key]
+	if !ok || time.Since(record.EnqueuedAt) > f.Window {
+		return "", nil
+	}
+	return record.JobID, nil
+}
+
+func (f *FileIdempotencyKeys) Record(ctx context.Context, key, jobID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, err := f.read()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	maps.DeleteFunc(records, func(_ string, r idempotencyRecord) bool { return now.Sub(r.EnqueuedAt) > f.Window })
+	records[key] = idempotencyRecord{JobID: jobID, EnqueuedAt: now}
+	return f.write(records)
+}
+
+// idempotencyLockPoll is how often Lock retries a lock held by another enqueue.
+const idempotencyLockPoll = 20 * time.Millisecond
+
+// Lock takes an exclusive flock on Path.lock, so that enqueues sharing the file,
+// in this or any other process, run Find, dispatch and Record one at a time.
+func (f *FileIdempotencyKeys) Lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(f.Path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			// Closing the file releases the lock.
+			return func() { file.Close() }, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("lock %s: %w", file.Name(), err)
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(idempotencyLockPoll):
+		}
+	}
+}
+
+func (f *FileIdempotencyKeys) read() (map[string]idempotencyRecord, error) {
+	records := map[string]idempotencyRecord{}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return records, nil
+}
+
+// write replaces the file atomically, so a concurrent enqueue never reads a
+// partial write.
+func (f *FileIdempotencyKeys) write(records map[string]idempotencyRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".idempotency-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
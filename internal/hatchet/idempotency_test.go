@@ -0,0 +1,110 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeriveIdempotencyKey(t *testing.T) {
+	key, err := DeriveIdempotencyKey(JobTypeWindowsExec, map[string]any{"command": "hostname", "timeout": 2 * time.Minute, "trace_id": "t-1"})
+	if err != nil {
+		t.Fatalf("DeriveIdempotencyKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, "windows.exec:") {
+		t.Errorf("key = %q, want the job type prefix", key)
+	}
+
+	// The same job decoded from JSON, with another trace id, has the same key.
+	same, _ := DeriveIdempotencyKey(JobTypeWindowsExec, map[string]any{"trace_id": "t-2", "timeout": float64(2 * time.Minute), "command": "hostname"})
+	if same != key {
+		t.Errorf("key for the same job = %q, want %q", same, key)
+	}
+	for _, other := range []struct {
+		jobType JobType
+		payload any
+	}{
+		{JobTypeWindowsExec, map[string]any{"command": "whoami", "timeout": 2 * time.Minute}},
+		{JobTypeWindowsExec, map[string]any{"command": "hostname"}},
+		{JobTypeAlohaRun, map[string]any{"command": "hostname", "timeout": 2 * time.Minute}},
+	} {
+		if got, _ := DeriveIdempotencyKey(other.jobType, other.payload); got == key {
+			t.Errorf("DeriveIdempotencyKey(%s, %v) = %q, want a different key", other.jobType, other.payload, got)
+		}
+	}
+}
+
+func TestFileIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "idempotency.json")
+	keys := &FileIdempotencyKeys{Path: path, Window: time.Hour}
+
+	if jobID, err := keys.Find(ctx, "provision-web-1"); err != nil || jobID != "" {
+		t.Fatalf("Find() on a missing file = %q, %v; want no job", jobID, err)
+	}
+	if err := keys.Record(ctx, "provision-web-1", "job-1"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if jobID, err := keys.Find(ctx, "provision-web-1"); err != nil || jobID != "job-1" {
+		t.Errorf("Find() = %q, %v; want job-1", jobID, err)
+	}
+
+	// A new store over the same file sees the key; once the window has passed
+	// it does not, and the next write drops it.
+	expired := &FileIdempotencyKeys{Path: path, Window: time.Nanosecond}
+	time.Sleep(time.Millisecond)
+	if jobID, _ := expired.Find(ctx, "provision-web-1"); jobID != "" {
+		t.Errorf("Find() after the window = %q, want no job", jobID)
+	}
+	if err := expired.Record(ctx, "provision-web-2", "job-2"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	records, err := keys.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := records["provision-web-1"]; ok || len(records) != 1 {
+		t.Errorf("records = %v, want only provision-web-2", records)
+	}
+}
+
+func TestFileIdempotencyKeys_Lock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "idempotency.json")
+	// Separate stores stand in for separate processes sharing the file.
+	first := &FileIdempotencyKeys{Path: path, Window: time.Hour}
+	second := &FileIdempotencyKeys{Path: path, Window: time.Hour}
+
+	unlock, err := first.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := second.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock() while held error = %v, want deadline exceeded", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		unlock, err := second.Lock(context.Background())
+		if err != nil {
+			t.Errorf("Lock() after release error = %v", err)
+		}
+		acquired <- unlock
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Lock() acquired while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case unlock := <-acquired:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("Lock() not acquired after release")
+	}
+}
@@ -0,0 +1,294 @@
+package hatchet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/playwright"
+	"gopkg.in/yaml.v3"
+)
+
+// JobFileEntry is one job read from a jobs file, with the line it starts on.
+type JobFileEntry struct {
+	Line    int
+	Request JobRequest
+}
+
+// jobDocument is the on-disk shape of a JobRequest: durations are strings such
+// as "5m" and the payload is the job type's input.
+type jobDocument struct {
+	Type    string         `json:"type" yaml:"type"`
+	Payload map[string]any `json:"payload" yaml:"payload"`
+	Timeout string         `json:"timeout" yaml:"timeout"`
+	TraceID string         `json:"trace_id" yaml:"trace_id"`
+
+	RetryMax     int    `json:"retry_max" yaml:"retry_max"`
+	RetryBackoff string `json:"retry_backoff" yaml:"retry_backoff"`
+	Idempotent   bool   `json:"idempotent" yaml:"idempotent"`
+
+	IdempotencyKey string `json:"idempotency_key" yaml:"idempotency_key"`
+	Priority       string `json:"priority" yaml:"priority"`
+}
+
+var jobDocumentKeys = map[string]bool{
+	"type": true, "payload": true, "timeout": true, "trace_id": true,
+	"retry_max": true, "retry_backoff": true, "idempotent": true, "idempotency_key": true,
+	"priority": true,
+}
+
+// ParseJobFile reads the jobs in data. Files named *.yaml or *.yml hold a YAML
+// list or a stream of YAML documents; anything else is JSON Lines, where blank
+// lines and lines starting with # are skipped. Every entry is validated and all
+// problems are reported together, prefixed with name and line number.
+func ParseJobFile(name string, data []byte) ([]JobFileEntry, error) {
+	var (
+		entries []JobFileEntry
+		errs    []error
+	)
+	add := func(line int, doc jobDocument, err error) {
+		if err == nil {
+			var req JobRequest
+			req, err = doc.request()
+			if err == nil {
+				entries = append(entries, JobFileEntry{Line: line, Request: req})
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", name, line, err))
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		if err := parseYAMLJobs(data, add); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			var doc jobDocument
+			dec := json.NewDecoder(strings.NewReader(text))
+			dec.DisallowUnknownFields()
+			err := decodeJobJSON(dec, &doc)
+			add(line, doc, err)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: no jobs", name)
+	}
+	return entries, nil
+}
+
+// decodeJobJSON decodes exactly one JSON object from dec into doc.
+func decodeJobJSON(dec *json.Decoder, doc *jobDocument) error {
+	if err := dec.Decode(doc); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid json: trailing data after the object")
+	}
+	return nil
+}
+
+// parseYAMLJobs hands every job in a YAML list or document stream to add.
+func parseYAMLJobs(data []byte, add func(int, jobDocument, error)) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var root yaml.Node
+		if err := dec.Decode(&root); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(root.Content) == 0 {
+			continue
+		}
+		node := root.Content[0]
+		items := []*yaml.Node{node}
+		if node.Kind == yaml.SequenceNode {
+			items = node.Content
+		}
+		for _, item := range items {
+			var doc jobDocument
+			err := decodeJobYAML(item, &doc)
+			add(item.Line, doc, err)
+		}
+	}
+}
+
+func decodeJobYAML(node *yaml.Node, doc *jobDocument) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.New("want a mapping with type and payload")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; !jobDocumentKeys[key] {
+			return fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return node.Decode(doc)
+}
+
+// request validates the document and converts it to a JobRequest.
+func (d jobDocument) request() (JobRequest, error) {
+	req := JobRequest{Type: JobType(d.Type), TraceID: d.TraceID, IdempotencyKey: d.IdempotencyKey, Priority: d.Priority}
+	if _, err := RunPriority(d.Priority); err != nil {
+		return JobRequest{}, err
+	}
+	if d.Timeout != "" {
+		timeout, err := time.ParseDuration(d.Timeout)
+		if err != nil || timeout <= 0 {
+			return JobRequest{}, fmt.Errorf("invalid timeout %q: want a positive duration such as 5m", d.Timeout)
+		}
+		req.Timeout = timeout
+	}
+	if d.RetryMax < 0 {
+		return JobRequest{}, fmt.Errorf("invalid retry_max %d: must not be negative", d.RetryMax)
+	}
+	req.RetryMax = d.RetryMax
+	if d.RetryBackoff != "" {
+		backoff, err := time.ParseDuration(d.RetryBackoff)
+		if err != nil || backoff <= 0 {
+			return JobRequest{}, fmt.Errorf("invalid retry_backoff %q: want a positive duration such as 10s", d.RetryBackoff)
+		}
+		req.RetryBackoff = backoff
+	}
+	req.Idempotent = d.Idempotent
+	req.Payload = d.Payload
+	if err := ValidateJobPayload(req.Type, d.Payload); err != nil {
+		return JobRequest{}, err
+	}
+	return req, nil
+}
+
+// ValidateJobPayload checks that payload decodes to the input of jobType and has
+// what the worker requires, so bad jobs are rejected before they are queued.
+func ValidateJobPayload(jobType JobType, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	decode := func(input any) error {
+		if err := json.Unmarshal(data, input); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", jobType, err)
+		}
+		return nil
+	}
+	switch jobType {
+	case JobTypeWindowsExec:
+		var input WindowsExecInput
+		if err := decode(&input); err != nil {
+			return err
+		}
+		if strings.TrimSpace(input.Command) == "" {
+			return errors.New("payload.command is required for windows.exec")
+		}
+	case JobTypeAlohaRun:
+		var input AlohaRunInput
+		if err := decode(&input); err != nil {
+			return err
+		}
+		if strings.TrimSpace(input.Task) == "" {
+			return errors.New("payload.task is required for aloha.run")
+		}
+	case JobTypePlaywrightRun:
+		var input PlaywrightRunInput
+		if err := decode(&input); err != nil {
+			return err
+		}
+		if strings.TrimSpace(input.Script) == "" {
+			return errors.New("payload.script is required for playwright.run")
+		}
+		return validatePlaywrightOptions(input.Browser, input.Profile, input.Policies())
+	case JobTypePlaywrightCapture:
+		var input PlaywrightCaptureInput
+		if err := decode(&input); err != nil {
+			return err
+		}
+		spec := playwright.CaptureSpec{
+			URL:        input.URL,
+			Screenshot: input.Screenshot,
+			PDF:        input.PDF,
+			HAR:        input.HAR,
+			Viewport:   input.Viewport,
+		}
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+		policies := playwright.Policies{Trace: playwright.Policy(input.TracePolicy), Video: playwright.Policy(input.VideoPolicy)}
+		return validatePlaywrightOptions(input.Browser, input.Profile, policies)
+	case "":
+		return errors.New("type is required")
+	default:
+		return fmt.Errorf("unknown job type: %s", jobType)
+	}
+	return nil
+}
+
+func validatePlaywrightOptions(browser, profile string, policies playwright.Policies) error {
+	if browser != "" {
+		if err := playwright.ValidateBrowser(browser); err != nil {
+			return err
+		}
+	}
+	if profile != "" {
+		if err := playwright.ValidateProfile(profile); err != nil {
+			return err
+		}
+	}
+	return policies.Validate()
+}
+
+// WorkflowInput returns the payload to run the request's workflow with: the
+// payload plus the trace id, timeout and retry settings that are set. The worker
+// reads them back to bound and retry the job.
+func (r JobRequest) WorkflowInput() (map[string]any, error) {
+	data, err := json.Marshal(r.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]any
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	if input == nil {
+		input = map[string]any{}
+	}
+	if r.TraceID != "" {
+		input["trace_id"] = r.TraceID
+	}
+	if r.Timeout > 0 {
+		input["timeout"] = r.Timeout
+	}
+	if r.RetryMax > 0 {
+		input["retry_max"] = r.RetryMax
+	}
+	if r.RetryBackoff > 0 {
+		input["retry_backoff"] = r.RetryBackoff
+	}
+	if r.Idempotent {
+		input["idempotent"] = true
+	}
+	return input, nil
+}
@@ -0,0 +1,143 @@
+package hatchet
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJobFile_JSONL(t *testing.T) {
+	data := `# nightly sweep
+{"type":"windows.exec","payload":{"command":"hostname"},"timeout":"2m","trace_id":"t-1"}
+
+{"type":"aloha.run","payload":{"task":"open notepad","max_steps":5}}
+`
+	entries, err := ParseJobFile("jobs.jsonl", []byte(data))
+	if err != nil {
+		t.Fatalf("ParseJobFile() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	first := entries[0]
+	if first.Line != 2 || first.Request.Type != JobTypeWindowsExec || first.Request.Timeout != 2*time.Minute || first.Request.TraceID != "t-1" {
+		t.Errorf("first = %+v", first)
+	}
+	if entries[1].Line != 4 || entries[1].Request.Type != JobTypeAlohaRun {
+		t.Errorf("second = %+v", entries[1])
+	}
+
+	input, err := first.Request.WorkflowInput()
+	if err != nil {
+		t.Fatalf("WorkflowInput() error = %v", err)
+	}
+	if input["command"] != "hostname" || input["trace_id"] != "t-1" || input["timeout"] != 2*time.Minute {
+		t.Errorf("WorkflowInput() = %v", input)
+	}
+}
+
+func TestParseJobFile_RetrySettings(t *testing.T) {
+	data := `{"type":"windows.exec","payload":{"command":"Restart-Service Spooler"},"retry_max":2,"retry_backoff":"10s","idempotent":true,"idempotency_key":"spooler-1"}
+`
+	entries, err := ParseJobFile("jobs.jsonl", []byte(data))
+	if err != nil {
+		t.Fatalf("ParseJobFile() error = %v", err)
+	}
+	req := entries[0].Request
+	if req.RetryMax != 2 || req.RetryBackoff != 10*time.Second || !req.Idempotent || req.IdempotencyKey != "spooler-1" {
+		t.Errorf("request = %+v", req)
+	}
+	input, err := req.WorkflowInput()
+	if err != nil {
+		t.Fatalf("WorkflowInput() error = %v", err)
+	}
+	if input["retry_max"] != 2 || input["retry_backoff"] != 10*time.Second || input["idempotent"] != true || input["idempotency_key"] != nil {
+		t.Errorf("WorkflowInput() = %v", input)
+	}
+
+	if _, err := ParseJobFile("jobs.jsonl", []byte(`{"type":"aloha.run","payload":{"task":"x"},"retry_backoff":"-1s"}`)); err == nil || !strings.Contains(err.Error(), "invalid retry_backoff") {
+		t.Errorf("ParseJobFile() error = %v, want invalid retry_backoff", err)
+	}
+}
+
+func TestParseJobFile_Priority(t *testing.T) {
+	entries, err := ParseJobFile("jobs.jsonl", []byte(`{"type":"aloha.run","payload":{"task":"x"},"priority":"high"}`))
+	if err != nil {
+		t.Fatalf("ParseJobFile() error = %v", err)
+	}
+	if req := entries[0].Request; req.Priority != PriorityHigh {
+		t.Errorf("Priority = %q, want high", req.Priority)
+	}
+	if _, err := ParseJobFile("jobs.jsonl", []byte(`{"type":"aloha.run","payload":{"task":"x"},"priority":"urgent"}`)); err == nil || !strings.Contains(err.Error(), "invalid priority") {
+		t.Errorf("ParseJobFile() error = %v, want invalid priority", err)
+	}
+}
+
+func TestParseJobFile_YAML(t *testing.T) {
+	list := `- type: windows.exec
+  payload:
+    command: hostname
+- type: playwright.capture
+  payload:
+    url: https://intranet/
+    screenshot: page.png
+`
+	entries, err := ParseJobFile("jobs.yaml", []byte(list))
+	if err != nil {
+		t.Fatalf("ParseJobFile(list) error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Line != 1 || entries[1].Line != 4 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	stream := `type: windows.exec
+payload: {command: hostname}
+---
+type: aloha.run
+payload: {task: open notepad}
+`
+	entries, err = ParseJobFile("jobs.yml", []byte(stream))
+	if err != nil {
+		t.Fatalf("ParseJobFile(stream) error = %v", err)
+	}
+	if len(entries) != 2 || entries[1].Line != 4 {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestParseJobFile_ReportsEveryInvalidLine(t *testing.T) {
+	data := `{"type":"windows.exec","payload":{"command":"hostname"}}
+{"type":"windows.exec","payload":{}}
+{"type":"unknown.job","payload":{}}
+{"type":"aloha.run","payload":{"task":"x"},"timeout":"soon"}
+{"type":"aloha.run","paylod":{"task":"x"}}
+{"type":"playwright.capture","payload":{"url":"ftp://host/","pdf":"a.pdf"}}
+not json
+`
+	_, err := ParseJobFile("jobs.jsonl", []byte(data))
+	if err == nil {
+		t.Fatal("ParseJobFile() error = nil, want error")
+	}
+	for _, want := range []string{
+		"jobs.jsonl:2: payload.command is required",
+		"jobs.jsonl:3: unknown job type",
+		"jobs.jsonl:4: invalid timeout",
+		"jobs.jsonl:5: invalid json",
+		"jobs.jsonl:6: invalid url",
+		"jobs.jsonl:7: invalid json",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "jobs.jsonl:1:") {
+		t.Errorf("valid line 1 reported:\n%v", err)
+	}
+
+	if _, err := ParseJobFile("jobs.yaml", []byte("- type: aloha.run\n  payload: {task: x}\n  retries: 3\n")); err == nil || !strings.Contains(err.Error(), `jobs.yaml:1: unknown field "retries"`) {
+		t.Errorf("ParseJobFile(yaml) error = %v, want unknown field", err)
+	}
+	if _, err := ParseJobFile("jobs.jsonl", []byte("\n# nothing\n")); err == nil {
+		t.Error("ParseJobFile(empty) error = nil, want error")
+	}
+}
@@ -0,0 +1,162 @@
+package hatchet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	"github.com/hatchet-dev/hatchet/pkg/client/types"
+	clientconfig "github.com/hatchet-dev/hatchet/pkg/config/client"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+	"github.com/hatchet-dev/hatchet/sdks/go/features"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+// Job priorities order the queue: Hatchet starts queued high jobs before
+// normal ones and normal before low.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// Priorities lists the valid job priorities.
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh}
+
+// jobTaskTypes are the job types the worker runs as Hatchet tasks of the same
+// name, and so the types queue depth and concurrency limits apply to.
+var jobTaskTypes = []JobType{JobTypeWindowsExec, JobTypeAlohaRun, JobTypePlaywrightRun, JobTypePlaywrightCapture}
+
+// RunPriority maps a job priority to Hatchet's; empty is normal.
+func RunPriority(priority string) (sdk.RunPriority, error) {
+	switch priority {
+	case PriorityLow:
+		return features.RunPriorityLow, nil
+	case "", PriorityNormal:
+		return features.RunPriorityMedium, nil
+	case PriorityHigh:
+		return features.RunPriorityHigh, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q: want low, normal or high", priority)
+}
+
+// jobConcurrency returns the Hatchet concurrency limits of jobType's task from
+// cfg: a cap on all its running jobs, and one on those sharing the value of a
+// payload field. Jobs over a limit stay queued, and groups take turns.
+func jobConcurrency(cfg config.Config, jobType JobType) []types.Concurrency {
+	strategy := types.GroupRoundRobin
+	var limits []types.Concurrency
+	if n, ok := cfg.HatchetConcurrency[string(jobType)]; ok {
+		maxRuns := int32(n)
+		limits = append(limits, types.Concurrency{
+			// A constant key puts every job of the type in one group.
+			Expression:    fmt.Sprintf("'%s'", jobType),
+			MaxRuns:       &maxRuns,
+			LimitStrategy: &strategy,
+		})
+	}
+	if group, ok := cfg.HatchetConcurrencyGroups[string(jobType)]; ok {
+		maxRuns := int32(group.MaxRuns)
+		limits = append(limits, types.Concurrency{
+			// Jobs without the field share the empty group.
+			Expression:    fmt.Sprintf(`has(input.%[1]s) ? string(input.%[1]s) : ""`, group.Key),
+			MaxRuns:       &maxRuns,
+			LimitStrategy: &strategy,
+		})
+	}
+	return limits
+}
+
+// queueDepthWindow is how far back QueueDepths looks for queued jobs.
+const queueDepthWindow = 7 * 24 * time.Hour
+
+// QueueMonitor counts the queued jobs of each type in Hatchet.
+type QueueMonitor struct {
+	api       *rest.ClientWithResponses
+	tenant    uuid.UUID
+	namespace string
+	// workflows caches the Hatchet workflow id of each job type's task.
+	workflows map[JobType]uuid.UUID
+}
+
+// NewQueueMonitor returns a QueueMonitor for the Hatchet server in cfg.
+func NewQueueMonitor(cfg config.Config) (*QueueMonitor, error) {
+	client, err := newLegacyClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := uuid.Parse(client.TenantId())
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant id in hatchet token: %w", err)
+	}
+	return &QueueMonitor{
+		api:       client.API(),
+		tenant:    tenant,
+		namespace: client.Namespace(),
+		workflows: map[JobType]uuid.UUID{},
+	}, nil
+}
+
+// QueueDepths returns how many jobs of each type wait in the queue. Types no
+// worker has registered yet are left out.
+func (q *QueueMonitor) QueueDepths(ctx context.Context) (map[JobType]int, error) {
+	depths := map[JobType]int{}
+	for _, jobType := range jobTaskTypes {
+		id, err := q.workflowID(ctx, jobType)
+		if err != nil {
+			return depths, err
+		}
+		if id == uuid.Nil {
+			continue
+		}
+		resp, err := q.api.V1TaskListStatusMetricsWithResponse(ctx, q.tenant, &rest.V1TaskListStatusMetricsParams{
+			Since:       time.Now().Add(-queueDepthWindow),
+			WorkflowIds: &[]uuid.UUID{id},
+		})
+		if err != nil {
+			return depths, err
+		}
+		if resp.JSON200 == nil {
+			return depths, fmt.Errorf("task status metrics: %s", resp.Status())
+		}
+		depths[jobType] = queuedCount(*resp.JSON200)
+	}
+	return depths, nil
+}
+
+func queuedCount(statuses rest.V1TaskRunMetrics) int {
+	i := slices.IndexFunc(statuses, func(m rest.V1TaskRunMetric) bool { return m.Status == rest.V1TaskStatusQUEUED })
+	if i < 0 {
+		return 0
+	}
+	return statuses[i].Count
+}
+
+// workflowID returns the id of jobType's task, or uuid.Nil when it is not
+// registered.
+func (q *QueueMonitor) workflowID(ctx context.Context, jobType JobType) (uuid.UUID, error) {
+	if id, ok := q.workflows[jobType]; ok {
+		return id, nil
+	}
+	name := clientconfig.ApplyNamespace(string(jobType), &q.namespace)
+	resp, err := q.api.WorkflowListWithResponse(ctx, q.tenant, &rest.WorkflowListParams{Name: &name})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if resp.JSON200 == nil {
+		return uuid.Nil, fmt.Errorf("list workflows: %s", resp.Status())
+	}
+	if resp.JSON200.Rows == nil || len(*resp.JSON200.Rows) == 0 {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse((*resp.JSON200.Rows)[0].Metadata.Id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	q.workflows[jobType] = id
+	return id, nil
+}
@@ -0,0 +1,65 @@
+package hatchet
+
+import (
+	"testing"
+
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	"github.com/hatchet-dev/hatchet/sdks/go/features"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+func TestRunPriority(t *testing.T) {
+	for priority, want := range map[string]features.RunPriority{
+		"":             features.RunPriorityMedium,
+		PriorityLow:    features.RunPriorityLow,
+		PriorityNormal: features.RunPriorityMedium,
+		PriorityHigh:   features.RunPriorityHigh,
+	} {
+		got, err := RunPriority(priority)
+		if err != nil || got != want {
+			t.Errorf("RunPriority(%q) = %v, %v, want %v", priority, got, err, want)
+		}
+	}
+	if _, err := RunPriority("urgent"); err == nil {
+		t.Error("RunPriority(urgent) error = nil, want error")
+	}
+}
+
+func TestJobConcurrency(t *testing.T) {
+	cfg := config.Config{
+		HatchetConcurrency:       map[string]int{"aloha.run": 1, "windows.exec": 4},
+		HatchetConcurrencyGroups: map[string]config.ConcurrencyGroup{"aloha.run": {Key: "screen", MaxRuns: 1}},
+	}
+
+	limits := jobConcurrency(cfg, JobTypeAlohaRun)
+	if len(limits) != 2 {
+		t.Fatalf("jobConcurrency(aloha.run) = %d limits, want 2", len(limits))
+	}
+	if limits[0].Expression != "'aloha.run'" || *limits[0].MaxRuns != 1 {
+		t.Errorf("type limit = %s max %d", limits[0].Expression, *limits[0].MaxRuns)
+	}
+	if limits[1].Expression != `has(input.screen) ? string(input.screen) : ""` || *limits[1].MaxRuns != 1 {
+		t.Errorf("group limit = %s max %d", limits[1].Expression, *limits[1].MaxRuns)
+	}
+
+	if limits := jobConcurrency(cfg, JobTypeWindowsExec); len(limits) != 1 || *limits[0].MaxRuns != 4 {
+		t.Errorf("jobConcurrency(windows.exec) = %+v", limits)
+	}
+	if limits := jobConcurrency(cfg, JobTypePlaywrightRun); len(limits) != 0 {
+		t.Errorf("jobConcurrency(playwright.run) = %+v, want none", limits)
+	}
+}
+
+func TestQueuedCount(t *testing.T) {
+	statuses := rest.V1TaskRunMetrics{
+		{Status: rest.V1TaskStatusRUNNING, Count: 2},
+		{Status: rest.V1TaskStatusQUEUED, Count: 7},
+	}
+	if got := queuedCount(statuses); got != 7 {
+		t.Errorf("queuedCount() = %d, want 7", got)
+	}
+	if got := queuedCount(nil); got != 0 {
+		t.Errorf("queuedCount(nil) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,271 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	v0Client "github.com/hatchet-dev/hatchet/pkg/client"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	"github.com/hatchet-dev/hatchet/pkg/worker"
+	sdk "github.com/hatchet-dev/hatchet/sdks/go"
+	"github.com/hatchet-dev/hatchet/sdks/go/features"
+
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+// workflowRunTimeout bounds a whole workflow.run; each step is bounded by its own
+// timeout and the job timeout.
+const workflowRunTimeout = 24 * time.Hour
+
+// scheduleTickTimeout bounds a schedule.tick, which may wait for the schedule's
+// previous job under the queue overlap policy.
+const scheduleTickTimeout = 24 * time.Hour
+
+// scheduleActiveWindow is how far back a tick looks for the schedule's jobs that
+// are still running, and scheduleTickWindow for its previous tick.
+const (
+	scheduleActiveWindow = 7 * 24 * time.Hour
+	scheduleTickWindow   = 400 * 24 * time.Hour
+)
+
+// HatchetWorkflows returns the Hatchet tasks this worker serves: one per job
+// type, running the job through its handler with its timeout and retry policy
+// under the type's concurrency limits, and workflow.run, which executes a
+// WorkflowDef by spawning each step as a child run, and schedule.tick, which
+// schedules start. workflow.run and schedule.tick are durable so waiting on
+// other runs does not hold the slots those runs need.
+func (w *Worker) HatchetWorkflows(client *sdk.Client) []sdk.WorkflowBase {
+	var workflows []sdk.WorkflowBase
+	for _, jobType := range jobTaskTypes {
+		// HandleJob retries transient failures itself, so Hatchet retries none and
+		// the execution timeout covers every attempt. Runs enqueued without a
+		// priority are normal.
+		options := []sdk.StandaloneTaskOption{
+			sdk.WithExecutionTimeout(jobExecutionTimeout(w.cfg)),
+			sdk.WithRetries(0),
+			sdk.WithWorkflowDefaultPriority(features.RunPriorityMedium),
+		}
+		if limits := jobConcurrency(w.cfg, jobType); len(limits) > 0 {
+			options = append(options, sdk.WithWorkflowConcurrency(limits...))
+		}
+		task := client.NewStandaloneTask(string(jobType), func(ctx sdk.Context, input map[string]any) (any, error) {
+			req, err := jobRequestFromInput(w.cfg, ctx.WorkflowRunId(), jobType, input)
+			if err != nil {
+				return nil, err
+			}
+			result, err := w.HandleJob(ctx, req)
+			if result.Attempts > 1 {
+				ctx.Log(fmt.Sprintf("attempts=%d", result.Attempts))
+			}
+			if err != nil {
+				if output, ok := commandExited(result, err); ok {
+					ctx.Log(fmt.Sprintf("exit_code=%d", output.ExitCode))
+					return output, nil
+				}
+				return nil, err
+			}
+			return result.Output, nil
+		}, options...)
+		workflows = append(workflows, task)
+	}
+	workflows = append(workflows, client.NewStandaloneDurableTask(string(JobTypeWorkflowRun), func(ctx sdk.DurableContext, input WorkflowRunInput) (WorkflowRunOutput, error) {
+		return w.runWorkflow(ctx, client, input)
+	}, sdk.WithExecutionTimeout(workflowRunTimeout), sdk.WithWorkflowDefaultPriority(features.RunPriorityMedium)))
+	workflows = append(workflows, client.NewStandaloneDurableTask(ScheduleTickWorkflow, func(ctx sdk.DurableContext, input Schedule) (ScheduleTickOutput, error) {
+		out, err := RunScheduleTick(ctx, input, ctx.WorkflowRunId(), time.Now().UTC(), hatchetScheduleRuns{client: client})
+		if out.Missed > 0 {
+			ctx.Log(fmt.Sprintf("missed=%d", out.Missed))
+		}
+		return out, err
+	}, sdk.WithExecutionTimeout(scheduleTickTimeout)))
+	return workflows
+}
+
+// commandExited returns the output of a windows.exec job whose command ran and
+// exited non-zero, which the task returns instead of failing so the output is kept.
+// ssh's own connection failure (exit 255) is not a command exit.
+func commandExited(result *JobResult, err error) (WindowsExecOutput, bool) {
+	var exitErr *sshx.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code == sshx.ExitConnectionFailed {
+		return WindowsExecOutput{}, false
+	}
+	output, ok := result.Output.(WindowsExecOutput)
+	return output, ok && output.ExitCode != 0
+}
+
+// StartScheduleTick enqueues a schedule.tick run for s, as its cron trigger would.
+func StartScheduleTick(ctx context.Context, client *sdk.Client, s Schedule) error {
+	_, err := client.RunNoWait(ctx, ScheduleTickWorkflow, s, sdk.WithRunMetadata(map[string]string{"schedule_tick": s.Name}))
+	return err
+}
+
+// hatchetScheduleRuns finds a schedule's jobs and ticks by their run metadata:
+// jobs carry schedule=<name> and ticks schedule_tick=<name>.
+type hatchetScheduleRuns struct {
+	client *sdk.Client
+}
+
+func (h hatchetScheduleRuns) Active(ctx context.Context, schedule string) ([]string, error) {
+	statuses := []rest.V1TaskStatus{rest.V1TaskStatusQUEUED, rest.V1TaskStatusRUNNING}
+	runs, err := h.client.Runs().List(ctx, rest.V1WorkflowRunListParams{
+		Since:              time.Now().Add(-scheduleActiveWindow),
+		Statuses:           &statuses,
+		AdditionalMetadata: &[]string{"schedule:" + schedule},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(runs.Rows))
+	for _, row := range runs.Rows {
+		ids = append(ids, row.WorkflowRunExternalId.String())
+	}
+	return ids, nil
+}
+
+func (h hatchetScheduleRuns) LastTick(ctx context.Context, schedule, self string) (time.Time, error) {
+	limit := int64(10)
+	runs, err := h.client.Runs().List(ctx, rest.V1WorkflowRunListParams{
+		Since:              time.Now().Add(-scheduleTickWindow),
+		Limit:              &limit,
+		AdditionalMetadata: &[]string{"schedule_tick:" + schedule},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	var last time.Time
+	for _, row := range runs.Rows {
+		if row.WorkflowRunExternalId.String() != self && row.CreatedAt.After(last) {
+			last = row.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+func (h hatchetScheduleRuns) Cancel(ctx context.Context, jobIDs []string) error {
+	ids := make([]uuid.UUID, 0, len(jobIDs))
+	for _, jobID := range jobIDs {
+		id, err := uuid.Parse(jobID)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	_, err := h.client.Runs().Cancel(ctx, rest.V1CancelTaskRequest{ExternalIds: &ids})
+	return err
+}
+
+func (h hatchetScheduleRuns) Start(ctx context.Context, s Schedule, traceID string) (string, error) {
+	req := s.Job
+	req.TraceID = traceID
+	input, err := req.WorkflowInput()
+	if err != nil {
+		return "", err
+	}
+	priority, err := RunPriority(s.Job.Priority)
+	if err != nil {
+		return "", err
+	}
+	ref, err := h.client.RunNoWait(ctx, string(s.Job.Type), input,
+		sdk.WithRunMetadata(map[string]string{"trace_id": traceID, "schedule": s.Name}),
+		sdk.WithRunPriority(priority))
+	if err != nil {
+		return "", err
+	}
+	return ref.RunId, nil
+}
+
+func (w *Worker) runWorkflow(ctx sdk.Context, client *sdk.Client, input WorkflowRunInput) (WorkflowRunOutput, error) {
+	fields := []logx.Field{
+		{Key: "job_id", Value: ctx.WorkflowRunId()},
+		{Key: "workflow", Value: input.Workflow.Name},
+		{Key: "trace_id", Value: input.TraceID},
+	}
+	if err := input.Workflow.Validate(w.cfg.HatchetJobTimeout); err != nil {
+		logx.Error("workflow", "run", "invalid workflow", err, fields...)
+		return WorkflowRunOutput{}, err
+	}
+	logx.Info("workflow", "run", "started", fields...)
+	output, err := RunWorkflow(ctx, input.Workflow, input.TraceID, spawnStep(ctx, client, input.TraceID))
+	// A failed run keeps no output, so the step summary also goes to the run's log.
+	for _, step := range output.Steps {
+		ctx.Log(fmt.Sprintf("step=%s state=%s job_id=%s attempts=%d %s", step.ID, step.State, step.JobID, step.Attempts, step.Error+step.Reason))
+	}
+	if err != nil {
+		logx.Error("workflow", "run", "failed", err, fields...)
+		return output, err
+	}
+	logx.Info("workflow", "run", "completed", append(fields, logx.Field{Key: "duration_ms", Value: output.Duration.Milliseconds()})...)
+	return output, nil
+}
+
+// spawnStep returns a StepRunner that runs each step attempt as a child run of
+// the workflow, so every step has its own job id, logs and artifacts and the
+// workflow's priority. A timed out attempt cancels its child.
+func spawnStep(hctx sdk.Context, client *sdk.Client, traceID string) StepRunner {
+	var spawnMu sync.Mutex
+	return func(ctx context.Context, step WorkflowStep, attempt int, input map[string]any) (string, map[string]any, error) {
+		// The key makes a re-run of the parent task pick up the same children.
+		key := fmt.Sprintf("%s-%d", step.ID, attempt)
+		metadata := map[string]string{"trace_id": traceID, "workflow_step": step.ID}
+		opts := &worker.SpawnWorkflowOpts{Key: &key, AdditionalMetadata: &metadata}
+		if priority := hctx.Priority(); priority > 0 {
+			opts.Priority = &priority
+		}
+		spawnMu.Lock()
+		child, err := hctx.SpawnWorkflow(string(step.Type), input, opts)
+		spawnMu.Unlock()
+		if err != nil {
+			return "", nil, err
+		}
+		jobID := child.RunId()
+
+		type childResult struct {
+			result *v0Client.WorkflowResult
+			err    error
+		}
+		results := make(chan childResult, 1)
+		go func() {
+			result, err := child.Result()
+			results <- childResult{result: result, err: err}
+		}()
+
+		select {
+		case <-ctx.Done():
+			cancelRun(client, jobID)
+			return jobID, nil, ctx.Err()
+		case r := <-results:
+			if r.err != nil {
+				return jobID, nil, r.err
+			}
+			var output map[string]any
+			if err := r.result.StepOutput(string(step.Type), &output); err != nil {
+				return jobID, nil, err
+			}
+			if code := FailedExitCode(step.Type, output); code != 0 {
+				return jobID, output, &sshx.ExitError{Code: code}
+			}
+			return jobID, output, nil
+		}
+	}
+}
+
+func cancelRun(client *sdk.Client, jobID string) {
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	request := rest.V1CancelTaskRequest{ExternalIds: &[]uuid.UUID{id}}
+	if _, err := client.Runs().Cancel(ctx, request); err != nil {
+		logx.Warn("workflow", "step", "cancel failed",
+			logx.Field{Key: "job_id", Value: jobID},
+			logx.Field{Key: "err", Value: err.Error()},
+		)
+	}
+}
@@ -0,0 +1,47 @@
+package hatchet
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+func TestCommandExited(t *testing.T) {
+	output := WindowsExecOutput{Stdout: "partial\n", ExitCode: 3}
+	tests := []struct {
+		name   string
+		result *JobResult
+		err    error
+		want   bool
+	}{
+		{"exited", &JobResult{Output: output}, fmt.Errorf("run: %w", &sshx.ExitError{Code: 3}), true},
+		{"connection failed", &JobResult{Output: WindowsExecOutput{ExitCode: 255}}, &sshx.ExitError{Code: sshx.ExitConnectionFailed}, false},
+		{"other error", &JobResult{Output: output}, errors.New("timeout"), false},
+		{"other job type", &JobResult{Output: AlohaRunOutput{}}, &sshx.ExitError{Code: 3}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := commandExited(tt.result, tt.err)
+			if ok != tt.want {
+				t.Fatalf("commandExited() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && got != output {
+				t.Errorf("commandExited() = %+v, want %+v", got, output)
+			}
+		})
+	}
+}
+
+func TestFailedExitCode(t *testing.T) {
+	if got := FailedExitCode(JobTypeWindowsExec, map[string]any{"exit_code": float64(3)}); got != 3 {
+		t.Errorf("FailedExitCode(windows.exec, 3) = %d, want 3", got)
+	}
+	if got := FailedExitCode(JobTypeWindowsExec, map[string]any{"exit_code": float64(0)}); got != 0 {
+		t.Errorf("FailedExitCode(windows.exec, 0) = %d, want 0", got)
+	}
+	if got := FailedExitCode(JobTypeAlohaRun, map[string]any{"exit_code": float64(3)}); got != 0 {
+		t.Errorf("FailedExitCode(aloha.run) = %d, want 0", got)
+	}
+}
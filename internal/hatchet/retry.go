@@ -0,0 +1,98 @@
+package hatchet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+// maxRetryDelay caps the doubling delay between attempts of a job.
+const maxRetryDelay = 2 * time.Minute
+
+// jobPolicy is the part of a job's input that WorkflowInput adds for the worker:
+// the attempt timeout and the retry policy. The rest is the job type's payload.
+type jobPolicy struct {
+	Timeout      time.Duration `json:"timeout"`
+	RetryMax     int           `json:"retry_max"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+	Idempotent   bool          `json:"idempotent"`
+}
+
+// jobRequestFromInput rebuilds the request a job's input was made from, with
+// what the input leaves unset filled from cfg.
+func jobRequestFromInput(cfg config.Config, id string, jobType JobType, input map[string]any) (*JobRequest, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var policy jobPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid %s timeout or retry settings: %w", jobType, err)
+	}
+	req := &JobRequest{
+		ID:           id,
+		Type:         jobType,
+		Payload:      input,
+		Timeout:      policy.Timeout,
+		RetryMax:     policy.RetryMax,
+		RetryBackoff: policy.RetryBackoff,
+		Idempotent:   policy.Idempotent,
+	}
+	if traceID, ok := input["trace_id"].(string); ok {
+		req.TraceID = traceID
+	}
+	return req.WithDefaults(cfg), nil
+}
+
+// jobExecutionTimeout is the Hatchet execution timeout of a job task: every
+// attempt HandleJob may make with cfg's limits, plus the delays between them.
+func jobExecutionTimeout(cfg config.Config) time.Duration {
+	attempts := time.Duration(cfg.HatchetRetryMax + 1)
+	return attempts*cfg.HatchetJobTimeout + (attempts-1)*maxRetryDelay
+}
+
+// IsTransient reports whether err may succeed if the job is simply run again:
+// ssh failing to connect or losing the connection, an HTTP 5xx from Aloha or a
+// network error. Command exit codes, timeouts and invalid input are not.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var exitErr *sshx.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code == sshx.ExitConnectionFailed
+	}
+	var statusErr *aloha.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// shouldRetry reports whether a failed attempt is retried. Idempotent jobs are
+// also retried when the remote command exits non-zero.
+func shouldRetry(err error, idempotent bool) bool {
+	var exitErr *sshx.ExitError
+	if idempotent && errors.As(err, &exitErr) && exitErr.Code > 0 {
+		return true
+	}
+	return IsTransient(err)
+}
+
+// retryDelay is the delay before retry n (1-based): backoff doubled for every
+// earlier retry, capped at maxRetryDelay.
+func retryDelay(backoff time.Duration, n int) time.Duration {
+	delay := backoff
+	for i := 1; i < n && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
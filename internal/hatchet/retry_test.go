@@ -0,0 +1,82 @@
+package hatchet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"ssh connection failed", fmt.Errorf("run: %w", &sshx.ExitError{Code: 255}), true},
+		{"command exit code", &sshx.ExitError{Code: 1}, false},
+		{"aloha 503", &aloha.StatusError{Endpoint: "client", Status: 503}, true},
+		{"aloha 400", &aloha.StatusError{Endpoint: "client", Status: 400}, false},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"deadline", fmt.Errorf("dial: %w", context.DeadlineExceeded), false},
+		{"invalid payload", errors.New("command is required"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+
+	if shouldRetry(&sshx.ExitError{Code: 1}, false) || !shouldRetry(&sshx.ExitError{Code: 1}, true) {
+		t.Error("shouldRetry() must retry exit codes only for idempotent jobs")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for n, want := range map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 3: 20 * time.Second, 10: maxRetryDelay} {
+		if got := retryDelay(5*time.Second, n); got != want {
+			t.Errorf("retryDelay(5s, %d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestJobRequestFromInput(t *testing.T) {
+	cfg := config.Config{HatchetJobTimeout: 10 * time.Minute, HatchetRetryMax: 3, HatchetRetryBackoff: 5 * time.Second}
+	sent := JobRequest{
+		Type:       JobTypeWindowsExec,
+		Payload:    WindowsExecInput{Command: "hostname"},
+		Timeout:    2 * time.Minute,
+		RetryMax:   1,
+		Idempotent: true,
+		TraceID:    "t-1",
+	}
+	input, err := sent.WorkflowInput()
+	if err != nil {
+		t.Fatalf("WorkflowInput() error = %v", err)
+	}
+	// The worker receives the input as decoded JSON.
+	data, _ := json.Marshal(input)
+	var received map[string]any
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := jobRequestFromInput(cfg, "job-1", JobTypeWindowsExec, received)
+	if err != nil {
+		t.Fatalf("jobRequestFromInput() error = %v", err)
+	}
+	if req.ID != "job-1" || req.Timeout != 2*time.Minute || req.RetryMax != 1 || req.RetryBackoff != 5*time.Second || !req.Idempotent || req.TraceID != "t-1" {
+		t.Errorf("request = %+v", req)
+	}
+
+	if _, err := jobRequestFromInput(cfg, "job-2", JobTypeAlohaRun, map[string]any{"task": "x", "timeout": "2m"}); err == nil {
+		t.Error("jobRequestFromInput() with a string timeout error = nil, want error")
+	}
+}
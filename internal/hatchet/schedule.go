@@ -0,0 +1,192 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// ScheduleTickWorkflow is the worker task a schedule starts on every cron tick.
+// It applies the overlap policy, reports missed ticks and enqueues the job.
+const ScheduleTickWorkflow = "schedule.tick"
+
+// Overlap policies decide what a tick does while the schedule's previous job is
+// still queued or running.
+const (
+	OverlapSkip           = "skip"
+	OverlapQueue          = "queue"
+	OverlapCancelPrevious = "cancel-previous"
+)
+
+// OverlapPolicies lists the valid overlap policies.
+var OverlapPolicies = []string{OverlapSkip, OverlapQueue, OverlapCancelPrevious}
+
+var scheduleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// cronParser accepts the five-field expressions Hatchet cron triggers accept.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Schedule is a job enqueued on a cron expression. It is also the input of its
+// schedule.tick runs, so ticks need nothing but their input.
+type Schedule struct {
+	Name    string     `json:"name"`
+	Cron    string     `json:"cron"`
+	Overlap string     `json:"overlap"`
+	Job     JobRequest `json:"job"`
+	Created time.Time  `json:"created"`
+	Paused  bool       `json:"paused,omitempty"`
+	// ID is the Hatchet cron trigger id; local schedules have none.
+	ID string `json:"id,omitempty"`
+}
+
+// ScheduleTickOutput is the output of a schedule.tick run.
+type ScheduleTickOutput struct {
+	Schedule  string   `json:"schedule"`
+	JobID     string   `json:"job_id,omitempty"`
+	TraceID   string   `json:"trace_id,omitempty"`
+	Skipped   bool     `json:"skipped,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Cancelled []string `json:"cancelled,omitempty"`
+	// Missed counts the ticks due since the previous one that never started.
+	Missed int `json:"missed,omitempty"`
+}
+
+// Validate checks the name, cron expression, overlap policy and job.
+func (s Schedule) Validate() error {
+	if !scheduleNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid schedule name %q: use lowercase letters, digits, '.', '_' and '-'", s.Name)
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return err
+	}
+	if !slices.Contains(OverlapPolicies, s.Overlap) {
+		return fmt.Errorf("invalid overlap policy %q: want skip, queue or cancel-previous", s.Overlap)
+	}
+	switch s.Job.Type {
+	case JobTypeWindowsExec, JobTypeAlohaRun, JobTypePlaywrightRun, JobTypePlaywrightCapture, JobTypeWorkflowRun:
+	default:
+		return fmt.Errorf("unknown job type %q", s.Job.Type)
+	}
+	return nil
+}
+
+// ParseCron parses a five-field cron expression (minute hour day month weekday).
+func ParseCron(expression string) (cron.Schedule, error) {
+	sched, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+	return sched, nil
+}
+
+// NextRun returns the schedule's next tick after now.
+func (s Schedule) NextRun(now time.Time) time.Time {
+	sched, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return sched.Next(now)
+}
+
+// missedTicks counts the ticks due after last and up to until. The count is
+// capped, since a long outage of a frequent schedule only needs reporting.
+func missedTicks(sched cron.Schedule, last, until time.Time) int {
+	const limit = 10000
+	n := 0
+	for t := sched.Next(last); !t.After(until) && n < limit; t = sched.Next(t) {
+		n++
+	}
+	return n
+}
+
+// ScheduleRuns is what a schedule tick needs from Hatchet.
+type ScheduleRuns interface {
+	// Active returns the ids of the schedule's jobs that are queued or running.
+	Active(ctx context.Context, schedule string) ([]string, error)
+	// LastTick returns when the schedule's latest tick other than self started,
+	// or the zero time.
+	LastTick(ctx context.Context, schedule, self string) (time.Time, error)
+	Cancel(ctx context.Context, jobIDs []string) error
+	// Start enqueues the schedule's job and returns its id.
+	Start(ctx context.Context, s Schedule, traceID string) (string, error)
+}
+
+// RunScheduleTick runs one tick of s: it reports ticks missed since the previous
+// one, applies the overlap policy to jobs still running and enqueues the job
+// with a fresh trace id. self is the tick's own run id.
+func RunScheduleTick(ctx context.Context, s Schedule, self string, now time.Time, runs ScheduleRuns) (ScheduleTickOutput, error) {
+	out := ScheduleTickOutput{Schedule: s.Name}
+	fields := []logx.Field{{Key: "schedule", Value: s.Name}, {Key: "tick_id", Value: self}}
+
+	sched, err := ParseCron(s.Cron)
+	if err != nil {
+		return out, err
+	}
+	last, err := runs.LastTick(ctx, s.Name, self)
+	if err != nil {
+		logx.Warn("schedule", "tick", "previous tick unavailable", append(fields, logx.Field{Key: "err", Value: err.Error()})...)
+	}
+	if last.Before(s.Created) {
+		last = s.Created
+	}
+	// The tick itself is the last one due up to now.
+	if !last.IsZero() {
+		out.Missed = max(missedTicks(sched, last, now)-1, 0)
+	}
+	if out.Missed > 0 {
+		logx.Warn("schedule", "tick", "missed runs", append(fields,
+			logx.Field{Key: "missed", Value: out.Missed},
+			logx.Field{Key: "since", Value: last.UTC().Format(time.RFC3339)},
+		)...)
+	}
+
+	active, err := runs.Active(ctx, s.Name)
+	if err != nil {
+		return out, fmt.Errorf("list running jobs: %w", err)
+	}
+	if len(active) > 0 {
+		switch s.Overlap {
+		case OverlapSkip:
+			out.Skipped = true
+			out.Reason = fmt.Sprintf("previous job %s is still running", active[0])
+			logx.Info("schedule", "tick", "skipped", append(fields, logx.Field{Key: "reason", Value: out.Reason})...)
+			return out, nil
+		case OverlapCancelPrevious:
+			if err := runs.Cancel(ctx, active); err != nil {
+				return out, fmt.Errorf("cancel previous jobs: %w", err)
+			}
+			out.Cancelled = active
+			logx.Info("schedule", "tick", "cancelled previous", append(fields, logx.Field{Key: "job_ids", Value: active})...)
+		case OverlapQueue:
+			logx.Info("schedule", "tick", "waiting for previous", append(fields, logx.Field{Key: "job_ids", Value: active})...)
+			err := PollUntil(ctx, nil, func(ctx context.Context) (bool, error) {
+				active, err := runs.Active(ctx, s.Name)
+				return len(active) == 0, err
+			})
+			if err != nil {
+				return out, fmt.Errorf("wait for previous jobs: %w", err)
+			}
+		default:
+			return out, errors.New("invalid overlap policy " + s.Overlap)
+		}
+	}
+
+	out.TraceID = uuid.NewString()
+	out.JobID, err = runs.Start(ctx, s, out.TraceID)
+	if err != nil {
+		return out, fmt.Errorf("enqueue %s: %w", s.Job.Type, err)
+	}
+	logx.Info("schedule", "tick", "enqueued", append(fields,
+		logx.Field{Key: "job_id", Value: out.JobID},
+		logx.Field{Key: "trace_id", Value: out.TraceID},
+	)...)
+	return out, nil
+}
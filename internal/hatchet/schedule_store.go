@@ -0,0 +1,357 @@
+package hatchet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hatchet-dev/hatchet/pkg/client/rest"
+	clientconfig "github.com/hatchet-dev/hatchet/pkg/config/client"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// Schedulers select where schedules live and who starts their ticks.
+const (
+	// SchedulerHatchet keeps schedules as Hatchet cron triggers on schedule.tick.
+	SchedulerHatchet = "hatchet"
+	// SchedulerLocal keeps schedules in a file that the worker's built-in
+	// scheduler reads, for Hatchet servers without cron triggers.
+	SchedulerLocal = "local"
+)
+
+// ErrScheduleNotFound is returned for an unknown schedule name.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduleStore creates, lists, deletes and pauses schedules.
+type ScheduleStore interface {
+	List(ctx context.Context) ([]Schedule, error)
+	Create(ctx context.Context, s Schedule) (Schedule, error)
+	Delete(ctx context.Context, name string) error
+	SetPaused(ctx context.Context, name string, paused bool) error
+}
+
+// NewScheduleStore returns the store for cfg.HatchetScheduler.
+func NewScheduleStore(cfg config.Config) (ScheduleStore, error) {
+	if cfg.HatchetScheduler == SchedulerLocal {
+		return FileSchedules{Path: cfg.HatchetScheduleFile}, nil
+	}
+	client, err := newLegacyClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := uuid.Parse(client.TenantId())
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant id in hatchet token: %w", err)
+	}
+	namespace := client.Namespace()
+	return hatchetSchedules{
+		api:      client.API(),
+		tenant:   tenant,
+		workflow: clientconfig.ApplyNamespace(ScheduleTickWorkflow, &namespace),
+	}, nil
+}
+
+// FileSchedules keeps schedules in a JSON file.
+type FileSchedules struct {
+	Path string
+}
+
+func (f FileSchedules) List(ctx context.Context) ([]Schedule, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return schedules, nil
+}
+
+func (f FileSchedules) Create(ctx context.Context, s Schedule) (Schedule, error) {
+	schedules, err := f.List(ctx)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if slices.ContainsFunc(schedules, func(other Schedule) bool { return other.Name == s.Name }) {
+		return Schedule{}, fmt.Errorf("schedule %q already exists", s.Name)
+	}
+	schedules = append(schedules, s)
+	return s, f.save(schedules)
+}
+
+func (f FileSchedules) Delete(ctx context.Context, name string) error {
+	schedules, err := f.List(ctx)
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(schedules), func(s Schedule) bool { return s.Name == name })
+	if len(kept) == len(schedules) {
+		return ErrScheduleNotFound
+	}
+	return f.save(kept)
+}
+
+func (f FileSchedules) SetPaused(ctx context.Context, name string, paused bool) error {
+	schedules, err := f.List(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(schedules, func(s Schedule) bool { return s.Name == name })
+	if i < 0 {
+		return ErrScheduleNotFound
+	}
+	schedules[i].Paused = paused
+	return f.save(schedules)
+}
+
+// save replaces the file atomically, so the worker never reads a partial write.
+func (f FileSchedules) save(schedules []Schedule) error {
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".schedules-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// hatchetSchedules keeps schedules as cron triggers on schedule.tick whose input
+// is the schedule. The SDK cron client cannot pause triggers, so this uses the
+// REST API directly.
+type hatchetSchedules struct {
+	api    *rest.ClientWithResponses
+	tenant uuid.UUID
+	// workflow is schedule.tick with the client's namespace applied.
+	workflow string
+}
+
+func (h hatchetSchedules) List(ctx context.Context) ([]Schedule, error) {
+	var schedules []Schedule
+	for offset := int64(0); ; {
+		limit := int64(100)
+		resp, err := h.api.CronWorkflowListWithResponse(ctx, h.tenant, &rest.CronWorkflowListParams{
+			WorkflowName: &h.workflow,
+			Offset:       &offset,
+			Limit:        &limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.JSON200 == nil {
+			return nil, fmt.Errorf("list cron triggers: %s", resp.Status())
+		}
+		var rows []rest.CronWorkflows
+		if resp.JSON200.Rows != nil {
+			rows = *resp.JSON200.Rows
+		}
+		for _, row := range rows {
+			schedules = append(schedules, scheduleFromCron(row))
+		}
+		if int64(len(rows)) < limit {
+			return schedules, nil
+		}
+		offset += limit
+	}
+}
+
+func scheduleFromCron(row rest.CronWorkflows) Schedule {
+	var s Schedule
+	if row.Input != nil {
+		if data, err := json.Marshal(*row.Input); err == nil {
+			_ = json.Unmarshal(data, &s)
+		}
+	}
+	if row.Name != nil {
+		s.Name = *row.Name
+	}
+	s.Cron = row.Cron
+	s.Paused = !row.Enabled
+	s.ID = row.Metadata.Id
+	return s
+}
+
+func (h hatchetSchedules) Create(ctx context.Context, s Schedule) (Schedule, error) {
+	if _, err := h.find(ctx, s.Name); err == nil {
+		return Schedule{}, fmt.Errorf("schedule %q already exists", s.Name)
+	} else if !errors.Is(err, ErrScheduleNotFound) {
+		return Schedule{}, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return Schedule{}, err
+	}
+	var input map[string]any
+	if err := json.Unmarshal(data, &input); err != nil {
+		return Schedule{}, err
+	}
+	resp, err := h.api.CronWorkflowTriggerCreateWithResponse(ctx, h.tenant, h.workflow, rest.CreateCronWorkflowTriggerRequest{
+		CronName:           s.Name,
+		CronExpression:     s.Cron,
+		Input:              input,
+		AdditionalMetadata: map[string]any{"schedule_tick": s.Name},
+	})
+	if err != nil {
+		return Schedule{}, err
+	}
+	if resp.JSON200 == nil {
+		return Schedule{}, fmt.Errorf("create cron trigger: %s: %s", resp.Status(), resp.Body)
+	}
+	created := scheduleFromCron(*resp.JSON200)
+	if s.Paused {
+		if err := h.SetPaused(ctx, s.Name, true); err != nil {
+			return created, err
+		}
+		created.Paused = true
+	}
+	return created, nil
+}
+
+func (h hatchetSchedules) Delete(ctx context.Context, name string) error {
+	s, err := h.find(ctx, name)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(s.ID)
+	if err != nil {
+		return err
+	}
+	resp, err := h.api.WorkflowCronDeleteWithResponse(ctx, h.tenant, id)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 300 {
+		return fmt.Errorf("delete cron trigger: %s", resp.Status())
+	}
+	return nil
+}
+
+func (h hatchetSchedules) SetPaused(ctx context.Context, name string, paused bool) error {
+	s, err := h.find(ctx, name)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(s.ID)
+	if err != nil {
+		return err
+	}
+	enabled := !paused
+	resp, err := h.api.WorkflowCronUpdateWithResponse(ctx, h.tenant, id, rest.UpdateCronWorkflowTriggerRequest{Enabled: &enabled})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 300 {
+		return fmt.Errorf("update cron trigger: %s", resp.Status())
+	}
+	return nil
+}
+
+func (h hatchetSchedules) find(ctx context.Context, name string) (Schedule, error) {
+	schedules, err := h.List(ctx)
+	if err != nil {
+		return Schedule{}, err
+	}
+	for _, s := range schedules {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Schedule{}, ErrScheduleNotFound
+}
+
+// localSchedulerInterval is how often the built-in scheduler rereads the
+// schedule file and starts due ticks.
+const localSchedulerInterval = 10 * time.Second
+
+// localScheduler tracks the next tick of every schedule in the file.
+type localScheduler struct {
+	next map[string]time.Time
+	cron map[string]string
+}
+
+// due returns the schedules whose next tick has come and advances them. A new
+// or changed schedule starts counting from now, so restarts do not replay ticks;
+// the ticks report what was missed.
+func (l *localScheduler) due(schedules []Schedule, now time.Time) []Schedule {
+	var due []Schedule
+	seen := map[string]bool{}
+	for _, s := range schedules {
+		seen[s.Name] = true
+		sched, err := ParseCron(s.Cron)
+		if err != nil || s.Paused {
+			delete(l.next, s.Name)
+			continue
+		}
+		next, ok := l.next[s.Name]
+		if !ok || l.cron[s.Name] != s.Cron {
+			l.next[s.Name] = sched.Next(now)
+			l.cron[s.Name] = s.Cron
+			continue
+		}
+		if !now.Before(next) {
+			due = append(due, s)
+			l.next[s.Name] = sched.Next(now)
+		}
+	}
+	for name := range l.next {
+		if !seen[name] {
+			delete(l.next, name)
+			delete(l.cron, name)
+		}
+	}
+	return due
+}
+
+// RunLocalScheduler starts a schedule.tick run with start for every due tick of
+// the schedules in store until ctx ends. It is the worker's stand-in for Hatchet
+// cron triggers when the scheduler is local.
+func RunLocalScheduler(ctx context.Context, store ScheduleStore, start func(context.Context, Schedule) error) {
+	l := &localScheduler{next: map[string]time.Time{}, cron: map[string]string{}}
+	ticker := time.NewTicker(localSchedulerInterval)
+	defer ticker.Stop()
+	logx.Info("schedule", "local", "started")
+	for {
+		schedules, err := store.List(ctx)
+		if err != nil {
+			// Keep the pending ticks; they start once the file reads again.
+			logx.Error("schedule", "local", "read schedules failed", err)
+		} else {
+			for _, s := range l.due(schedules, time.Now().UTC()) {
+				if err := start(ctx, s); err != nil {
+					logx.Error("schedule", "local", "start tick failed", err, logx.Field{Key: "schedule", Value: s.Name})
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
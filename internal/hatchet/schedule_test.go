@@ -0,0 +1,178 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Validate(t *testing.T) {
+	valid := Schedule{Name: "nightly-cleanup", Cron: "0 2 * * *", Overlap: OverlapSkip, Job: JobRequest{Type: JobTypeWindowsExec}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	tests := []struct {
+		name   string
+		change func(*Schedule)
+		want   string
+	}{
+		{"name", func(s *Schedule) { s.Name = "Nightly Cleanup" }, "invalid schedule name"},
+		{"cron", func(s *Schedule) { s.Cron = "0 2 * *" }, "invalid cron expression"},
+		{"seconds", func(s *Schedule) { s.Cron = "0 0 2 * * *" }, "invalid cron expression"},
+		{"overlap", func(s *Schedule) { s.Overlap = "replace" }, "invalid overlap policy"},
+		{"type", func(s *Schedule) { s.Job.Type = "shell" }, "unknown job type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.change(&s)
+			if err := s.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+type fakeScheduleRuns struct {
+	active    []string
+	lastTick  time.Time
+	cancelled []string
+	started   []string
+}
+
+func (f *fakeScheduleRuns) Active(ctx context.Context, schedule string) ([]string, error) {
+	return f.active, nil
+}
+
+func (f *fakeScheduleRuns) LastTick(ctx context.Context, schedule, self string) (time.Time, error) {
+	return f.lastTick, nil
+}
+
+func (f *fakeScheduleRuns) Cancel(ctx context.Context, jobIDs []string) error {
+	f.cancelled = append(f.cancelled, jobIDs...)
+	f.active = nil
+	return nil
+}
+
+func (f *fakeScheduleRuns) Start(ctx context.Context, s Schedule, traceID string) (string, error) {
+	if traceID == "" {
+		return "", errors.New("missing trace id")
+	}
+	f.started = append(f.started, s.Name)
+	return "job-new", nil
+}
+
+func TestRunScheduleTick(t *testing.T) {
+	now := time.Date(2026, 3, 10, 2, 0, 5, 0, time.UTC)
+	hourly := Schedule{Name: "health", Cron: "0 * * * *", Overlap: OverlapSkip, Job: JobRequest{Type: JobTypeWindowsExec}}
+
+	t.Run("missed", func(t *testing.T) {
+		runs := &fakeScheduleRuns{lastTick: now.Add(-3*time.Hour - 5*time.Second)}
+		out, err := RunScheduleTick(context.Background(), hourly, "tick", now, runs)
+		if err != nil {
+			t.Fatalf("RunScheduleTick() error = %v", err)
+		}
+		if out.Missed != 2 || out.JobID != "job-new" || out.TraceID == "" {
+			t.Errorf("out = %+v, want 2 missed ticks and a new job", out)
+		}
+	})
+
+	t.Run("first tick", func(t *testing.T) {
+		s := hourly
+		s.Created = now.Add(-10 * time.Minute)
+		out, err := RunScheduleTick(context.Background(), s, "tick", now, &fakeScheduleRuns{})
+		if err != nil || out.Missed != 0 {
+			t.Errorf("RunScheduleTick() = %+v, %v; want nothing missed", out, err)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		runs := &fakeScheduleRuns{active: []string{"job-old"}}
+		out, err := RunScheduleTick(context.Background(), hourly, "tick", now, runs)
+		if err != nil {
+			t.Fatalf("RunScheduleTick() error = %v", err)
+		}
+		if !out.Skipped || !strings.Contains(out.Reason, "job-old") || len(runs.started) != 0 {
+			t.Errorf("out = %+v, started = %v; want skipped", out, runs.started)
+		}
+	})
+
+	t.Run("cancel-previous", func(t *testing.T) {
+		s := hourly
+		s.Overlap = OverlapCancelPrevious
+		runs := &fakeScheduleRuns{active: []string{"job-old"}}
+		out, err := RunScheduleTick(context.Background(), s, "tick", now, runs)
+		if err != nil {
+			t.Fatalf("RunScheduleTick() error = %v", err)
+		}
+		if len(out.Cancelled) != 1 || runs.cancelled[0] != "job-old" || out.JobID != "job-new" {
+			t.Errorf("out = %+v; want job-old cancelled and a new job", out)
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		s := hourly
+		s.Overlap = OverlapQueue
+		runs := &fakeScheduleRuns{active: []string{"job-old"}}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := RunScheduleTick(ctx, s, "tick", now, runs); !errors.Is(err, context.DeadlineExceeded) || len(runs.started) != 0 {
+			t.Errorf("RunScheduleTick() error = %v, started = %v; want to wait for job-old", err, runs.started)
+		}
+	})
+}
+
+func TestFileSchedules(t *testing.T) {
+	ctx := context.Background()
+	store := FileSchedules{Path: filepath.Join(t.TempDir(), "state", "schedules.json")}
+	if schedules, err := store.List(ctx); err != nil || len(schedules) != 0 {
+		t.Fatalf("List() of a missing file = %v, %v", schedules, err)
+	}
+	for _, name := range []string{"nightly", "hourly"} {
+		if _, err := store.Create(ctx, Schedule{Name: name, Cron: "0 2 * * *", Overlap: OverlapSkip}); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+	}
+	if _, err := store.Create(ctx, Schedule{Name: "nightly"}); err == nil {
+		t.Error("Create() of a duplicate name error = nil, want error")
+	}
+	if err := store.SetPaused(ctx, "nightly", true); err != nil {
+		t.Fatalf("SetPaused() error = %v", err)
+	}
+	if err := store.Delete(ctx, "hourly"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, "hourly"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Delete() of a deleted schedule error = %v, want ErrScheduleNotFound", err)
+	}
+	schedules, err := store.List(ctx)
+	if err != nil || len(schedules) != 1 || schedules[0].Name != "nightly" || !schedules[0].Paused {
+		t.Errorf("List() = %+v, %v; want nightly paused", schedules, err)
+	}
+}
+
+func TestLocalScheduler_Due(t *testing.T) {
+	l := &localScheduler{next: map[string]time.Time{}, cron: map[string]string{}}
+	start := time.Date(2026, 3, 10, 1, 59, 50, 0, time.UTC)
+	schedules := []Schedule{
+		{Name: "nightly", Cron: "0 2 * * *"},
+		{Name: "paused", Cron: "* * * * *", Paused: true},
+	}
+
+	if due := l.due(schedules, start); len(due) != 0 {
+		t.Errorf("due() at start = %v, want none", due)
+	}
+	if due := l.due(schedules, start.Add(5*time.Second)); len(due) != 0 {
+		t.Errorf("due() before 02:00 = %v, want none", due)
+	}
+	due := l.due(schedules, start.Add(12*time.Second))
+	if len(due) != 1 || due[0].Name != "nightly" {
+		t.Errorf("due() at 02:00 = %v, want nightly", due)
+	}
+	if due := l.due(schedules, start.Add(22*time.Second)); len(due) != 0 {
+		t.Errorf("due() after the tick = %v, want none", due)
+	}
+}
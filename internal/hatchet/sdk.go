@@ -16,21 +16,53 @@ import (
 
 // NewSDKClient builds a Hatchet SDK client that honors the repository configuration.
 func NewSDKClient(cfg config.Config) (*sdk.Client, error) {
+	var client *sdk.Client
+	err := withClientOpts(cfg, func(opts []v0Client.ClientOpt) (err error) {
+		client, err = sdk.NewClient(opts...)
+		return err
+	})
+	return client, err
+}
+
+// NewSubscribeClient builds a client for Hatchet's workflow event stream, configured
+// like NewSDKClient. The SDK client does not expose it.
+func NewSubscribeClient(cfg config.Config) (v0Client.SubscribeClient, error) {
+	client, err := newLegacyClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return client.Subscribe(), nil
+}
+
+// newLegacyClient builds the v0 client, configured like NewSDKClient, for the
+// APIs the SDK client does not wrap.
+func newLegacyClient(cfg config.Config) (v0Client.Client, error) {
+	var client v0Client.Client
+	err := withClientOpts(cfg, func(opts []v0Client.ClientOpt) (err error) {
+		client, err = v0Client.New(opts...)
+		return err
+	})
+	return client, err
+}
+
+// withClientOpts validates the Hatchet settings and calls build with the client
+// options while the environment the client reads at construction is in place.
+func withClientOpts(cfg config.Config, build func([]v0Client.ClientOpt) error) error {
 	if cfg.HatchetToken == "" {
-		return nil, fmt.Errorf("hatchet token is required")
+		return fmt.Errorf("hatchet token is required")
 	}
 
 	host, port, err := parseHostPort(cfg.HatchetGRPCAddress)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if cfg.HatchetHTTPURL == "" {
-		return nil, fmt.Errorf("hatchet http url is required")
+		return fmt.Errorf("hatchet http url is required")
 	}
 
 	if err := ensureHTTPURL(cfg.HatchetHTTPURL); err != nil {
-		return nil, err
+		return err
 	}
 
 	restores := make([]func(), 0, 2)
@@ -55,7 +87,7 @@ func NewSDKClient(cfg config.Config) (*sdk.Client, error) {
 		opts = append(opts, v0Client.WithNamespace(cfg.HatchetNamespace))
 	}
 
-	return sdk.NewClient(opts...)
+	return build(opts)
 }
 
 func parseHostPort(addr string) (string, int, error) {
@@ -0,0 +1,78 @@
+package hatchet
+
+import (
+	"context"
+	"time"
+
+	v0Client "github.com/hatchet-dev/hatchet/pkg/client"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// Polling bounds for PollUntil.
+const (
+	pollMinInterval = time.Second
+	pollMaxInterval = 30 * time.Second
+)
+
+// PollUntil calls poll until it reports done, fails or ctx ends. Between polls it
+// waits with exponential backoff from one to 30 seconds; a signal on wake polls
+// immediately and resets the backoff, so an event stream makes waiting prompt
+// while polling alone still converges.
+func PollUntil(ctx context.Context, wake <-chan struct{}, poll func(context.Context) (done bool, err error)) error {
+	return pollUntil(ctx, wake, pollMinInterval, pollMaxInterval, poll)
+}
+
+func pollUntil(ctx context.Context, wake <-chan struct{}, minInterval, maxInterval time.Duration, poll func(context.Context) (bool, error)) error {
+	interval := minInterval
+	for {
+		done, err := poll(ctx)
+		if err != nil || done {
+			return err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-wake:
+			timer.Stop()
+			interval = minInterval
+		case <-timer.C:
+			interval = min(interval*2, maxInterval)
+		}
+	}
+}
+
+// RunEvents subscribes to the workflow run's events and signals the returned
+// channel whenever one arrives, for use as PollUntil's wake. When the stream is
+// unavailable a warning is logged and the channel stays quiet, leaving callers
+// to polling. The subscription ends with ctx.
+func RunEvents(ctx context.Context, cfg config.Config, runID string) <-chan struct{} {
+	wake := make(chan struct{}, 1)
+	sub, err := NewSubscribeClient(cfg)
+	if err != nil {
+		logx.Warn("jobs", "wait", "event stream unavailable; polling",
+			logx.Field{Key: "job_id", Value: runID},
+			logx.Field{Key: "err", Value: err.Error()},
+		)
+		return wake
+	}
+	go func() {
+		err := sub.On(ctx, runID, func(v0Client.WorkflowEvent) error {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+		if err != nil && ctx.Err() == nil {
+			logx.Warn("jobs", "wait", "event stream unavailable; polling",
+				logx.Field{Key: "job_id", Value: runID},
+				logx.Field{Key: "err", Value: err.Error()},
+			)
+		}
+	}()
+	return wake
+}
@@ -0,0 +1,62 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollUntil_BacksOff(t *testing.T) {
+	var polls []time.Time
+	err := pollUntil(context.Background(), nil, 5*time.Millisecond, 20*time.Millisecond, func(context.Context) (bool, error) {
+		polls = append(polls, time.Now())
+		return len(polls) == 5, nil
+	})
+	if err != nil {
+		t.Fatalf("pollUntil() error = %v", err)
+	}
+	if len(polls) != 5 {
+		t.Fatalf("polls = %d, want 5", len(polls))
+	}
+	// Waits double from 5ms and are capped at 20ms: 5, 10, 20, 20.
+	if gap := polls[3].Sub(polls[2]); gap < 20*time.Millisecond {
+		t.Errorf("third wait = %v, want at least 20ms", gap)
+	}
+}
+
+func TestPollUntil_WakePollsImmediately(t *testing.T) {
+	wake := make(chan struct{}, 1)
+	polls := 0
+	start := time.Now()
+	err := pollUntil(context.Background(), wake, time.Hour, time.Hour, func(context.Context) (bool, error) {
+		polls++
+		if polls == 1 {
+			wake <- struct{}{}
+		}
+		return polls == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("pollUntil() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("woken poll took %v", elapsed)
+	}
+}
+
+func TestPollUntil_StopsOnErrorAndContext(t *testing.T) {
+	boom := errors.New("boom")
+	if err := pollUntil(context.Background(), nil, time.Millisecond, time.Millisecond, func(context.Context) (bool, error) {
+		return false, boom
+	}); !errors.Is(err, boom) {
+		t.Errorf("pollUntil() error = %v, want boom", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pollUntil(ctx, nil, time.Millisecond, time.Millisecond, func(context.Context) (bool, error) {
+		return false, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("pollUntil() error = %v, want deadline exceeded", err)
+	}
+}
@@ -3,24 +3,45 @@ package hatchet
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/artifacts"
 	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/desktop"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/metrics"
+	"github.com/alejg/win-automation/internal/playwright"
 	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/verify"
+	"github.com/google/uuid"
+)
+
+// listDisplays and runPlaywrightScript are replaced in tests that run without a
+// Windows host.
+var (
+	listDisplays        = desktop.ListDisplays
+	runPlaywrightScript = playwright.RunScript
 )
 
 type TaskHandler func(ctx context.Context, payload json.RawMessage) (any, error)
 
 type Worker struct {
-	cfg      config.Config
-	handlers map[JobType]TaskHandler
+	cfg             config.Config
+	handlers        map[JobType]TaskHandler
+	playwrightSlots *playwright.Limiter
 }
 
 func NewWorker(cfg config.Config) *Worker {
 	w := &Worker{
-		cfg:      cfg,
-		handlers: make(map[JobType]TaskHandler),
+		cfg:             cfg,
+		handlers:        make(map[JobType]TaskHandler),
+		playwrightSlots: playwright.NewLimiter(cfg.PlaywrightMaxSessions, metrics.DefaultMetrics),
 	}
 	w.registerDefaultHandlers()
 	return w
@@ -29,6 +50,8 @@ func NewWorker(cfg config.Config) *Worker {
 func (w *Worker) registerDefaultHandlers() {
 	w.handlers[JobTypeWindowsExec] = w.handleWindowsExec
 	w.handlers[JobTypeAlohaRun] = w.handleAlohaRun
+	w.handlers[JobTypePlaywrightRun] = w.handlePlaywrightRun
+	w.handlers[JobTypePlaywrightCapture] = w.handlePlaywrightCapture
 }
 
 func (w *Worker) handleWindowsExec(ctx context.Context, payload json.RawMessage) (any, error) {
@@ -41,14 +64,31 @@ func (w *Worker) handleWindowsExec(ctx context.Context, payload json.RawMessage)
 		return nil, fmt.Errorf("command is required")
 	}
 
-	result, err := sshx.Run(ctx, w.cfg, input.Command)
+	if input.Interactive {
+		lease, err := w.acquireDesktop(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer w.releaseDesktop(lease)
+		var cancel context.CancelFunc
+		ctx, cancel = lease.Context(ctx)
+		defer cancel()
+	}
+
+	runCtx := ctx
+	if input.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, input.Timeout)
+		defer cancel()
+	}
+	result, err := sshx.Run(runCtx, w.cfg, input.Command)
 	output := WindowsExecOutput{
 		Stdout:   result.Stdout,
 		Stderr:   result.Stderr,
 		ExitCode: result.ExitCode,
 	}
 	if err != nil {
-		return output, err
+		return output, leaseLost(ctx, err)
 	}
 	return output, nil
 }
@@ -63,10 +103,204 @@ func (w *Worker) handleAlohaRun(ctx context.Context, payload json.RawMessage) (a
 		return nil, fmt.Errorf("task is required")
 	}
 
-	client := aloha.New(w.cfg)
+	lease, err := w.acquireDesktop(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.releaseDesktop(lease)
+	ctx, cancel := lease.Context(ctx)
+	defer cancel()
+
+	rec := w.recorder(ctx, input.TraceID)
+	output, err := RunAloha(ctx, w.cfg, input, rec)
+	err = leaseLost(ctx, err)
+	if closeErr := rec.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("write artifacts: %w", closeErr)
+	}
+	return output, err
+}
+
+func (w *Worker) handlePlaywrightRun(ctx context.Context, payload json.RawMessage) (any, error) {
+	var input PlaywrightRunInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		return nil, fmt.Errorf("invalid playwright.run payload: %w", err)
+	}
+
+	if strings.TrimSpace(input.Script) == "" {
+		return nil, fmt.Errorf("script is required")
+	}
+
+	slot, err := w.acquirePlaywrightSlot(ctx, input.Profile)
+	if err != nil {
+		return nil, err
+	}
+	defer slot.Release()
+
+	rec := w.playwrightRecorder(ctx, input.TraceID)
+	rec.SetMetadata("slot", slot.ID)
+	output, err := RunPlaywright(ctx, w.cfg, input, rec)
+	if closeErr := rec.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("write artifacts: %w", closeErr)
+	}
+	return output, err
+}
+
+func (w *Worker) handlePlaywrightCapture(ctx context.Context, payload json.RawMessage) (any, error) {
+	var input PlaywrightCaptureInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		return nil, fmt.Errorf("invalid playwright.capture payload: %w", err)
+	}
+
+	slot, err := w.acquirePlaywrightSlot(ctx, input.Profile)
+	if err != nil {
+		return nil, err
+	}
+	defer slot.Release()
+
+	rec := w.playwrightRecorder(ctx, input.TraceID)
+	rec.SetMetadata("slot", slot.ID)
+	output, err := RunPlaywrightCapture(ctx, w.cfg, input, rec)
+	if closeErr := rec.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("write artifacts: %w", closeErr)
+	}
+	return output, err
+}
+
+// acquirePlaywrightSlot waits for a slot in the worker's limiter, which caps the
+// Playwright jobs running at once and serializes jobs sharing a profile.
+func (w *Worker) acquirePlaywrightSlot(ctx context.Context, profile string) (*playwright.Slot, error) {
+	slot, err := w.playwrightSlots.Acquire(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	logx.Info("worker", "playwright_slot", "acquired",
+		logx.Field{Key: "job_id", Value: jobIDFromContext(ctx)},
+		logx.Field{Key: "slot", Value: slot.ID},
+		logx.Field{Key: "profile", Value: profile},
+		logx.Field{Key: "wait_ms", Value: slot.Wait.Milliseconds()},
+	)
+	return slot, nil
+}
+
+// playwrightRecorder returns the job's recorder. Screenshots and traces are the
+// result of playwright jobs, so they are kept even when the job has no ID.
+func (w *Worker) playwrightRecorder(ctx context.Context, traceID string) *artifacts.Recorder {
+	if rec := w.recorder(ctx, traceID); rec != nil {
+		return rec
+	}
+	return artifacts.NewRecorder(w.cfg.ArtifactOutDir, uuid.NewString(), traceID)
+}
+
+// RunPlaywright runs a flow script on Windows and records the screenshots, videos
+// and trace.zip it produced as playwright artifacts under rec. Files are collected
+// even when the flow fails; what is kept follows the run's policies.
+func RunPlaywright(ctx context.Context, cfg config.Config, input PlaywrightRunInput, rec *artifacts.Recorder) (PlaywrightRunOutput, error) {
+	rec.SetMetadata("script", input.ScriptName)
+	if input.Profile != "" {
+		rec.SetMetadata("profile", input.Profile)
+	}
+	return runPlaywrightSpec(ctx, cfg, playwright.RunSpec{
+		Script:   []byte(input.Script),
+		Browser:  input.Browser,
+		Policies: input.Policies(),
+		Profile:  input.Profile,
+	}, rec)
+}
+
+// RunPlaywrightCapture loads input.URL in the remote browser and records the
+// requested screenshot, PDF and HAR as playwright artifacts under rec.
+func RunPlaywrightCapture(ctx context.Context, cfg config.Config, input PlaywrightCaptureInput, rec *artifacts.Recorder) (PlaywrightRunOutput, error) {
+	capture := playwright.CaptureSpec{
+		URL:        input.URL,
+		WaitFor:    input.WaitFor,
+		Screenshot: input.Screenshot,
+		PDF:        input.PDF,
+		HAR:        input.HAR,
+		Viewport:   input.Viewport,
+	}
+	spec, err := capture.RunSpec(input.Browser)
+	if err != nil {
+		return PlaywrightRunOutput{}, err
+	}
+	spec.Trace = playwright.Policy(input.TracePolicy)
+	spec.Video = playwright.Policy(input.VideoPolicy)
+	spec.Profile = input.Profile
+	rec.SetMetadata("url", input.URL)
+	if input.Profile != "" {
+		rec.SetMetadata("profile", input.Profile)
+	}
+	return runPlaywrightSpec(ctx, cfg, spec, rec)
+}
+
+func runPlaywrightSpec(ctx context.Context, cfg config.Config, spec playwright.RunSpec, rec *artifacts.Recorder) (PlaywrightRunOutput, error) {
+	output := PlaywrightRunOutput{ArtifactDir: rec.Root()}
+	start := time.Now()
+	result, err := runPlaywrightScript(ctx, cfg, spec, filepath.Join(rec.Root(), "playwright"))
+	output.Duration = time.Since(start)
+	output.Browser = result.Browser
+	for _, name := range result.Files {
+		relPath := "playwright/" + name
+		if addErr := rec.Add(relPath, "playwright"); addErr != nil {
+			return output, fmt.Errorf("record %s: %w", relPath, addErr)
+		}
+		output.Files = append(output.Files, relPath)
+		if name == playwright.TraceFile {
+			rec.SetMetadata("trace", relPath)
+		}
+	}
+	rec.SetMetadata("browser", result.Browser)
+	rec.SetMetadata("duration_ms", output.Duration.Milliseconds())
+	return output, err
+}
+
+// Budget errors returned by RunAloha.
+var (
+	ErrTaskBudgetExceeded  = errors.New("aloha task budget exceeded")
+	ErrStepBudgetExhausted = errors.New("aloha daily step budget exhausted")
+)
+
+// RunAloha validates a selected screen other than the default (index 0) against
+// the attached displays, submits an Aloha task and, when input.Verify is set,
+// checks the postcondition afterwards. A task whose postcondition fails is
+// re-submitted up to input.TaskRetries times.
+//
+// The whole run is bounded by the task budget (input.Budget or cfg.AlohaTaskBudget)
+// and max_steps is capped by what remains of cfg.AlohaDailyStepBudget for the
+// namespace. Steps, duration and token/cost fields are returned, appended to the
+// usage ledger and written to rec together with responses, verification output and
+// the display layout.
+func RunAloha(ctx context.Context, cfg config.Config, input AlohaRunInput, rec *artifacts.Recorder) (AlohaRunOutput, error) {
+	var output AlohaRunOutput
+	screen := 0
+	selector := strings.TrimSpace(input.Screen)
+	if selector == "" {
+		selector = strconv.Itoa(input.SelectedScreen)
+	}
+	// The default screen needs no validation: the layout is only queried for the
+	// manifest, so a failed query does not fail the run and without a recorder
+	// it is skipped.
+	if selector != "0" || rec != nil {
+		displays, err := listDisplays(ctx, cfg)
+		switch {
+		case err != nil && selector != "0":
+			return output, err
+		case err != nil:
+			logx.Warn("aloha", "displays", "display query failed", logx.Field{Key: "err", Value: err.Error()})
+		default:
+			rec.SetMetadata("displays", displays)
+			if selector != "0" {
+				if screen, err = desktop.ResolveScreen(displays, selector); err != nil {
+					return output, err
+				}
+			}
+		}
+	}
+	rec.SetMetadata("selected_screen", screen)
+	output.Screen = screen
+
 	req := aloha.RunTaskRequest{
 		Task:           input.Task,
-		SelectedScreen: input.SelectedScreen,
+		SelectedScreen: screen,
 		TraceID:        input.TraceID,
 		MaxSteps:       input.MaxSteps,
 	}
@@ -77,13 +311,208 @@ func (w *Worker) handleAlohaRun(ctx context.Context, payload json.RawMessage) (a
 		req.TraceID = "win-automation"
 	}
 
-	resp, err := client.RunTask(ctx, req)
-	if err != nil {
-		return AlohaRunOutput{Raw: resp.Raw}, err
+	ledger := aloha.UsageLedgerPath(cfg)
+	remaining := -1
+	if cfg.AlohaDailyStepBudget > 0 {
+		used, err := aloha.StepsUsedToday(ledger, cfg.HatchetNamespace, time.Now())
+		if err != nil {
+			return output, fmt.Errorf("read usage ledger: %w", err)
+		}
+		remaining = cfg.AlohaDailyStepBudget - used
+		if remaining <= 0 {
+			return output, fmt.Errorf("%w: %d/%d steps used today in namespace %s",
+				ErrStepBudgetExhausted, used, cfg.AlohaDailyStepBudget, cfg.HatchetNamespace)
+		}
+	}
+
+	runCtx := ctx
+	budget := input.Budget
+	if budget <= 0 {
+		budget = cfg.AlohaTaskBudget
+	}
+	if budget > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := runAlohaAttempts(runCtx, cfg, input, req, remaining, rec, &output)
+	output.Duration = time.Since(start)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s: %v", ErrTaskBudgetExceeded, budget, err)
+	}
+
+	rec.SetMetadata("usage", output.Usage)
+	rec.SetMetadata("duration_ms", output.Duration.Milliseconds())
+	jobID := jobIDFromContext(ctx)
+	if jobID == "" {
+		jobID = rec.JobID()
+	}
+	usageRec := aloha.UsageRecord{
+		Time:      start.UTC(),
+		Namespace: cfg.HatchetNamespace,
+		JobID:     jobID,
+		TraceID:   req.TraceID,
+		Template:  input.Template,
+		Outcome:   usageOutcome(err),
+		Duration:  output.Duration,
+		Usage:     output.Usage,
+	}
+	if ledgerErr := aloha.AppendUsage(ledger, usageRec); ledgerErr != nil {
+		logx.Error("aloha", "usage", "ledger write failed", ledgerErr, logx.Field{Key: "path", Value: ledger})
+	}
+	return output, err
+}
+
+// runAlohaAttempts submits the task and runs the postcondition, re-submitting on
+// verification failure. remaining is the daily step allowance (-1 for unlimited);
+// each attempt's max_steps is capped by it.
+func runAlohaAttempts(ctx context.Context, cfg config.Config, input AlohaRunInput, req aloha.RunTaskRequest, remaining int, rec *artifacts.Recorder, output *AlohaRunOutput) error {
+	client := aloha.New(cfg)
+	maxSteps := req.MaxSteps
+	for attempt := 1; attempt <= input.TaskRetries+1; attempt++ {
+		if remaining >= 0 {
+			if remaining == 0 {
+				return fmt.Errorf("%w before attempt %d", ErrStepBudgetExhausted, attempt)
+			}
+			req.MaxSteps = min(maxSteps, remaining)
+		}
+
+		output.Attempts = attempt
+		resp, err := client.RunTask(ctx, req)
+		output.Raw = resp.Raw
+		usage, ok := aloha.ParseUsage(resp.Raw)
+		if !ok && resp.Raw != "" {
+			// Charge the allowance when Aloha does not report steps so budgets
+			// stay enforceable.
+			usage.Steps, usage.StepsEstimated = req.MaxSteps, true
+		}
+		output.Usage.Add(usage)
+		if remaining >= 0 {
+			remaining = max(remaining-usage.Steps, 0)
+		}
+		if recErr := rec.Write(fmt.Sprintf("aloha/%d/response.json", attempt), "aloha", []byte(resp.Raw)); recErr != nil {
+			return fmt.Errorf("record aloha response: %w", recErr)
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(input.Verify) == "" {
+			return nil
+		}
+
+		result, err := verify.Check(ctx, cfg, verify.Spec{
+			Script:  input.Verify,
+			Retries: input.VerifyRetries,
+			Delay:   input.VerifyDelay,
+		})
+		output.Verification = &result
+		if recErr := recordVerification(rec, attempt, result); recErr != nil {
+			return fmt.Errorf("record verification: %w", recErr)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, verify.ErrFailed) {
+			return err
+		}
+	}
+	return fmt.Errorf("aloha task not verified after %d attempts: %w", output.Attempts, verify.ErrFailed)
+}
+
+func usageOutcome(err error) string {
+	switch {
+	case err == nil:
+		return aloha.OutcomeSucceeded
+	case errors.Is(err, ErrTaskBudgetExceeded), errors.Is(err, ErrStepBudgetExhausted):
+		return aloha.OutcomeBudgetExceeded
+	case errors.Is(err, verify.ErrFailed):
+		return aloha.OutcomeVerifyFailed
+	default:
+		return aloha.OutcomeFailed
+	}
+}
+
+func recordVerification(rec *artifacts.Recorder, taskAttempt int, result verify.Result) error {
+	for i, attempt := range result.Attempts {
+		dir := fmt.Sprintf("verify/%d-%d/", taskAttempt, i+1)
+		files := []struct {
+			name string
+			data string
+		}{
+			{name: "stdout.txt", data: attempt.Stdout},
+			{name: "stderr.txt", data: attempt.Stderr},
+			{name: "exit_code.txt", data: strconv.Itoa(attempt.ExitCode)},
+		}
+		for _, f := range files {
+			if err := rec.Write(dir+f.name, "verify", []byte(f.data)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recorder returns an artifact recorder for the job in ctx, or nil when the job
+// has no ID to key artifacts by.
+func (w *Worker) recorder(ctx context.Context, traceID string) *artifacts.Recorder {
+	jobID := jobIDFromContext(ctx)
+	if jobID == "" {
+		return nil
+	}
+	return artifacts.NewRecorder(w.cfg.ArtifactOutDir, jobID, traceID)
+}
+
+// acquireDesktop takes the exclusive desktop lease for the job in ctx, logging
+// queue position changes while it waits. A job without an id holds it under a
+// generated one, so such jobs are never taken for the same holder.
+func (w *Worker) acquireDesktop(ctx context.Context) (*desktop.Lease, error) {
+	jobID := jobIDFromContext(ctx)
+	if jobID == "" {
+		jobID = "local-" + uuid.NewString()[:8]
+	}
+	holder := "job:" + jobID
+	return desktop.Acquire(ctx, w.cfg, holder, func(status desktop.Status) {
+		logx.Info("worker", "desktop_lock", "waiting",
+			logx.Field{Key: "job_id", Value: jobIDFromContext(ctx)},
+			logx.Field{Key: "position", Value: status.Position},
+			logx.Field{Key: "holder", Value: status.Holder},
+		)
+	})
+}
+
+func (w *Worker) releaseDesktop(lease *desktop.Lease) {
+	if err := lease.Release(); err != nil {
+		logx.Error("worker", "desktop_lock", "release failed", err, logx.Field{Key: "holder", Value: lease.Holder()})
+	}
+}
+
+// leaseLost returns the lease loss that cancelled ctx in place of err, so a job
+// that lost the desktop fails with that rather than a bare context error.
+func leaseLost(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, desktop.ErrLeaseLost) {
+		return cause
 	}
-	return AlohaRunOutput{Raw: resp.Raw}, nil
+	return err
 }
 
+type jobIDKey struct{}
+
+func withJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey{}, jobID)
+}
+
+func jobIDFromContext(ctx context.Context) string {
+	jobID, _ := ctx.Value(jobIDKey{}).(string)
+	return jobID
+}
+
+// HandleJob runs req through its type's handler. Each attempt is bounded by
+// req.Timeout, capped at the worker's job timeout. Failed attempts are retried up
+// to req.RetryMax times (capped at the worker's retry_max), RetryBackoff apart and
+// doubling, but only when the failure is transient, or a command exit code of an
+// idempotent job.
 func (w *Worker) HandleJob(ctx context.Context, req *JobRequest) (*JobResult, error) {
 	handler, ok := w.handlers[req.Type]
 	if !ok {
@@ -101,19 +530,55 @@ func (w *Worker) HandleJob(ctx context.Context, req *JobRequest) (*JobResult, er
 		}, err
 	}
 
-	output, err := handler(ctx, payload)
-	if err != nil {
-		return &JobResult{
-			Status: JobStatusFailed,
-			Output: output,
-			Error:  err.Error(),
-		}, err
+	ctx = withJobID(ctx, req.ID)
+	timeout := req.Timeout
+	if w.cfg.HatchetJobTimeout > 0 && (timeout <= 0 || timeout > w.cfg.HatchetJobTimeout) {
+		timeout = w.cfg.HatchetJobTimeout
 	}
+	retryMax := min(req.RetryMax, w.cfg.HatchetRetryMax)
+	result := &JobResult{ID: req.ID, StartedAt: time.Now().UTC()}
+	for {
+		result.Attempts++
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		result.Output, err = handler(attemptCtx, payload)
+		timedOut := err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if timedOut {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		if err == nil || timedOut || ctx.Err() != nil || result.Attempts > retryMax || !shouldRetry(err, req.Idempotent) {
+			break
+		}
+		delay := retryDelay(req.RetryBackoff, result.Attempts)
+		logx.Warn("worker", "job", "retrying",
+			logx.Field{Key: "job_id", Value: req.ID},
+			logx.Field{Key: "type", Value: string(req.Type)},
+			logx.Field{Key: "attempt", Value: result.Attempts},
+			logx.Field{Key: "delay_ms", Value: delay.Milliseconds()},
+			logx.Field{Key: "err", Value: err.Error()},
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	result.EndedAt = time.Now().UTC()
 
-	return &JobResult{
-		Status: JobStatusCompleted,
-		Output: output,
-	}, nil
+	if err != nil {
+		result.Status = JobStatusFailed
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Status = JobStatusCompleted
+	return result, nil
 }
 
 func (w *Worker) Config() config.Config {
@@ -0,0 +1,326 @@
+package hatchet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/aloha"
+	"github.com/alejg/win-automation/internal/artifacts"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/desktop"
+	"github.com/alejg/win-automation/internal/playwright"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+func stubDisplays(t *testing.T, displays []desktop.Display) {
+	t.Helper()
+	orig := listDisplays
+	listDisplays = func(context.Context, config.Config) ([]desktop.Display, error) { return displays, nil }
+	t.Cleanup(func() { listDisplays = orig })
+}
+
+func TestRunAloha_RecordsResponse(t *testing.T) {
+	stubDisplays(t, []desktop.Display{
+		{Index: 0, Name: `\\.\DISPLAY1`, Width: 1920, Height: 1080, Scale: 1},
+		{Index: 1, Name: `\\.\DISPLAY2`, Width: 2560, Height: 1440, Scale: 1.5, Primary: true},
+	})
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := config.Config{
+		AlohaServerURL: srv.URL,
+		AlohaClientURL: srv.URL,
+		ArtifactOutDir: dir,
+		Timeout:        5 * time.Second,
+	}
+	rec := artifacts.NewRecorder(dir, "job-1", "trace-1")
+
+	output, err := RunAloha(context.Background(), cfg, AlohaRunInput{Task: "open notepad", Screen: "primary"}, rec)
+	if err != nil {
+		t.Fatalf("RunAloha() error = %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if output.Raw != `{"status":"ok"}` {
+		t.Errorf("Raw = %q, want %q", output.Raw, `{"status":"ok"}`)
+	}
+	if output.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", output.Attempts)
+	}
+	if output.Verification != nil {
+		t.Errorf("Verification = %+v, want nil without --verify", output.Verification)
+	}
+	if got["selected_screen"] != float64(1) || output.Screen != 1 {
+		t.Errorf("selected_screen = %v, output.Screen = %d; want primary display 1", got["selected_screen"], output.Screen)
+	}
+	if got["max_steps"] != float64(10) || got["trace_id"] != "win-automation" {
+		t.Errorf("request defaults = %v", got)
+	}
+
+	manifest, err := artifacts.ReadManifest(rec.Root())
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(manifest.Artifacts) != 1 || manifest.Artifacts[0].Path != "aloha/1/response.json" {
+		t.Errorf("Artifacts = %+v, want aloha/1/response.json", manifest.Artifacts)
+	}
+	if _, ok := manifest.Metadata["displays"]; !ok {
+		t.Errorf("Metadata = %v, want displays", manifest.Metadata)
+	}
+}
+
+func TestRunAloha_DefaultScreenRecordsDisplays(t *testing.T) {
+	stubDisplays(t, []desktop.Display{{Index: 0, Name: `\\.\DISPLAY1`, Primary: true}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cfg := config.Config{AlohaServerURL: srv.URL, AlohaClientURL: srv.URL, ArtifactOutDir: dir, Timeout: 5 * time.Second}
+	rec := artifacts.NewRecorder(dir, "job-1", "trace-1")
+	if _, err := RunAloha(context.Background(), cfg, AlohaRunInput{Task: "open notepad"}, rec); err != nil {
+		t.Fatalf("RunAloha() error = %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	manifest, err := artifacts.ReadManifest(rec.Root())
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if _, ok := manifest.Metadata["displays"]; !ok || manifest.Metadata["selected_screen"] != float64(0) {
+		t.Errorf("Metadata = %v, want displays and selected_screen 0", manifest.Metadata)
+	}
+}
+
+func TestRunAloha_DefaultScreenIgnoresDisplayQueryFailure(t *testing.T) {
+	orig := listDisplays
+	listDisplays = func(context.Context, config.Config) ([]desktop.Display, error) {
+		return nil, errors.New("display query failed")
+	}
+	t.Cleanup(func() { listDisplays = orig })
+	var req aloha.RunTaskRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{AlohaServerURL: srv.URL, AlohaClientURL: srv.URL, ArtifactOutDir: t.TempDir(), Timeout: 5 * time.Second}
+	rec := artifacts.NewRecorder(cfg.ArtifactOutDir, "job-1", "trace-1")
+	for _, input := range []AlohaRunInput{{Task: "open notepad"}, {Task: "open notepad", Screen: "0"}} {
+		output, err := RunAloha(context.Background(), cfg, input, rec)
+		if err != nil {
+			t.Fatalf("RunAloha(%+v) error = %v", input, err)
+		}
+		if output.Screen != 0 || req.SelectedScreen != 0 {
+			t.Errorf("screen = %d, request screen = %d; want 0", output.Screen, req.SelectedScreen)
+		}
+	}
+}
+
+func TestRunAloha_RejectsUnknownScreen(t *testing.T) {
+	stubDisplays(t, []desktop.Display{{Index: 0, Name: `\\.\DISPLAY1`, Primary: true}})
+
+	_, err := RunAloha(context.Background(), config.Config{}, AlohaRunInput{Task: "open notepad", SelectedScreen: 1}, nil)
+	if err == nil {
+		t.Fatal("RunAloha() error = nil, want out-of-range screen error")
+	}
+}
+
+func TestRunAloha_StepBudget(t *testing.T) {
+	stubDisplays(t, []desktop.Display{{Index: 0, Name: `\\.\DISPLAY1`, Primary: true}})
+	var maxSteps []float64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		maxSteps = append(maxSteps, req["max_steps"].(float64))
+		w.Write([]byte(`{"status":"ok","steps":4,"usage":{"input_tokens":100,"output_tokens":20,"cost_usd":0.01}}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{
+		AlohaServerURL:       srv.URL,
+		AlohaClientURL:       srv.URL,
+		ArtifactOutDir:       t.TempDir(),
+		Timeout:              5 * time.Second,
+		AlohaTaskBudget:      time.Minute,
+		AlohaDailyStepBudget: 6,
+		HatchetNamespace:     "default",
+	}
+	input := AlohaRunInput{Task: "open notepad", Template: "notepad"}
+
+	output, err := RunAloha(context.Background(), cfg, input, nil)
+	if err != nil {
+		t.Fatalf("first RunAloha() error = %v", err)
+	}
+	want := aloha.Usage{Steps: 4, InputTokens: 100, OutputTokens: 20, TotalTokens: 120, CostUSD: 0.01}
+	if output.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", output.Usage, want)
+	}
+
+	// Two steps remain today: the second run is capped, the third refused.
+	if _, err := RunAloha(context.Background(), cfg, input, nil); err != nil {
+		t.Fatalf("second RunAloha() error = %v", err)
+	}
+	if len(maxSteps) != 2 || maxSteps[0] != 6 || maxSteps[1] != 2 {
+		t.Errorf("max_steps sent = %v, want [6 2]", maxSteps)
+	}
+	if _, err := RunAloha(context.Background(), cfg, input, nil); !errors.Is(err, ErrStepBudgetExhausted) {
+		t.Errorf("third RunAloha() error = %v, want ErrStepBudgetExhausted", err)
+	}
+
+	records, err := aloha.LoadUsage(aloha.UsageLedgerPath(cfg), time.Time{})
+	if err != nil {
+		t.Fatalf("LoadUsage() error = %v", err)
+	}
+	if len(records) != 2 || records[0].Template != "notepad" || records[0].Outcome != aloha.OutcomeSucceeded {
+		t.Errorf("ledger = %+v, want two succeeded notepad runs", records)
+	}
+}
+
+func TestRunPlaywright_CollectsFilesOnFailure(t *testing.T) {
+	orig := runPlaywrightScript
+	runPlaywrightScript = func(_ context.Context, _ config.Config, spec playwright.RunSpec, localDir string) (playwright.RunResult, error) {
+		if spec.Trace != playwright.PolicyOn || spec.Browser != "msedge" {
+			t.Errorf("spec = %+v, want traced msedge run", spec)
+		}
+		os.MkdirAll(localDir, 0o755)
+		for _, name := range []string{"home.png", "trace.zip"} {
+			os.WriteFile(filepath.Join(localDir, name), []byte(name), 0o644)
+		}
+		return playwright.RunResult{Browser: "msedge", Files: []string{"home.png", "trace.zip"}, Error: "boom"}, playwright.ErrScriptFailed
+	}
+	t.Cleanup(func() { runPlaywrightScript = orig })
+
+	dir := t.TempDir()
+	rec := artifacts.NewRecorder(dir, "job-1", "trace-1")
+	output, err := RunPlaywright(context.Background(), config.Config{}, PlaywrightRunInput{Script: "module.exports = async () => {}", Browser: "msedge", Trace: true}, rec)
+	if !errors.Is(err, playwright.ErrScriptFailed) {
+		t.Fatalf("RunPlaywright() error = %v, want ErrScriptFailed", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(output.Files) != 2 || output.Files[1] != "playwright/trace.zip" {
+		t.Errorf("Files = %v", output.Files)
+	}
+	manifest, err := artifacts.ReadManifest(rec.Root())
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(manifest.Artifacts) != 2 || manifest.Artifacts[0].Type != "playwright" {
+		t.Errorf("Artifacts = %+v, want two playwright artifacts", manifest.Artifacts)
+	}
+	if manifest.Metadata["trace"] != "playwright/trace.zip" {
+		t.Errorf("trace metadata = %v", manifest.Metadata["trace"])
+	}
+}
+
+func TestRunPlaywrightCapture(t *testing.T) {
+	orig := runPlaywrightScript
+	var got playwright.RunSpec
+	runPlaywrightScript = func(_ context.Context, _ config.Config, spec playwright.RunSpec, localDir string) (playwright.RunResult, error) {
+		got = spec
+		os.MkdirAll(localDir, 0o755)
+		os.WriteFile(filepath.Join(localDir, "page.har"), []byte("{}"), 0o644)
+		return playwright.RunResult{Browser: "chromium", Files: []string{"page.har"}}, nil
+	}
+	t.Cleanup(func() { runPlaywrightScript = orig })
+
+	rec := artifacts.NewRecorder(t.TempDir(), "job-1", "trace-1")
+	if _, err := RunPlaywrightCapture(context.Background(), config.Config{}, PlaywrightCaptureInput{URL: "https://example.com"}, rec); err == nil {
+		t.Error("RunPlaywrightCapture() without outputs error = nil, want error")
+	}
+
+	input := PlaywrightCaptureInput{URL: "https://example.com", HAR: "page.har", Viewport: "1280x720"}
+	output, err := RunPlaywrightCapture(context.Background(), config.Config{}, input, rec)
+	if err != nil {
+		t.Fatalf("RunPlaywrightCapture() error = %v", err)
+	}
+	if got.HAR != "page.har" || got.Viewport != "1280x720" {
+		t.Errorf("spec = %+v, want HAR and viewport passed through", got)
+	}
+	if len(output.Files) != 1 || output.Files[0] != "playwright/page.har" {
+		t.Errorf("Files = %v", output.Files)
+	}
+}
+
+func TestHandleJob_RetriesTransientFailures(t *testing.T) {
+	cfg := config.Config{HatchetJobTimeout: time.Minute, HatchetRetryMax: 3}
+	attempts := 0
+	w := &Worker{cfg: cfg, handlers: map[JobType]TaskHandler{
+		JobTypeWindowsExec: func(ctx context.Context, payload json.RawMessage) (any, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, &sshx.ExitError{Code: sshx.ExitConnectionFailed}
+			}
+			return WindowsExecOutput{Stdout: "ok"}, nil
+		},
+	}}
+
+	result, err := w.HandleJob(context.Background(), &JobRequest{ID: "job-1", Type: JobTypeWindowsExec, RetryMax: 3, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("HandleJob() error = %v", err)
+	}
+	if result.Status != JobStatusCompleted || result.Attempts != 3 {
+		t.Errorf("result = %+v, want completed after 3 attempts", result)
+	}
+
+	attempts = 0
+	w.handlers[JobTypeWindowsExec] = func(ctx context.Context, payload json.RawMessage) (any, error) {
+		attempts++
+		return nil, &sshx.ExitError{Code: 1}
+	}
+	result, err = w.HandleJob(context.Background(), &JobRequest{ID: "job-2", Type: JobTypeWindowsExec, RetryMax: 3, RetryBackoff: time.Millisecond})
+	if err == nil || result.Attempts != 1 {
+		t.Errorf("exit code: attempts = %d, err = %v; want 1 attempt and an error", result.Attempts, err)
+	}
+
+	attempts = 0
+	result, err = w.HandleJob(context.Background(), &JobRequest{ID: "job-3", Type: JobTypeWindowsExec, RetryMax: 5, RetryBackoff: time.Millisecond, Idempotent: true})
+	if err == nil || result.Attempts != 4 {
+		t.Errorf("idempotent: attempts = %d, err = %v; want 4 attempts capped by retry_max", result.Attempts, err)
+	}
+}
+
+func TestHandleJob_EnforcesTimeout(t *testing.T) {
+	cfg := config.Config{HatchetJobTimeout: time.Minute, HatchetRetryMax: 3}
+	attempts := 0
+	w := &Worker{cfg: cfg, handlers: map[JobType]TaskHandler{
+		JobTypeAlohaRun: func(ctx context.Context, payload json.RawMessage) (any, error) {
+			attempts++
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}}
+
+	result, err := w.HandleJob(context.Background(), &JobRequest{ID: "job-1", Type: JobTypeAlohaRun, Timeout: 20 * time.Millisecond, RetryMax: 3})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Fatalf("HandleJob() error = %v, want timed out after 20ms", err)
+	}
+	if result.Status != JobStatusFailed || attempts != 1 {
+		t.Errorf("status = %s, attempts = %d; want failed without retries", result.Status, attempts)
+	}
+}
@@ -0,0 +1,497 @@
+package hatchet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/alejg/win-automation/internal/expr"
+	"gopkg.in/yaml.v3"
+)
+
+// JobTypeWorkflowRun executes a WorkflowDef, running each step as a child job.
+const JobTypeWorkflowRun JobType = "workflow.run"
+
+// maxStepRetries bounds WorkflowStep.Retries.
+const maxStepRetries = 10
+
+var stepIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// WorkflowDef is a multi-step workflow: a DAG of jobs where each step may wait
+// on others and use their outputs.
+type WorkflowDef struct {
+	Name  string         `json:"name"`
+	Steps []WorkflowStep `json:"steps"`
+}
+
+// WorkflowStep is one job of a workflow. String values in Payload are Go
+// templates over the outputs of earlier steps, e.g.
+// {{ trim .steps.install.stdout }}; only steps in Needs (directly or
+// transitively) can be referenced.
+type WorkflowStep struct {
+	ID      string         `json:"id"`
+	Type    JobType        `json:"type"`
+	Needs   []string       `json:"needs,omitempty"`
+	Payload map[string]any `json:"payload"`
+	// Timeout bounds each attempt; 0 leaves the job's own timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Retries re-runs a failed step this many times.
+	Retries int `json:"retries,omitempty"`
+	// When is a CEL condition over earlier outputs (steps.<id>.<field>); the
+	// step is skipped when it is false.
+	When string `json:"when,omitempty"`
+	// Assert lists CEL checks over the outputs, the step's own included, that
+	// must hold for the step to complete.
+	Assert []string `json:"assert,omitempty"`
+}
+
+// WorkflowRunInput is the payload of a workflow.run job.
+type WorkflowRunInput struct {
+	Workflow WorkflowDef `json:"workflow"`
+	TraceID  string      `json:"trace_id,omitempty"`
+}
+
+// WorkflowRunOutput reports every step of a workflow run, in definition order.
+type WorkflowRunOutput struct {
+	Name     string               `json:"name"`
+	Steps    []WorkflowStepResult `json:"steps"`
+	Duration time.Duration        `json:"duration"`
+}
+
+// Workflow step states. Skipped steps never ran: their when was false, a step
+// they need did not complete, or another step failed first.
+const (
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+	StepSkipped   = "skipped"
+)
+
+// WorkflowStepResult is the outcome of one step.
+type WorkflowStepResult struct {
+	ID       string         `json:"id"`
+	Type     JobType        `json:"type"`
+	State    string         `json:"state"`
+	JobID    string         `json:"job_id,omitempty"`
+	Attempts int            `json:"attempts,omitempty"`
+	Output   map[string]any `json:"output,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	// Reason says why a step was skipped.
+	Reason    string            `json:"reason,omitempty"`
+	Assertion *AssertionFailure `json:"assertion,omitempty"`
+	Duration  time.Duration     `json:"duration,omitempty"`
+}
+
+// AssertionFailure records a failed assert with the values it read.
+type AssertionFailure struct {
+	Expression string         `json:"expression"`
+	Values     map[string]any `json:"values,omitempty"`
+}
+
+func (a *AssertionFailure) Error() string {
+	keys := make([]string, 0, len(a.Values))
+	for key := range a.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, fmt.Sprintf("%s=%#v", key, a.Values[key]))
+	}
+	return fmt.Sprintf("assertion failed: %s (%s)", a.Expression, strings.Join(values, ", "))
+}
+
+// workflowDocument and stepDocument are the YAML shapes of a workflow, with
+// durations written as strings such as "10m".
+type workflowDocument struct {
+	Name  string         `yaml:"name"`
+	Steps []stepDocument `yaml:"steps"`
+}
+
+type stepDocument struct {
+	ID      string         `yaml:"id"`
+	Type    string         `yaml:"type"`
+	Needs   []string       `yaml:"needs"`
+	Payload map[string]any `yaml:"payload"`
+	Timeout string         `yaml:"timeout"`
+	Retries int            `yaml:"retries"`
+	When    string         `yaml:"when"`
+	Assert  []string       `yaml:"assert"`
+}
+
+// ParseWorkflow reads a YAML workflow definition. The name defaults to the file
+// name without extension. The result still needs Validate.
+func ParseWorkflow(name string, data []byte) (WorkflowDef, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var doc workflowDocument
+	if err := dec.Decode(&doc); err != nil {
+		return WorkflowDef{}, fmt.Errorf("%s: %w", name, err)
+	}
+	def := WorkflowDef{Name: doc.Name}
+	if def.Name == "" {
+		def.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	for _, s := range doc.Steps {
+		step := WorkflowStep{
+			ID:      s.ID,
+			Type:    JobType(s.Type),
+			Needs:   s.Needs,
+			Payload: s.Payload,
+			Retries: s.Retries,
+			When:    s.When,
+			Assert:  s.Assert,
+		}
+		if s.Timeout != "" {
+			timeout, err := time.ParseDuration(s.Timeout)
+			if err != nil || timeout <= 0 {
+				return WorkflowDef{}, fmt.Errorf("%s: step %s: invalid timeout %q: want a positive duration such as 10m", name, s.ID, s.Timeout)
+			}
+			step.Timeout = timeout
+		}
+		def.Steps = append(def.Steps, step)
+	}
+	return def, nil
+}
+
+// Validate checks the step ids, types and dependencies, rejects cycles, type
+// checks the when and assert expressions, and checks every payload template and
+// the steps it references. Payloads without templates are validated like single
+// jobs; templated ones are validated by the worker once rendered. maxTimeout,
+// when set, caps step timeouts.
+func (d WorkflowDef) Validate(maxTimeout time.Duration) error {
+	if len(d.Steps) == 0 {
+		return errors.New("workflow has no steps")
+	}
+	steps := make(map[string]WorkflowStep, len(d.Steps))
+	for _, step := range d.Steps {
+		if !stepIDPattern.MatchString(step.ID) {
+			return fmt.Errorf("invalid step id %q: want lowercase letters, digits and _ starting with a letter", step.ID)
+		}
+		if _, dup := steps[step.ID]; dup {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		steps[step.ID] = step
+	}
+
+	for _, step := range d.Steps {
+		if step.Type == JobTypeWorkflowRun {
+			return fmt.Errorf("step %s: workflows cannot be nested", step.ID)
+		}
+		if step.Timeout < 0 || (maxTimeout > 0 && step.Timeout > maxTimeout) {
+			return fmt.Errorf("step %s: timeout %s exceeds the job timeout %s", step.ID, step.Timeout, maxTimeout)
+		}
+		if step.Retries < 0 || step.Retries > maxStepRetries {
+			return fmt.Errorf("step %s: retries must be between 0 and %d", step.ID, maxStepRetries)
+		}
+		for _, need := range step.Needs {
+			if _, ok := steps[need]; !ok {
+				return fmt.Errorf("step %s: needs unknown step %q", step.ID, need)
+			}
+		}
+	}
+
+	if _, err := d.order(); err != nil {
+		return err
+	}
+	if _, err := d.conditions(); err != nil {
+		return err
+	}
+
+	for _, step := range d.Steps {
+		ancestors := d.ancestors(step.ID)
+		templated := false
+		err := walkStrings(step.Payload, func(value string) error {
+			if !strings.Contains(value, "{{") {
+				return nil
+			}
+			templated = true
+			tmpl, err := parsePayloadTemplate(value)
+			if err != nil {
+				return err
+			}
+			for _, ref := range stepRefs(tmpl.Tree.Root) {
+				if !ancestors[ref] {
+					return fmt.Errorf("references step %q, which it does not need", ref)
+				}
+			}
+			return nil
+		})
+		if err == nil && !templated {
+			err = ValidateJobPayload(step.Type, step.Payload)
+		}
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+	}
+	return nil
+}
+
+// stepOutputFields are the output fields when and assert can read for each step
+// type, following the type's output struct.
+var stepOutputFields = map[JobType]map[string]expr.FieldType{
+	JobTypeWindowsExec: {"stdout": expr.String, "stderr": expr.String, "exit_code": expr.Int},
+	JobTypeAlohaRun: {
+		"raw": expr.String, "attempts": expr.Int, "screen": expr.Int, "duration": expr.Int,
+		"usage": expr.Dyn, "verification": expr.Dyn,
+	},
+	JobTypePlaywrightRun:     {"browser": expr.String, "files": expr.StringList, "artifact_dir": expr.String, "duration": expr.Int},
+	JobTypePlaywrightCapture: {"browser": expr.String, "files": expr.StringList, "artifact_dir": expr.String, "duration": expr.Int},
+}
+
+// stepConditions are a step's compiled when and assert expressions.
+type stepConditions struct {
+	when   *expr.Program
+	assert []*expr.Program
+}
+
+// conditions compiles every when and assert, checking that they only read
+// steps the step needs (and, for assert, the step itself).
+func (d WorkflowDef) conditions() (map[string]stepConditions, error) {
+	fields := make(map[string]map[string]expr.FieldType, len(d.Steps))
+	for _, step := range d.Steps {
+		fields[step.ID] = stepOutputFields[step.Type]
+	}
+	env, err := expr.StepsEnv(fields)
+	if err != nil {
+		return nil, err
+	}
+	compile := func(step WorkflowStep, source string, self bool) (*expr.Program, error) {
+		prg, err := env.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.ID, err)
+		}
+		ancestors := d.ancestors(step.ID)
+		for _, ref := range prg.Refs() {
+			parts := strings.Split(ref, ".")
+			if parts[0] != "steps" || len(parts) < 2 {
+				continue
+			}
+			if !ancestors[parts[1]] && !(self && parts[1] == step.ID) {
+				return nil, fmt.Errorf("step %s: %q references step %q, which it does not need", step.ID, source, parts[1])
+			}
+		}
+		return prg, nil
+	}
+
+	conds := map[string]stepConditions{}
+	for _, step := range d.Steps {
+		var c stepConditions
+		if step.When != "" {
+			if c.when, err = compile(step, step.When, false); err != nil {
+				return nil, err
+			}
+		}
+		for _, source := range step.Assert {
+			prg, err := compile(step, source, true)
+			if err != nil {
+				return nil, err
+			}
+			c.assert = append(c.assert, prg)
+		}
+		conds[step.ID] = c
+	}
+	return conds, nil
+}
+
+// stepsVars returns the CEL variables for the given step outputs.
+func stepsVars(outputs map[string]map[string]any) map[string]any {
+	steps := make(map[string]any, len(outputs))
+	for id, output := range outputs {
+		steps[id] = output
+	}
+	return map[string]any{"steps": steps}
+}
+
+// order returns the step ids in a dependency-respecting order, failing on cycles.
+func (d WorkflowDef) order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	needs := make(map[string][]string, len(d.Steps))
+	for _, step := range d.Steps {
+		needs[step.ID] = step.Needs
+	}
+	state := map[string]int{}
+	var order []string
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, id), " -> "))
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, need := range needs[id] {
+			if err := visit(need, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		order = append(order, id)
+		return nil
+	}
+	for _, step := range d.Steps {
+		if err := visit(step.ID, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ancestors returns every step id reachable through id's needs.
+func (d WorkflowDef) ancestors(id string) map[string]bool {
+	needs := make(map[string][]string, len(d.Steps))
+	for _, step := range d.Steps {
+		needs[step.ID] = step.Needs
+	}
+	seen := map[string]bool{}
+	var walk func(string)
+	walk = func(id string) {
+		for _, need := range needs[id] {
+			if !seen[need] {
+				seen[need] = true
+				walk(need)
+			}
+		}
+	}
+	walk(id)
+	return seen
+}
+
+// payloadFuncs are available in payload templates.
+var payloadFuncs = template.FuncMap{
+	"trim": strings.TrimSpace,
+}
+
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Option("missingkey=error").Funcs(payloadFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	return tmpl, nil
+}
+
+// renderPayload executes the templates in payload against the outputs of the
+// steps run so far, keyed by step id.
+func renderPayload(payload map[string]any, outputs map[string]map[string]any) (map[string]any, error) {
+	data := map[string]any{"steps": outputs}
+	rendered, err := mapStrings(payload, func(value string) (string, error) {
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+		tmpl, err := parsePayloadTemplate(value)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return "", fmt.Errorf("render %q: %w", value, err)
+		}
+		return sb.String(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, _ := rendered.(map[string]any)
+	return out, nil
+}
+
+// walkStrings calls fn for every string in value, descending into maps and lists.
+func walkStrings(value any, fn func(string) error) error {
+	_, err := mapStrings(value, func(s string) (string, error) {
+		return s, fn(s)
+	})
+	return err
+}
+
+// mapStrings copies value with every string replaced by fn's result.
+func mapStrings(value any, fn func(string) (string, error)) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			mapped, err := mapStrings(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = mapped
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			mapped, err := mapStrings(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = mapped
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// stepRefs returns the step ids a template reads through .steps.<id>.
+func stepRefs(node parse.Node) []string {
+	var refs []string
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.FieldNode:
+			if len(n.Ident) >= 2 && n.Ident[0] == "steps" {
+				refs = append(refs, n.Ident[1])
+			}
+		}
+	}
+	walk(node)
+	return refs
+}
@@ -0,0 +1,198 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/alejg/win-automation/internal/expr"
+	"github.com/alejg/win-automation/internal/logx"
+)
+
+// StepRunner runs one attempt of a workflow step as a job with the rendered
+// input and returns the job's id and decoded output.
+type StepRunner func(ctx context.Context, step WorkflowStep, attempt int, input map[string]any) (jobID string, output map[string]any, err error)
+
+type stepDone struct {
+	result WorkflowStepResult
+	err    error
+}
+
+// RunWorkflow executes def, starting each step once everything it needs has
+// completed, so independent steps run concurrently. A step whose when is false
+// is skipped. The first failed step stops new steps from starting; steps that
+// never ran are reported as skipped.
+func RunWorkflow(ctx context.Context, def WorkflowDef, traceID string, run StepRunner) (WorkflowRunOutput, error) {
+	start := time.Now()
+	conds, err := def.conditions()
+	if err != nil {
+		return WorkflowRunOutput{Name: def.Name}, err
+	}
+	results := make(map[string]WorkflowStepResult, len(def.Steps))
+	outputs := map[string]map[string]any{}
+	done := make(chan stepDone)
+	running := 0
+	var failure error
+
+	for {
+		if failure == nil && ctx.Err() == nil {
+			for _, step := range def.Steps {
+				if _, seen := results[step.ID]; seen || !needsCompleted(step, results) {
+					continue
+				}
+				if when := conds[step.ID].when; when != nil {
+					ok, err := when.Eval(stepsVars(outputs))
+					if err != nil {
+						results[step.ID] = WorkflowStepResult{ID: step.ID, Type: step.Type, State: StepFailed, Error: err.Error()}
+						failure = fmt.Errorf("step %s: %w", step.ID, err)
+						break
+					}
+					if !ok {
+						results[step.ID] = WorkflowStepResult{ID: step.ID, Type: step.Type, State: StepSkipped, Reason: "when is false: " + step.When}
+						logx.Info("workflow", "step", "skipped",
+							logx.Field{Key: "step", Value: step.ID},
+							logx.Field{Key: "when", Value: step.When},
+							logx.Field{Key: "trace_id", Value: traceID},
+						)
+						continue
+					}
+				}
+				input, err := renderPayload(step.Payload, outputs)
+				if err == nil {
+					req := JobRequest{Type: step.Type, Payload: input, Timeout: step.Timeout, TraceID: traceID}
+					input, err = req.WorkflowInput()
+				}
+				if err == nil {
+					err = ValidateJobPayload(step.Type, input)
+				}
+				if err != nil {
+					results[step.ID] = WorkflowStepResult{ID: step.ID, Type: step.Type, State: StepFailed, Error: err.Error()}
+					failure = fmt.Errorf("step %s: %w", step.ID, err)
+					break
+				}
+				results[step.ID] = WorkflowStepResult{ID: step.ID, Type: step.Type, State: "running"}
+				running++
+				go func(step WorkflowStep, input map[string]any, outputs map[string]map[string]any) {
+					result, err := runStep(ctx, step, input, conds[step.ID].assert, outputs, traceID, run)
+					done <- stepDone{result: result, err: err}
+				}(step, input, maps.Clone(outputs))
+			}
+		}
+		if running == 0 {
+			break
+		}
+		d := <-done
+		running--
+		results[d.result.ID] = d.result
+		if d.err != nil {
+			if failure == nil {
+				failure = fmt.Errorf("step %s: %w", d.result.ID, d.err)
+			}
+			continue
+		}
+		outputs[d.result.ID] = d.result.Output
+	}
+
+	out := WorkflowRunOutput{Name: def.Name, Duration: time.Since(start)}
+	for _, step := range def.Steps {
+		result, ok := results[step.ID]
+		if !ok {
+			result = WorkflowStepResult{ID: step.ID, Type: step.Type, State: StepSkipped, Reason: "not started after a failure"}
+			for _, need := range step.Needs {
+				if state := results[need].State; state != StepCompleted {
+					result.Reason = fmt.Sprintf("needs %s, which was %s", need, state)
+					break
+				}
+			}
+		}
+		out.Steps = append(out.Steps, result)
+	}
+	if failure == nil {
+		failure = ctx.Err()
+	}
+	return out, failure
+}
+
+func needsCompleted(step WorkflowStep, results map[string]WorkflowStepResult) bool {
+	for _, need := range step.Needs {
+		if results[need].State != StepCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// runStep runs a step with its timeout and retries. An attempt whose output
+// fails an assert counts as failed.
+func runStep(ctx context.Context, step WorkflowStep, input map[string]any, asserts []*expr.Program, outputs map[string]map[string]any, traceID string, run StepRunner) (WorkflowStepResult, error) {
+	result := WorkflowStepResult{ID: step.ID, Type: step.Type}
+	start := time.Now()
+	fields := func(extra ...logx.Field) []logx.Field {
+		return append([]logx.Field{
+			{Key: "step", Value: step.ID},
+			{Key: "type", Value: string(step.Type)},
+			{Key: "attempt", Value: result.Attempts},
+			{Key: "trace_id", Value: traceID},
+		}, extra...)
+	}
+
+	var err error
+	for attempt := 1; attempt <= step.Retries+1; attempt++ {
+		result.Attempts = attempt
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		}
+		logx.Info("workflow", "step", "started", fields()...)
+		result.JobID, result.Output, err = run(attemptCtx, step, attempt, input)
+		cancel()
+		result.Assertion = nil
+		if err == nil {
+			outputs[step.ID] = result.Output
+			result.Assertion, err = checkAsserts(asserts, stepsVars(outputs))
+		}
+		if err == nil {
+			break
+		}
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("timed out after %s", step.Timeout)
+		}
+		logx.Warn("workflow", "step", "attempt failed", fields(
+			logx.Field{Key: "job_id", Value: result.JobID},
+			logx.Field{Key: "err", Value: err.Error()},
+		)...)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.State = StepFailed
+		result.Error = err.Error()
+		logx.Error("workflow", "step", "failed", err, fields(logx.Field{Key: "job_id", Value: result.JobID})...)
+		return result, err
+	}
+	result.State = StepCompleted
+	logx.Info("workflow", "step", "completed", fields(
+		logx.Field{Key: "job_id", Value: result.JobID},
+		logx.Field{Key: "duration_ms", Value: result.Duration.Milliseconds()},
+	)...)
+	return result, nil
+}
+
+// checkAsserts evaluates asserts in order and returns the first that fails.
+func checkAsserts(asserts []*expr.Program, vars map[string]any) (*AssertionFailure, error) {
+	for _, prg := range asserts {
+		ok, err := prg.Eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			failure := &AssertionFailure{Expression: prg.Source(), Values: prg.Values(vars)}
+			return failure, failure
+		}
+	}
+	return nil, nil
+}
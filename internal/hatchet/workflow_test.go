@@ -0,0 +1,247 @@
+package hatchet
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const installWorkflow = `name: install-app
+steps:
+  - id: install
+    type: windows.exec
+    payload:
+      command: choco install app -y
+    timeout: 10m
+    retries: 1
+  - id: version
+    type: windows.exec
+    needs: [install]
+    payload:
+      command: (Get-Item C:\app.exe).VersionInfo.FileVersion
+  - id: open
+    type: aloha.run
+    needs: [version]
+    payload:
+      task: "Open app {{ trim .steps.version.stdout }} and check the About dialog"
+`
+
+func TestParseWorkflow(t *testing.T) {
+	def, err := ParseWorkflow("install.yaml", []byte(installWorkflow))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+	if err := def.Validate(30 * time.Minute); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if def.Name != "install-app" || len(def.Steps) != 3 {
+		t.Fatalf("def = %+v", def)
+	}
+	if install := def.Steps[0]; install.Timeout != 10*time.Minute || install.Retries != 1 {
+		t.Errorf("install = %+v", install)
+	}
+
+	unnamed, err := ParseWorkflow("/tmp/nightly.yml", []byte("steps: []\n"))
+	if err != nil || unnamed.Name != "nightly" {
+		t.Errorf("ParseWorkflow() = %+v, %v; want name from file", unnamed, err)
+	}
+	if _, err := ParseWorkflow("w.yaml", []byte("steps:\n  - id: a\n    type: windows.exec\n    depends: [b]\n")); err == nil {
+		t.Error("ParseWorkflow() with an unknown field error = nil, want error")
+	}
+}
+
+func TestWorkflowDef_Validate(t *testing.T) {
+	exec := func(id string, needs ...string) WorkflowStep {
+		return WorkflowStep{ID: id, Type: JobTypeWindowsExec, Needs: needs, Payload: map[string]any{"command": "hostname"}}
+	}
+	tests := []struct {
+		name string
+		def  WorkflowDef
+		want string
+	}{
+		{"no steps", WorkflowDef{}, "no steps"},
+		{"bad id", WorkflowDef{Steps: []WorkflowStep{exec("Install-App")}}, "invalid step id"},
+		{"duplicate", WorkflowDef{Steps: []WorkflowStep{exec("a"), exec("a")}}, "duplicate step id"},
+		{"unknown need", WorkflowDef{Steps: []WorkflowStep{exec("a", "b")}}, `needs unknown step "b"`},
+		{"cycle", WorkflowDef{Steps: []WorkflowStep{exec("a", "c"), exec("b", "a"), exec("c", "b")}}, "dependency cycle"},
+		{"nested", WorkflowDef{Steps: []WorkflowStep{{ID: "a", Type: JobTypeWorkflowRun}}}, "cannot be nested"},
+		{"timeout", WorkflowDef{Steps: []WorkflowStep{{ID: "a", Type: JobTypeWindowsExec, Payload: map[string]any{"command": "x"}, Timeout: time.Hour}}}, "exceeds the job timeout"},
+		{"payload", WorkflowDef{Steps: []WorkflowStep{{ID: "a", Type: JobTypeWindowsExec, Payload: map[string]any{}}}}, "payload.command is required"},
+		{"bad template", WorkflowDef{Steps: []WorkflowStep{
+			{ID: "a", Type: JobTypeWindowsExec, Payload: map[string]any{"command": "echo {{ .steps.b.stdout"}},
+		}}, "invalid template"},
+		{"reference not needed", WorkflowDef{Steps: []WorkflowStep{
+			exec("a"),
+			exec("b"),
+			{ID: "c", Type: JobTypeWindowsExec, Needs: []string{"a"}, Payload: map[string]any{"command": "echo {{ .steps.b.stdout }}"}},
+		}}, `references step "b"`},
+		{"bad when", WorkflowDef{Steps: []WorkflowStep{
+			exec("a"),
+			{ID: "b", Type: JobTypeWindowsExec, Needs: []string{"a"}, When: "steps.a.exit_code ==", Payload: map[string]any{"command": "x"}},
+		}}, "invalid expression"},
+		{"when not needed", WorkflowDef{Steps: []WorkflowStep{
+			exec("a"),
+			{ID: "b", Type: JobTypeWindowsExec, When: "steps.a.exit_code == 0", Payload: map[string]any{"command": "x"}},
+		}}, `references step "a"`},
+		{"when reads itself", WorkflowDef{Steps: []WorkflowStep{
+			{ID: "a", Type: JobTypeWindowsExec, When: "steps.a.exit_code == 0", Payload: map[string]any{"command": "x"}},
+		}}, `references step "a"`},
+		{"when compares exit code with a string", WorkflowDef{Steps: []WorkflowStep{
+			exec("a"),
+			{ID: "b", Type: JobTypeWindowsExec, Needs: []string{"a"}, When: `steps.a.exit_code == "0"`, Payload: map[string]any{"command": "x"}},
+		}}, "invalid expression"},
+		{"assert reads unknown field", WorkflowDef{Steps: []WorkflowStep{
+			{ID: "a", Type: JobTypeWindowsExec, Assert: []string{"steps.a.files.size() > 0"}, Payload: map[string]any{"command": "x"}},
+		}}, "invalid expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.Validate(30 * time.Minute)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	transitive := WorkflowDef{Steps: []WorkflowStep{
+		exec("a"),
+		exec("b", "a"),
+		{ID: "c", Type: JobTypeWindowsExec, Needs: []string{"b"}, Payload: map[string]any{"command": "echo {{ .steps.a.stdout }}"}},
+	}}
+	if err := transitive.Validate(0); err != nil {
+		t.Errorf("Validate() with a transitive reference error = %v", err)
+	}
+}
+
+func TestRunWorkflow_PassesOutputsAlongDependencies(t *testing.T) {
+	def, err := ParseWorkflow("install.yaml", []byte(installWorkflow))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	inputs := map[string]map[string]any{}
+	run := func(ctx context.Context, step WorkflowStep, attempt int, input map[string]any) (string, map[string]any, error) {
+		mu.Lock()
+		inputs[step.ID] = input
+		mu.Unlock()
+		if step.ID == "install" && attempt == 1 {
+			return "job-install-1", nil, errors.New("choco busy")
+		}
+		return "job-" + step.ID, map[string]any{"stdout": "1.2.3\r\n", "exit_code": 0}, nil
+	}
+
+	out, err := RunWorkflow(context.Background(), def, "trace-1", run)
+	if err != nil {
+		t.Fatalf("RunWorkflow() error = %v", err)
+	}
+	if got := inputs["open"]["task"]; got != "Open app 1.2.3 and check the About dialog" {
+		t.Errorf("open task = %q", got)
+	}
+	if got := inputs["install"]["timeout"]; got != 10*time.Minute {
+		t.Errorf("install timeout = %v, want the step timeout", got)
+	}
+	if got := inputs["install"]["trace_id"]; got != "trace-1" {
+		t.Errorf("install trace_id = %v", got)
+	}
+	install := out.Steps[0]
+	if install.State != StepCompleted || install.Attempts != 2 || install.JobID != "job-install" {
+		t.Errorf("install = %+v, want completed on the second attempt", install)
+	}
+}
+
+func TestRunWorkflow_FailureSkipsDependents(t *testing.T) {
+	def := WorkflowDef{Name: "w", Steps: []WorkflowStep{
+		{ID: "slow", Type: JobTypeWindowsExec, Payload: map[string]any{"command": "sleep"}, Timeout: 20 * time.Millisecond},
+		{ID: "after", Type: JobTypeWindowsExec, Needs: []string{"slow"}, Payload: map[string]any{"command": "x"}},
+		{ID: "other", Type: JobTypeWindowsExec, Payload: map[string]any{"command": "x"}},
+	}}
+	run := func(ctx context.Context, step WorkflowStep, attempt int, input map[string]any) (string, map[string]any, error) {
+		if step.ID == "slow" {
+			<-ctx.Done()
+			return "job-slow", nil, ctx.Err()
+		}
+		return "job-" + step.ID, map[string]any{}, nil
+	}
+
+	out, err := RunWorkflow(context.Background(), def, "", run)
+	if err == nil || !strings.Contains(err.Error(), "step slow: timed out") {
+		t.Fatalf("RunWorkflow() error = %v, want slow to time out", err)
+	}
+	states := map[string]string{}
+	for _, step := range out.Steps {
+		states[step.ID] = step.State
+	}
+	if states["slow"] != StepFailed || states["after"] != StepSkipped || states["other"] != StepCompleted {
+		t.Errorf("states = %v", states)
+	}
+}
+
+func TestRunWorkflow_Conditions(t *testing.T) {
+	def, err := ParseWorkflow("w.yaml", []byte(`steps:
+  - id: check
+    type: windows.exec
+    payload: {command: Test-Path C:\app.exe}
+  - id: install
+    type: windows.exec
+    needs: [check]
+    when: steps.check.stdout.trim() == "False"
+    payload: {command: choco install app -y}
+  - id: after_install
+    type: windows.exec
+    needs: [install]
+    payload: {command: hostname}
+  - id: verify
+    type: windows.exec
+    needs: [check]
+    retries: 1
+    payload: {command: app.exe --version}
+    assert:
+      - steps.verify.exit_code == 0 && steps.verify.stdout.contains("2.0")
+`))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+	if err := def.Validate(0); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	run := func(ctx context.Context, step WorkflowStep, attempt int, input map[string]any) (string, map[string]any, error) {
+		switch step.ID {
+		case "check":
+			return "job-check", map[string]any{"stdout": "True\r\n", "exit_code": float64(0)}, nil
+		case "verify":
+			return "job-verify", map[string]any{"stdout": "1.9.0", "exit_code": float64(0)}, nil
+		}
+		t.Errorf("step %s ran", step.ID)
+		return "", nil, nil
+	}
+
+	out, err := RunWorkflow(context.Background(), def, "", run)
+	var failure *AssertionFailure
+	if !errors.As(err, &failure) {
+		t.Fatalf("RunWorkflow() error = %v, want an assertion failure", err)
+	}
+	steps := map[string]WorkflowStepResult{}
+	for _, step := range out.Steps {
+		steps[step.ID] = step
+	}
+	if install := steps["install"]; install.State != StepSkipped || !strings.Contains(install.Reason, "when is false") {
+		t.Errorf("install = %+v, want skipped by its when", install)
+	}
+	if after := steps["after_install"]; after.State != StepSkipped || after.Reason != "needs install, which was skipped" {
+		t.Errorf("after_install = %+v", after)
+	}
+	verify := steps["verify"]
+	if verify.State != StepFailed || verify.Attempts != 2 || verify.Assertion == nil {
+		t.Fatalf("verify = %+v, want failed assertion after a retry", verify)
+	}
+	if got := verify.Assertion.Values; got["steps.verify.stdout"] != "1.9.0" || got["steps.verify.exit_code"] != float64(0) {
+		t.Errorf("assertion values = %v", got)
+	}
+	if !strings.Contains(verify.Error, `assertion failed: steps.verify.exit_code == 0`) {
+		t.Errorf("verify error = %q", verify.Error)
+	}
+}
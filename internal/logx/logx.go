@@ -4,9 +4,17 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
+const redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
 // Field represents a structured logging field.
 type Field struct {
 	Key   string
@@ -28,6 +36,31 @@ func Error(component, op, msg string, err error, fields ...Field) {
 	emitLog("error", component, op, msg, err, fields)
 }
 
+// RegisterSecret redacts value from every subsequent log line. Empty values are
+// ignored.
+func RegisterSecret(value string) {
+	if value == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+}
+
+func redact(line string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, s := range secrets {
+		line = strings.ReplaceAll(line, s, redacted)
+	}
+	return line
+}
+
 func emitLog(level, component, op, msg string, err error, fields []Field) {
 	var sb strings.Builder
 	sb.WriteString("ts=")
@@ -51,7 +84,7 @@ func emitLog(level, component, op, msg string, err error, fields []Field) {
 		sb.WriteByte(' ')
 		sb.WriteString(formatField(field))
 	}
-	fmt.Fprintln(os.Stderr, sb.String())
+	fmt.Fprintln(os.Stderr, redact(sb.String()))
 }
 
 func formatField(field Field) string {
@@ -0,0 +1,14 @@
+package logx
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("s3cr3t-path")
+	RegisterSecret("")
+
+	got := redact(`msg=connect url=ws://host:9323/s3cr3t-path err="dial s3cr3t-path"`)
+	want := `msg=connect url=ws://host:9323/[REDACTED] err="dial [REDACTED]"`
+	if got != want {
+		t.Errorf("redact() = %q, want %q", got, want)
+	}
+}
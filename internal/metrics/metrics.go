@@ -3,6 +3,7 @@ package metrics
 import (
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,6 +15,17 @@ const (
 	JobsCancelledTotal = "jobs_cancelled_total"
 	PlaywrightSessions = "playwright_sessions_total"
 	AlohaRunsTotal     = "aloha_runs_total"
+	// PlaywrightLeaseWaitMs sums how long Playwright jobs waited for a free slot;
+	// divided by PlaywrightSessions it gives the mean wait.
+	PlaywrightLeaseWaitMs = "playwright_session_lease_wait_ms_total"
+)
+
+// Gauges.
+const (
+	PlaywrightSessionsActive  = "playwright_sessions_active"
+	PlaywrightSessionsWaiting = "playwright_sessions_waiting"
+	// JobsQueued is the number of jobs waiting in Hatchet's queue, per job type.
+	JobsQueued = "jobs_queued"
 )
 
 var metricNames = []string{
@@ -23,12 +35,21 @@ var metricNames = []string{
 	JobsCancelledTotal,
 	PlaywrightSessions,
 	AlohaRunsTotal,
+	PlaywrightLeaseWaitMs,
 }
 
-// Metrics holds in-memory counters for emitted metrics.
+var gaugeNames = []string{
+	PlaywrightSessionsActive,
+	PlaywrightSessionsWaiting,
+}
+
+// Metrics holds in-memory counters and gauges for emitted metrics.
 type Metrics struct {
 	mu       sync.Mutex
 	counters map[string]uint64
+	gauges   map[string]int64
+	// queued holds JobsQueued by job type.
+	queued map[string]int64
 }
 
 // DefaultMetrics is the shared metrics instance.
@@ -38,10 +59,15 @@ var DefaultMetrics = NewMetrics()
 func NewMetrics() *Metrics {
 	m := &Metrics{
 		counters: make(map[string]uint64, len(metricNames)),
+		gauges:   make(map[string]int64, len(gaugeNames)),
+		queued:   make(map[string]int64),
 	}
 	for _, name := range metricNames {
 		m.counters[name] = 0
 	}
+	for _, name := range gaugeNames {
+		m.gauges[name] = 0
+	}
 	return m
 }
 
@@ -58,6 +84,55 @@ func (m *Metrics) Inc(name string) {
 	m.counters[name]++
 }
 
+// Add increases the named counter by delta when it is recognized.
+func (m *Metrics) Add(name string, delta uint64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.counters[name]; !ok {
+		return
+	}
+	m.counters[name] += delta
+}
+
+// AddGauge moves the named gauge by delta when it is recognized.
+func (m *Metrics) AddGauge(name string, delta int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.gauges[name]; !ok {
+		return
+	}
+	m.gauges[name] += delta
+}
+
+// SetQueued records how many jobs of jobType are queued.
+func (m *Metrics) SetQueued(jobType string, n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queued[jobType] = n
+}
+
+// Value returns the current value of a counter or gauge.
+func (m *Metrics) Value(name string) int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.gauges[name]; ok {
+		return v
+	}
+	return int64(m.counters[name])
+}
+
 // Emit writes all metrics in key=value format.
 func (m *Metrics) Emit(w io.Writer) {
 	if m == nil || w == nil {
@@ -70,4 +145,15 @@ func (m *Metrics) Emit(w io.Writer) {
 	for _, name := range metricNames {
 		fmt.Fprintf(w, "metric=%s value=%d ts=%s\n", name, m.counters[name], ts)
 	}
+	for _, name := range gaugeNames {
+		fmt.Fprintf(w, "metric=%s value=%d ts=%s\n", name, m.gauges[name], ts)
+	}
+	jobTypes := make([]string, 0, len(m.queued))
+	for jobType := range m.queued {
+		jobTypes = append(jobTypes, jobType)
+	}
+	sort.Strings(jobTypes)
+	for _, jobType := range jobTypes {
+		fmt.Fprintf(w, "metric=%s type=%s value=%d ts=%s\n", JobsQueued, jobType, m.queued[jobType], ts)
+	}
 }
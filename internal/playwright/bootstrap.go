@@ -0,0 +1,301 @@
+package playwright
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// NodeDir is where the Node.js MSI installs and where a zip distribution is
+// expanded; the server task runs node.exe from here.
+const NodeDir = `C:\Program Files\nodejs`
+
+// cacheDir stages the uploaded installers and archives on Windows.
+const cacheDir = InstallDir + `\cache`
+
+// Bootstrap step states.
+const (
+	StatePresent   = "present"   // already installed; nothing uploaded
+	StateInstalled = "installed" // installed from the cache by this run
+	StateSkipped   = "skipped"   // not needed for the configured browsers
+)
+
+// Cache is the offline bundle install --bootstrap uploads, found in a local directory:
+//
+//	node-v<version>-x64.msi or node-v<version>-win-x64.zip
+//	playwright-<version>.tgz and playwright-core-<version>.tgz (npm pack)
+//	playwright-browsers-<version>.zip (a zipped PLAYWRIGHT_BROWSERS_PATH; optional)
+type Cache struct {
+	Node        string // Node.js MSI or zip
+	NodeVersion Version
+	Playwright  Version
+	Packages    []string // playwright-core and playwright tarballs
+	Browsers    string   // empty when the cache has no browsers archive
+}
+
+// FindCache picks the newest Node.js installer in dir and the Playwright packages
+// matching version (major.minor picks the newest patch; empty picks the newest).
+func FindCache(dir, version string) (Cache, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Cache{}, fmt.Errorf("read cache: %w", err)
+	}
+	var want Version
+	if version != "" {
+		if want, err = ParseVersion(version); err != nil {
+			return Cache{}, err
+		}
+	}
+	patchPinned := strings.Count(strings.TrimPrefix(version, "v"), ".") == 2
+
+	var cache Cache
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		names[name] = true
+
+		if v, ok := cachedNodeVersion(name); ok {
+			// At the same version the MSI wins over the zip.
+			if cache.Node == "" || newer(v, cache.NodeVersion) ||
+				v == cache.NodeVersion && strings.HasSuffix(name, ".msi") {
+				cache.Node, cache.NodeVersion = filepath.Join(dir, name), v
+			}
+			continue
+		}
+		rest, ok := strings.CutPrefix(name, "playwright-")
+		if !ok || strings.HasPrefix(rest, "core-") || strings.HasPrefix(rest, "browsers-") {
+			continue
+		}
+		v, err := ParseVersion(strings.TrimSuffix(rest, ".tgz"))
+		if err != nil || !strings.HasSuffix(rest, ".tgz") {
+			continue
+		}
+		if patchPinned && v != want || version != "" && !v.Compatible(want) {
+			continue
+		}
+		if cache.Packages == nil || newer(v, cache.Playwright) {
+			cache.Playwright, cache.Packages = v, []string{filepath.Join(dir, name)}
+		}
+	}
+
+	if cache.Node == "" {
+		return Cache{}, fmt.Errorf("no Node.js installer (node-v*-x64.msi or node-v*-win-x64.zip) in %s", dir)
+	}
+	if cache.Packages == nil {
+		if version != "" {
+			return Cache{}, fmt.Errorf("no playwright-%s*.tgz in %s", version, dir)
+		}
+		return Cache{}, fmt.Errorf("no playwright-*.tgz in %s", dir)
+	}
+	core := fmt.Sprintf("playwright-core-%s.tgz", cache.Playwright)
+	if !names[core] {
+		return Cache{}, fmt.Errorf("%s missing from %s", core, dir)
+	}
+	// playwright depends on playwright-core at the same version; installing both
+	// tarballs lets npm resolve it without a registry.
+	cache.Packages = append([]string{filepath.Join(dir, core)}, cache.Packages...)
+	if browsers := fmt.Sprintf("playwright-browsers-%s.zip", cache.Playwright); names[browsers] {
+		cache.Browsers = filepath.Join(dir, browsers)
+	}
+	return cache, nil
+}
+
+func cachedNodeVersion(name string) (Version, bool) {
+	rest, ok := strings.CutPrefix(name, "node-v")
+	if !ok {
+		return Version{}, false
+	}
+	for _, suffix := range []string{"-x64.msi", "-win-x64.zip"} {
+		if v, ok := strings.CutSuffix(rest, suffix); ok {
+			parsed, err := ParseVersion(v)
+			return parsed, err == nil
+		}
+	}
+	return Version{}, false
+}
+
+func newer(a, b Version) bool {
+	if a.Major != b.Major {
+		return a.Major > b.Major
+	}
+	if a.Minor != b.Minor {
+		return a.Minor > b.Minor
+	}
+	return a.Patch > b.Patch
+}
+
+// BootstrapStep reports the outcome of one bootstrap step.
+type BootstrapStep struct {
+	Name   string // node, playwright or browsers
+	State  string
+	Detail string // installed version or browsers
+}
+
+// Bootstrap installs Node.js, the Playwright package and the browser builds for
+// browsers on Windows from cache, without network access on the VM. Steps whose
+// result is already in place are reported as present and not repeated, so a
+// failed bootstrap can simply be rerun. report is called after each step.
+//
+// The uploads take minutes, so ctx needs a deadline of its own rather than
+// cfg.Timeout; install runs it under its --timeout.
+func Bootstrap(ctx context.Context, cfg config.Config, cache Cache, browsers []string, report func(BootstrapStep)) error {
+	if _, err := runSSH(ctx, cfg, win.PowerShellCommand(fmt.Sprintf("New-Item -ItemType Directory -Force -Path '%s' | Out-Null", cacheDir))); err != nil {
+		return fmt.Errorf("prepare cache directory: %w", err)
+	}
+
+	step, err := bootstrapNode(ctx, cfg, cache)
+	if err != nil {
+		return err
+	}
+	report(step)
+
+	if step, err = bootstrapPackage(ctx, cfg, cache); err != nil {
+		return err
+	}
+	report(step)
+
+	if step, err = bootstrapBrowsers(ctx, cfg, cache, Engines(browsers)); err != nil {
+		return err
+	}
+	report(step)
+	return nil
+}
+
+func bootstrapNode(ctx context.Context, cfg config.Config, cache Cache) (BootstrapStep, error) {
+	step := BootstrapStep{Name: "node"}
+	if v, err := remoteNodeVersion(ctx, cfg); err == nil {
+		step.State, step.Detail = StatePresent, v
+		return step, nil
+	}
+
+	remote, err := uploadCached(ctx, cfg, cache.Node)
+	if err != nil {
+		return step, fmt.Errorf("upload node installer: %w", err)
+	}
+	install := win.MSIInstall(remote)
+	if strings.HasSuffix(remote, ".zip") {
+		install = win.NodeZipInstall(remote, NodeDir)
+	}
+	if res, err := runSSH(ctx, cfg, install); err != nil {
+		return step, fmt.Errorf("install node %s: %w: %s", cache.NodeVersion, err, strings.TrimSpace(res.Stderr))
+	}
+	v, err := remoteNodeVersion(ctx, cfg)
+	if err != nil {
+		return step, fmt.Errorf("node not found after install: %w", err)
+	}
+	step.State, step.Detail = StateInstalled, v
+	return step, nil
+}
+
+func remoteNodeVersion(ctx context.Context, cfg config.Config) (string, error) {
+	res, err := runSSH(ctx, cfg, win.NodeVersion())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+func bootstrapPackage(ctx context.Context, cfg config.Config, cache Cache) (BootstrapStep, error) {
+	step := BootstrapStep{Name: "playwright", Detail: cache.Playwright.String()}
+	if v, err := RemoteVersion(ctx, cfg); err == nil && v == cache.Playwright {
+		step.State = StatePresent
+		return step, nil
+	} else if err != nil && !errors.Is(err, ErrNotInstalled) {
+		return step, err
+	}
+
+	remotes := make([]string, 0, len(cache.Packages))
+	for _, pkg := range cache.Packages {
+		remote, err := uploadCached(ctx, cfg, pkg)
+		if err != nil {
+			return step, fmt.Errorf("upload %s: %w", filepath.Base(pkg), err)
+		}
+		remotes = append(remotes, remote)
+	}
+	if res, err := runSSH(ctx, cfg, win.NpmInstallOffline(InstallDir, remotes)); err != nil {
+		return step, fmt.Errorf("npm install playwright@%s: %w: %s", cache.Playwright, err, strings.TrimSpace(res.Stderr))
+	}
+	v, err := RemoteVersion(ctx, cfg)
+	if err != nil {
+		return step, err
+	}
+	if v != cache.Playwright {
+		return step, fmt.Errorf("installed playwright %s, want %s", v, cache.Playwright)
+	}
+	step.State = StateInstalled
+	return step, nil
+}
+
+func bootstrapBrowsers(ctx context.Context, cfg config.Config, cache Cache, engines []string) (BootstrapStep, error) {
+	step := BootstrapStep{Name: "browsers", Detail: strings.Join(engines, ",")}
+	if len(engines) == 0 {
+		step.State = StateSkipped
+		return step, nil
+	}
+	// Builds are tied to the package version, so the marker names it.
+	dir := InstallDir + `\browsers`
+	marker := ".bootstrap-" + cache.Playwright.String()
+	if _, err := runSSH(ctx, cfg, win.BrowsersPresent(dir, marker, engines)); err == nil {
+		step.State = StatePresent
+		return step, nil
+	}
+	if cache.Browsers == "" {
+		return step, fmt.Errorf("no playwright-browsers-%s.zip in the cache for %s", cache.Playwright, step.Detail)
+	}
+
+	remote, err := uploadCached(ctx, cfg, cache.Browsers)
+	if err != nil {
+		return step, fmt.Errorf("upload browsers: %w", err)
+	}
+	if res, err := runSSH(ctx, cfg, win.BrowsersExpand(remote, dir, marker)); err != nil {
+		return step, fmt.Errorf("expand browsers: %w: %s", err, strings.TrimSpace(res.Stderr))
+	}
+	if _, err := runSSH(ctx, cfg, win.BrowsersPresent(dir, marker, engines)); err != nil {
+		return step, fmt.Errorf("browsers archive lacks a build for %s", step.Detail)
+	}
+	step.State = StateInstalled
+	return step, nil
+}
+
+// uploadCached copies a cache file to cacheDir on Windows unless an identical copy
+// is already there, and returns the remote path.
+func uploadCached(ctx context.Context, cfg config.Config, local string) (string, error) {
+	remote := cacheDir + `\` + filepath.Base(local)
+	sum, err := fileSHA256(local)
+	if err != nil {
+		return "", err
+	}
+	res, err := runSSH(ctx, cfg, win.FileSHA256(remote))
+	if err == nil && strings.TrimSpace(res.Stdout) == sum {
+		return remote, nil
+	}
+	if err := uploadFile(ctx, cfg, local, remote); err != nil {
+		return "", err
+	}
+	return remote, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
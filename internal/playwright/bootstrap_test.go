@@ -0,0 +1,216 @@
+package playwright
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+func writeCache(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindCache(t *testing.T) {
+	dir := writeCache(t,
+		"node-v9.11.2-x64.msi",
+		"node-v20.18.1-win-x64.zip",
+		"node-v20.18.1-x64.msi",
+		"playwright-1.48.2.tgz",
+		"playwright-core-1.48.2.tgz",
+		"playwright-1.49.0.tgz",
+		"playwright-1.49.1.tgz",
+		"playwright-core-1.49.1.tgz",
+		"playwright-browsers-1.49.1.zip",
+		"README.txt",
+	)
+
+	cache, err := FindCache(dir, "")
+	if err != nil {
+		t.Fatalf("FindCache() error = %v", err)
+	}
+	want := Cache{
+		Node:        filepath.Join(dir, "node-v20.18.1-x64.msi"),
+		NodeVersion: Version{20, 18, 1},
+		Playwright:  Version{1, 49, 1},
+		Packages:    []string{filepath.Join(dir, "playwright-core-1.49.1.tgz"), filepath.Join(dir, "playwright-1.49.1.tgz")},
+		Browsers:    filepath.Join(dir, "playwright-browsers-1.49.1.zip"),
+	}
+	if !reflect.DeepEqual(cache, want) {
+		t.Errorf("FindCache() = %+v, want %+v", cache, want)
+	}
+
+	cache, err = FindCache(dir, "1.48")
+	if err != nil {
+		t.Fatalf("FindCache(1.48) error = %v", err)
+	}
+	if cache.Playwright != (Version{1, 48, 2}) || cache.Browsers != "" {
+		t.Errorf("FindCache(1.48) = %+v, want 1.48.2 without browsers", cache)
+	}
+
+	if _, err := FindCache(dir, "1.49.0"); err == nil {
+		t.Error("FindCache(1.49.0) error = nil, want missing playwright-core")
+	}
+	if _, err := FindCache(dir, "1.50"); err == nil {
+		t.Error("FindCache(1.50) error = nil, want error")
+	}
+}
+
+func TestFindCache_MissingNode(t *testing.T) {
+	dir := writeCache(t, "playwright-1.49.1.tgz", "playwright-core-1.49.1.tgz")
+	if _, err := FindCache(dir, ""); err == nil {
+		t.Error("FindCache() error = nil, want missing Node.js installer")
+	}
+}
+
+// fakeWindows answers SSH scripts from per-script queues of exit codes and
+// stdouts, and records the labels of the scripts and uploads in order.
+type fakeWindows struct {
+	labels  map[string]string
+	replies map[string][]sshx.Result
+	calls   []string
+}
+
+func newFakeWindows(t *testing.T) *fakeWindows {
+	t.Helper()
+	f := &fakeWindows{labels: map[string]string{}, replies: map[string][]sshx.Result{}}
+	origRun, origUpload := runSSH, uploadFile
+	runSSH = func(_ context.Context, _ config.Config, script string) (sshx.Result, error) {
+		label, ok := f.labels[script]
+		if !ok {
+			label = "other"
+		}
+		f.calls = append(f.calls, label)
+		var res sshx.Result
+		if queue := f.replies[script]; len(queue) > 0 {
+			res, f.replies[script] = queue[0], queue[1:]
+		}
+		if res.ExitCode != 0 {
+			return res, &sshx.ExitError{Code: res.ExitCode}
+		}
+		return res, nil
+	}
+	uploadFile = func(_ context.Context, _ config.Config, local, _ string) error {
+		f.calls = append(f.calls, "upload "+filepath.Base(local))
+		return nil
+	}
+	t.Cleanup(func() { runSSH, uploadFile = origRun, origUpload })
+	return f
+}
+
+// on labels script and queues its replies; once they run out it succeeds
+// with empty output.
+func (f *fakeWindows) on(label, script string, replies ...sshx.Result) {
+	f.labels[script] = label
+	f.replies[script] = replies
+}
+
+func TestBootstrap_FreshHost(t *testing.T) {
+	dir := writeCache(t, "node-v20.18.1-x64.msi", "playwright-1.49.1.tgz", "playwright-core-1.49.1.tgz", "playwright-browsers-1.49.1.zip")
+	cache, err := FindCache(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := func(name string) string { return cacheDir + `\` + name }
+	browsersDir, marker := InstallDir+`\browsers`, ".bootstrap-1.49.1"
+	engines := []string{"chromium"}
+
+	f := newFakeWindows(t)
+	f.on("node version", win.NodeVersion(), sshx.Result{ExitCode: 1}, sshx.Result{Stdout: "v20.18.1\n"})
+	f.on("install node", win.MSIInstall(remote("node-v20.18.1-x64.msi")))
+	f.on("playwright version", win.NodePackageVersion(InstallDir, "playwright"), sshx.Result{ExitCode: 3}, sshx.Result{Stdout: "1.49.1\n"})
+	f.on("npm install", win.NpmInstallOffline(InstallDir, []string{remote("playwright-core-1.49.1.tgz"), remote("playwright-1.49.1.tgz")}))
+	f.on("browsers present", win.BrowsersPresent(browsersDir, marker, engines), sshx.Result{ExitCode: 1})
+	f.on("expand browsers", win.BrowsersExpand(remote("playwright-browsers-1.49.1.zip"), browsersDir, marker))
+	for _, name := range []string{"node-v20.18.1-x64.msi", "playwright-core-1.49.1.tgz", "playwright-1.49.1.tgz", "playwright-browsers-1.49.1.zip"} {
+		f.on("hash "+name, win.FileSHA256(remote(name)), sshx.Result{ExitCode: 1})
+	}
+
+	var steps []BootstrapStep
+	if err := Bootstrap(context.Background(), config.Config{}, cache, []string{"chromium", "msedge"}, func(s BootstrapStep) { steps = append(steps, s) }); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+
+	want := []string{
+		"other", // mkdir
+		"node version", "hash node-v20.18.1-x64.msi", "upload node-v20.18.1-x64.msi", "install node", "node version",
+		"playwright version",
+		"hash playwright-core-1.49.1.tgz", "upload playwright-core-1.49.1.tgz",
+		"hash playwright-1.49.1.tgz", "upload playwright-1.49.1.tgz",
+		"npm install", "playwright version",
+		"browsers present", "hash playwright-browsers-1.49.1.zip", "upload playwright-browsers-1.49.1.zip", "expand browsers", "browsers present",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls =\n%q\nwant\n%q", f.calls, want)
+	}
+	wantSteps := []BootstrapStep{
+		{Name: "node", State: StateInstalled, Detail: "v20.18.1"},
+		{Name: "playwright", State: StateInstalled, Detail: "1.49.1"},
+		{Name: "browsers", State: StateInstalled, Detail: "chromium"},
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("steps = %+v, want %+v", steps, wantSteps)
+	}
+}
+
+func TestBootstrap_Rerun(t *testing.T) {
+	dir := writeCache(t, "node-v20.18.1-x64.msi", "playwright-1.49.1.tgz", "playwright-core-1.49.1.tgz")
+	cache, err := FindCache(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeWindows(t)
+	f.on("node version", win.NodeVersion(), sshx.Result{Stdout: "v20.18.1\n"})
+	f.on("playwright version", win.NodePackageVersion(InstallDir, "playwright"), sshx.Result{Stdout: "1.49.1\n"})
+	f.on("browsers present", win.BrowsersPresent(InstallDir+`\browsers`, ".bootstrap-1.49.1", []string{"firefox"}))
+
+	var steps []BootstrapStep
+	if err := Bootstrap(context.Background(), config.Config{}, cache, []string{"firefox"}, func(s BootstrapStep) { steps = append(steps, s) }); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	for _, call := range f.calls {
+		if strings.HasPrefix(call, "upload ") {
+			t.Errorf("rerun uploaded %s", strings.TrimPrefix(call, "upload "))
+		}
+	}
+	for _, s := range steps {
+		if s.State != StatePresent {
+			t.Errorf("step %s = %s, want %s", s.Name, s.State, StatePresent)
+		}
+	}
+}
+
+func TestUploadCached_SkipsIdenticalCopy(t *testing.T) {
+	dir := writeCache(t, "playwright-1.49.1.tgz")
+	local := filepath.Join(dir, "playwright-1.49.1.tgz")
+	sum, err := fileSHA256(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeWindows(t)
+	f.on("hash", win.FileSHA256(cacheDir+`\playwright-1.49.1.tgz`), sshx.Result{Stdout: sum + "\n"}, sshx.Result{Stdout: "stale\n"})
+	for i, wantUpload := range []bool{false, true} {
+		f.calls = nil
+		if _, err := uploadCached(context.Background(), config.Config{}, local); err != nil {
+			t.Fatalf("uploadCached() error = %v", err)
+		}
+		if uploaded := len(f.calls) == 2; uploaded != wantUpload {
+			t.Errorf("call %d: calls = %q, want upload %v", i, f.calls, wantUpload)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package playwright
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CaptureSpec describes a one-shot page capture. Output fields are file names
+// (not paths); empty ones are skipped, but at least one must be set.
+type CaptureSpec struct {
+	URL        string
+	WaitFor    string // CSS selector to wait for after load
+	Screenshot string
+	PDF        string
+	HAR        string
+	Viewport   string // WIDTHxHEIGHT
+}
+
+// Validate checks the URL, the output names and the viewport.
+func (s CaptureSpec) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q: want an absolute http(s) URL", s.URL)
+	}
+	names := map[string]bool{}
+	for _, name := range []string{s.Screenshot, s.PDF, s.HAR} {
+		if name == "" {
+			continue
+		}
+		if name != filepath.Base(name) || strings.ContainsAny(name, `\/:`) || name == ".." {
+			return fmt.Errorf("invalid output name %q: want a plain file name", name)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate output name %q", name)
+		}
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return errors.New("at least one of screenshot, pdf or har is required")
+	}
+	if s.Viewport != "" {
+		if _, _, err := ParseViewport(s.Viewport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunSpec returns the script run performing the capture.
+func (s CaptureSpec) RunSpec(browser string) (RunSpec, error) {
+	if err := s.Validate(); err != nil {
+		return RunSpec{}, err
+	}
+	flow, err := CaptureJS()
+	if err != nil {
+		return RunSpec{}, err
+	}
+	// JSON is valid JavaScript, and json.Marshal escapes the line separators
+	// that are not, so the options can be prepended verbatim.
+	opts, err := json.Marshal(map[string]string{
+		"url":        s.URL,
+		"waitFor":    s.WaitFor,
+		"screenshot": s.Screenshot,
+		"pdf":        s.PDF,
+	})
+	if err != nil {
+		return RunSpec{}, err
+	}
+	spec := RunSpec{
+		Script:  append([]byte("const opts = "+string(opts)+";\n"), flow...),
+		Browser: browser,
+		HAR:     s.HAR,
+	}
+	if s.Viewport != "" {
+		width, height, _ := ParseViewport(s.Viewport)
+		spec.Viewport = fmt.Sprintf("%dx%d", width, height)
+	}
+	return spec, nil
+}
+
+// ParseViewport parses WIDTHxHEIGHT, e.g. 1920x1080.
+func ParseViewport(value string) (width, height int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+		if err == nil {
+			height, err = strconv.Atoi(h)
+		}
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid viewport %q: want WIDTHxHEIGHT, e.g. 1920x1080", value)
+	}
+	return width, height, nil
+}
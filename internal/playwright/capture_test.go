@@ -0,0 +1,46 @@
+package playwright
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptureSpec_RunSpec(t *testing.T) {
+	spec := CaptureSpec{
+		URL:        "https://intranet.example/report?q='x'",
+		WaitFor:    "#done",
+		Screenshot: "page.png",
+		HAR:        "page.har",
+		Viewport:   "1920X1080",
+	}
+	run, err := spec.RunSpec(BrowserMSEdge)
+	if err != nil {
+		t.Fatalf("RunSpec() error = %v", err)
+	}
+	if run.Browser != BrowserMSEdge || run.HAR != "page.har" || run.Viewport != "1920x1080" {
+		t.Errorf("RunSpec() = %+v", run)
+	}
+	wantPrefix := `const opts = {"pdf":"","screenshot":"page.png","url":"https://intranet.example/report?q='x'","waitFor":"#done"};`
+	if !strings.HasPrefix(string(run.Script), wantPrefix+"\n") {
+		t.Errorf("Script starts with %q, want %q", strings.SplitN(string(run.Script), "\n", 2)[0], wantPrefix)
+	}
+}
+
+func TestCaptureSpec_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec CaptureSpec
+	}{
+		{"no outputs", CaptureSpec{URL: "https://example.com"}},
+		{"relative url", CaptureSpec{URL: "/report", PDF: "a.pdf"}},
+		{"file url", CaptureSpec{URL: "file:///C:/secret.txt", PDF: "a.pdf"}},
+		{"path in name", CaptureSpec{URL: "https://example.com", PDF: `..\a.pdf`}},
+		{"duplicate name", CaptureSpec{URL: "https://example.com", PDF: "a", HAR: "a"}},
+		{"bad viewport", CaptureSpec{URL: "https://example.com", PDF: "a.pdf", Viewport: "1920"}},
+	}
+	for _, tt := range tests {
+		if err := tt.spec.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want error", tt.name)
+		}
+	}
+}
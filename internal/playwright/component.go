@@ -0,0 +1,47 @@
+package playwright
+
+import (
+	"fmt"
+
+	"github.com/alejg/win-automation/internal/component"
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// Component returns the server scripts in InstallDir plus, for each of servers, its
+// scheduled task and firewall rule. Tasks are registered by install-playwright.ps1.
+func Component(cfg config.Config, servers []Server) (component.Component, error) {
+	launch, err := LaunchServerJS()
+	if err != nil {
+		return component.Component{}, err
+	}
+	install, err := InstallPlaywrightPS1()
+	if err != nil {
+		return component.Component{}, err
+	}
+	c := component.Component{
+		Name: "playwright",
+		Dir:  InstallDir,
+		Files: []component.File{
+			{Name: "launch-server.js", Data: launch},
+			{Name: "install-playwright.ps1", Data: install},
+		},
+	}
+	for _, s := range servers {
+		register := fmt.Sprintf("& '%s\\install-playwright.ps1' -Port %d -Browser '%s' -TaskName '%s'", InstallDir, s.Port, s.Browser, s.Task)
+		c.Tasks = append(c.Tasks, component.Task{Name: s.Task, Register: win.PowerShellCommand(register)})
+		c.Firewall = append(c.Firewall, component.FirewallRule{Name: s.FirewallRule(), Port: s.Port})
+	}
+	return c, nil
+}
+
+// Removal returns everything install may have placed on Windows: the install
+// directory (package, browsers, secret) and the task and rule of every browser.
+func Removal(cfg config.Config) component.Component {
+	c := component.Component{Name: "playwright", Dir: InstallDir}
+	for _, s := range AllServers(cfg) {
+		c.Tasks = append(c.Tasks, component.Task{Name: s.Task})
+		c.Firewall = append(c.Firewall, component.FirewallRule{Name: s.FirewallRule(), Port: s.Port})
+	}
+	return c
+}
@@ -2,7 +2,7 @@ package playwright
 
 import "embed"
 
-//go:embed scripts/launch-server.js scripts/install-playwright.ps1
+//go:embed scripts/launch-server.js scripts/install-playwright.ps1 scripts/run-script.js scripts/capture.js
 var Scripts embed.FS
 
 // LaunchServerJS returns the launch-server.js content
@@ -14,3 +14,13 @@ func LaunchServerJS() ([]byte, error) {
 func InstallPlaywrightPS1() ([]byte, error) {
 	return Scripts.ReadFile("scripts/install-playwright.ps1")
 }
+
+// RunScriptJS returns the run-script.js content
+func RunScriptJS() ([]byte, error) {
+	return Scripts.ReadFile("scripts/run-script.js")
+}
+
+// CaptureJS returns the capture.js content
+func CaptureJS() ([]byte, error) {
+	return Scripts.ReadFile("scripts/capture.js")
+}
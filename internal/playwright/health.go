@@ -0,0 +1,190 @@
+package playwright
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health check errors. Check wraps exactly one of them so callers can tell a
+// missing server from a misconfigured one.
+var (
+	ErrNotListening    = errors.New("playwright server not listening")
+	ErrWrongPath       = errors.New("playwright ws path rejected")
+	ErrVersionMismatch = errors.New("playwright version mismatch")
+	ErrProtocol        = errors.New("playwright protocol error")
+)
+
+// defaultCheckTimeout bounds a check whose context has no deadline.
+const defaultCheckTimeout = 10 * time.Second
+
+// Endpoint addresses a Playwright launchServer WebSocket.
+type Endpoint struct {
+	Host string
+	Port int
+	Path string // ws path, with or without the leading slash
+	// ClientVersion is announced as the Playwright client version (major.minor or
+	// major.minor.patch). When set, the server rejects mismatching versions; when
+	// empty the server skips the comparison.
+	ClientVersion string
+}
+
+// URL returns the ws:// URL of the endpoint. It contains the ws path secret and
+// must not be logged; use Addr for logs.
+func (e Endpoint) URL() string {
+	return "ws://" + e.Addr() + e.path()
+}
+
+// Addr returns host:port of the endpoint.
+func (e Endpoint) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+func (e Endpoint) path() string {
+	return "/" + strings.TrimPrefix(e.Path, "/")
+}
+
+// BrowserInfo is what a healthy server reports about its pre-launched browser.
+type BrowserInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Check dials the endpoint, completes the WebSocket handshake and sends the
+// Playwright initialize call, returning the browser the server exposes.
+func Check(ctx context.Context, ep Endpoint) (BrowserInfo, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCheckTimeout)
+		defer cancel()
+	}
+
+	addr := ep.Addr()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return BrowserInfo{}, fmt.Errorf("%w: %s: %v", ErrNotListening, addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	header := http.Header{}
+	if ep.ClientVersion != "" {
+		version := ep.ClientVersion
+		if strings.Count(version, ".") == 1 {
+			version += ".0"
+		}
+		header.Set("User-Agent", "Playwright/"+version+" (win-automation)")
+	} else {
+		header.Set("User-Agent", "win-automation")
+	}
+
+	ws, err := wsHandshake(conn, addr, ep.path(), header)
+	if err != nil {
+		return BrowserInfo{}, classifyHandshake(err)
+	}
+	defer ws.close()
+
+	return initialize(ws)
+}
+
+// initialize performs the Playwright initialize call and collects the Browser
+// object the server creates for its pre-launched browser.
+func initialize(ws *wsConn) (BrowserInfo, error) {
+	req, _ := json.Marshal(map[string]any{
+		"id":       1,
+		"guid":     "",
+		"method":   "initialize",
+		"params":   map[string]any{"sdkLanguage": "javascript"},
+		"metadata": map[string]any{"wallTime": time.Now().UnixMilli()},
+	})
+	if err := ws.writeText(req); err != nil {
+		return BrowserInfo{}, fmt.Errorf("%w: send initialize: %v", ErrProtocol, err)
+	}
+
+	var info BrowserInfo
+	for {
+		data, err := ws.readMessage()
+		if err != nil {
+			var ce *closeError
+			if errors.As(err, &ce) && isVersionMismatch(ce.reason) {
+				return BrowserInfo{}, fmt.Errorf("%w: %s", ErrVersionMismatch, compact(ce.reason))
+			}
+			return BrowserInfo{}, fmt.Errorf("%w: %v", ErrProtocol, err)
+		}
+
+		var msg struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Type        string      `json:"type"`
+				Initializer BrowserInfo `json:"initializer"`
+			} `json:"params"`
+			Error *struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return BrowserInfo{}, fmt.Errorf("%w: not a playwright message: %v", ErrProtocol, err)
+		}
+
+		switch {
+		case msg.Method == "__create__" && msg.Params.Type == "Browser":
+			info = msg.Params.Initializer
+		case msg.ID == 1 && msg.Error != nil:
+			if isVersionMismatch(msg.Error.Error.Message) {
+				return BrowserInfo{}, fmt.Errorf("%w: %s", ErrVersionMismatch, compact(msg.Error.Error.Message))
+			}
+			return BrowserInfo{}, fmt.Errorf("%w: initialize: %s", ErrProtocol, compact(msg.Error.Error.Message))
+		case msg.ID == 1:
+			if info.Name == "" {
+				return BrowserInfo{}, fmt.Errorf("%w: server reported no browser", ErrProtocol)
+			}
+			return info, nil
+		}
+	}
+}
+
+// classifyHandshake maps a failed upgrade to a health error. The ws path is a
+// secret, so it is never included in the message.
+func classifyHandshake(err error) error {
+	var he *handshakeError
+	if !errors.As(err, &he) {
+		return fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+	switch {
+	case he.status == http.StatusUpgradeRequired || he.status == http.StatusPreconditionRequired || isVersionMismatch(he.body):
+		return fmt.Errorf("%w: %s", ErrVersionMismatch, compact(he.body))
+	case he.status == http.StatusBadRequest || he.status == http.StatusNotFound || he.status == http.StatusForbidden:
+		return fmt.Errorf("%w: server returned http %d", ErrWrongPath, he.status)
+	default:
+		return fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+}
+
+func isVersionMismatch(text string) bool {
+	return strings.Contains(strings.ToLower(text), "version mismatch")
+}
+
+// compact flattens a multi-line server message onto one line. Playwright wraps the
+// version mismatch message in an ASCII box, which is stripped.
+func compact(text string) string {
+	var parts []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "║╔╗╚╝═ ")
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
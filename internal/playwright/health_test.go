@@ -0,0 +1,107 @@
+package playwright
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeServer emulates a Playwright launchServer on wsPath. It rejects clients whose
+// User-Agent announces a version other than serverVersion.
+func fakeServer(t *testing.T, wsPath, serverVersion string) Endpoint {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != wsPath {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if ua := r.Header.Get("User-Agent"); strings.HasPrefix(ua, "Playwright/") && !strings.HasPrefix(ua, "Playwright/"+serverVersion+".") {
+			http.Error(w, "Playwright version mismatch:\n  - server version: v"+serverVersion, http.StatusPreconditionRequired)
+			return
+		}
+
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+
+		ws := &wsConn{conn: conn, br: brw.Reader}
+		msg, err := ws.readMessage()
+		if err != nil || !strings.Contains(string(msg), `"initialize"`) {
+			t.Errorf("expected initialize, got %q (%v)", msg, err)
+			return
+		}
+		for _, reply := range []string{
+			`{"guid":"","method":"__create__","params":{"type":"Browser","initializer":{"version":"131.0.6778.33","name":"chromium"},"guid":"browser@1"}}`,
+			`{"id":1,"result":{"playwright":{"guid":"Playwright"}}}`,
+		} {
+			// Server frames are unmasked.
+			frame := []byte{0x80 | opText, 126, byte(len(reply) >> 8), byte(len(reply))}
+			if _, err := conn.Write(append(frame, reply...)); err != nil {
+				t.Errorf("write: %v", err)
+				return
+			}
+		}
+		ws.readMessage() // wait for the client's close frame
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+	return Endpoint{Host: host, Port: p, Path: wsPath}
+}
+
+func TestCheck(t *testing.T) {
+	ep := fakeServer(t, "/secret", "1.49")
+
+	t.Run("ok", func(t *testing.T) {
+		ep := ep
+		ep.Path = "secret"
+		ep.ClientVersion = "1.49"
+		info, err := Check(context.Background(), ep)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if info.Name != "chromium" || info.Version != "131.0.6778.33" {
+			t.Errorf("Check() = %+v", info)
+		}
+	})
+
+	t.Run("wrong path", func(t *testing.T) {
+		ep := ep
+		ep.Path = "/other"
+		if _, err := Check(context.Background(), ep); !errors.Is(err, ErrWrongPath) {
+			t.Errorf("Check() error = %v, want ErrWrongPath", err)
+		}
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		ep := ep
+		ep.ClientVersion = "1.48"
+		if _, err := Check(context.Background(), ep); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("Check() error = %v, want ErrVersionMismatch", err)
+		}
+	})
+
+	t.Run("not listening", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if _, err := Check(context.Background(), Endpoint{Host: "127.0.0.1", Port: port}); !errors.Is(err, ErrNotListening) {
+			t.Errorf("Check() error = %v, want ErrNotListening", err)
+		}
+	})
+}
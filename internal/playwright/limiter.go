@@ -0,0 +1,124 @@
+package playwright
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/alejg/win-automation/internal/metrics"
+	"github.com/google/uuid"
+)
+
+// ProfilesDir holds the storage state (cookies and local storage) of named
+// profiles on Windows, one <name>.json per profile.
+const ProfilesDir = InstallDir + `\profiles`
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidateProfile checks a profile name; it becomes a file name on Windows.
+func ValidateProfile(name string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile %q (letters, digits, '.', '_' and '-', up to 64)", name)
+	}
+	return nil
+}
+
+// ProfilePath returns where the named profile's storage state is kept.
+func ProfilePath(name string) string {
+	return ProfilesDir + `\` + name + ".json"
+}
+
+// Limiter caps the Playwright jobs one worker runs at once and serializes its jobs
+// that name the same profile. It is local to the worker process: nothing is kept
+// warm or reserved on Windows, and every run opens its own browser context in the
+// server's running browser. Workers on other hosts do not see its profile locks;
+// a hatchet.concurrency_groups limit keyed on profile covers those.
+type Limiter struct {
+	slots   chan struct{}
+	metrics *metrics.Metrics
+
+	mu       sync.Mutex
+	profiles map[string]chan struct{}
+}
+
+// Slot is a place in the Limiter held by one job.
+type Slot struct {
+	ID      string        // identifies the slot in logs and artifacts only
+	Profile string        // empty for an isolated run
+	Wait    time.Duration // time spent waiting for the slot
+
+	limiter *Limiter
+	profile chan struct{}
+	once    sync.Once
+}
+
+// NewLimiter returns a limiter of size slots reporting to m.
+func NewLimiter(size int, m *metrics.Metrics) *Limiter {
+	return &Limiter{
+		slots:    make(chan struct{}, max(size, 1)),
+		metrics:  m,
+		profiles: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire blocks until a slot (and, when named, the profile) is free or ctx is
+// done.
+func (l *Limiter) Acquire(ctx context.Context, profile string) (*Slot, error) {
+	if profile != "" {
+		if err := ValidateProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	start := time.Now()
+	l.metrics.AddGauge(metrics.PlaywrightSessionsWaiting, 1)
+	defer l.metrics.AddGauge(metrics.PlaywrightSessionsWaiting, -1)
+
+	s := &Slot{ID: uuid.NewString()[:8], Profile: profile, limiter: l}
+	// The profile comes first so a job waiting for it does not hold a slot.
+	if profile != "" {
+		s.profile = l.profileLock(profile)
+		select {
+		case s.profile <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for profile %s: %w", profile, ctx.Err())
+		}
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		if s.profile != nil {
+			<-s.profile
+		}
+		return nil, fmt.Errorf("wait for browser slot: %w", ctx.Err())
+	}
+
+	s.Wait = time.Since(start)
+	l.metrics.Inc(metrics.PlaywrightSessions)
+	l.metrics.Add(metrics.PlaywrightLeaseWaitMs, uint64(s.Wait.Milliseconds()))
+	l.metrics.AddGauge(metrics.PlaywrightSessionsActive, 1)
+	return s, nil
+}
+
+// Release frees the slot. It is safe to call more than once.
+func (s *Slot) Release() {
+	s.once.Do(func() {
+		<-s.limiter.slots
+		if s.profile != nil {
+			<-s.profile
+		}
+		s.limiter.metrics.AddGauge(metrics.PlaywrightSessionsActive, -1)
+	})
+}
+
+func (l *Limiter) profileLock(name string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, ok := l.profiles[name]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		l.profiles[name] = lock
+	}
+	return lock
+}
@@ -0,0 +1,93 @@
+package playwright
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alejg/win-automation/internal/metrics"
+)
+
+func TestLimiter_CapsSessions(t *testing.T) {
+	m := metrics.NewMetrics()
+	limiter := NewLimiter(2, m)
+	ctx := context.Background()
+
+	a, err := limiter.Acquire(ctx, "")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	b, err := limiter.Acquire(ctx, "")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if a.ID == b.ID {
+		t.Errorf("slots share ID %s", a.ID)
+	}
+	if got := m.Value(metrics.PlaywrightSessionsActive); got != 2 {
+		t.Errorf("active = %d, want 2", got)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(short, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() on a full limiter error = %v, want deadline exceeded", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		a.Release()
+	}()
+	c, err := limiter.Acquire(ctx, "")
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	if c.Wait <= 0 {
+		t.Errorf("Wait = %v, want the time spent blocked", c.Wait)
+	}
+	a.Release() // second release is a no-op
+	b.Release()
+	c.Release()
+
+	if got := m.Value(metrics.PlaywrightSessionsActive); got != 0 {
+		t.Errorf("active = %d, want 0", got)
+	}
+	if got := m.Value(metrics.PlaywrightSessions); got != 3 {
+		t.Errorf("slots = %d, want 3", got)
+	}
+	if got := m.Value(metrics.PlaywrightSessionsWaiting); got != 0 {
+		t.Errorf("waiting = %d, want 0", got)
+	}
+}
+
+func TestLimiter_ProfileIsExclusive(t *testing.T) {
+	limiter := NewLimiter(4, metrics.NewMetrics())
+	ctx := context.Background()
+
+	first, err := limiter.Acquire(ctx, "crm")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if other, err := limiter.Acquire(ctx, "erp"); err != nil {
+		t.Fatalf("Acquire(erp) error = %v", err)
+	} else {
+		other.Release()
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(short, "crm"); err == nil {
+		t.Fatal("Acquire(crm) while leased error = nil, want timeout")
+	}
+	first.Release()
+	second, err := limiter.Acquire(ctx, "crm")
+	if err != nil {
+		t.Fatalf("Acquire(crm) after release error = %v", err)
+	}
+	second.Release()
+
+	if _, err := limiter.Acquire(ctx, `..\evil`); err == nil {
+		t.Error("Acquire() with an invalid profile error = nil, want error")
+	}
+}
@@ -0,0 +1,72 @@
+package playwright
+
+import "fmt"
+
+// TraceFile is the name the runner gives the trace in a run's output.
+const TraceFile = "trace.zip"
+
+// Policy decides when a run keeps its trace, video or final screenshot.
+type Policy string
+
+const (
+	PolicyOff Policy = "off"
+	PolicyOn  Policy = "on"
+	// PolicyRetainOnFailure records every run but keeps the output only when the
+	// flow fails; for screenshots, the final screenshot is taken only then.
+	PolicyRetainOnFailure Policy = "retain-on-failure"
+)
+
+// ParsePolicy accepts off, on and retain-on-failure; empty means unset.
+func ParsePolicy(value string) (Policy, error) {
+	switch p := Policy(value); p {
+	case "", PolicyOff, PolicyOn, PolicyRetainOnFailure:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid policy %q (want %s, %s or %s)", value, PolicyOff, PolicyOn, PolicyRetainOnFailure)
+}
+
+// Policies selects the debugging output of a run. Empty fields fall back to
+// playwright.trace, playwright.video and playwright.screenshot.
+type Policies struct {
+	Trace      Policy
+	Video      Policy
+	Screenshot Policy
+}
+
+// Validate checks every policy.
+func (p Policies) Validate() error {
+	for _, policy := range []Policy{p.Trace, p.Video, p.Screenshot} {
+		if _, err := ParsePolicy(string(policy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDefaults fills unset policies from cfg.
+func (p Policies) withDefaults(trace, video, screenshot string) Policies {
+	if p.Trace == "" {
+		p.Trace = Policy(trace)
+	}
+	if p.Video == "" {
+		p.Video = Policy(video)
+	}
+	if p.Screenshot == "" {
+		p.Screenshot = Policy(screenshot)
+	}
+	return p
+}
+
+// args returns the runner arguments for the policies that are not off.
+func (p Policies) args() string {
+	var args string
+	for _, policy := range []struct {
+		name  string
+		value Policy
+	}{{"trace", p.Trace}, {"video", p.Video}, {"screenshot", p.Screenshot}} {
+		if policy.value != "" && policy.value != PolicyOff {
+			args += fmt.Sprintf(" '--%s=%s'", policy.name, policy.value)
+		}
+	}
+	return args
+}
@@ -0,0 +1,30 @@
+package playwright
+
+import "testing"
+
+func TestParsePolicy(t *testing.T) {
+	for _, value := range []string{"", "off", "on", "retain-on-failure"} {
+		if _, err := ParsePolicy(value); err != nil {
+			t.Errorf("ParsePolicy(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"true", "On", "retain-on-success"} {
+		if _, err := ParsePolicy(value); err == nil {
+			t.Errorf("ParsePolicy(%q) error = nil, want error", value)
+		}
+	}
+}
+
+func TestPolicies_Args(t *testing.T) {
+	p := Policies{Trace: PolicyRetainOnFailure}.withDefaults("on", "off", "on")
+	if p.Trace != PolicyRetainOnFailure || p.Video != PolicyOff || p.Screenshot != PolicyOn {
+		t.Fatalf("withDefaults() = %+v", p)
+	}
+	want := " '--trace=retain-on-failure' '--screenshot=on'"
+	if got := p.args(); got != want {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+	if got := (Policies{}).args(); got != "" {
+		t.Errorf("args() of unset policies = %q, want empty", got)
+	}
+}
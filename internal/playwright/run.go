@@ -0,0 +1,193 @@
+package playwright
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/logx"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+	"github.com/google/uuid"
+)
+
+// InstallDir holds the server, runner and ws path secret on Windows.
+const InstallDir = `C:\ProgramData\win-automation\playwright`
+
+// runsDir stages script runs; each run gets its own subdirectory.
+const runsDir = InstallDir + `\runs`
+
+// ErrScriptFailed is returned when the flow itself throws. Files produced before
+// the failure (including the trace) are still collected.
+var ErrScriptFailed = errors.New("playwright script failed")
+
+// The SSH calls are replaced in tests that run without a Windows host.
+var (
+	runSSH       = sshx.Run
+	uploadFile   = sshx.Upload
+	downloadFile = sshx.Download
+)
+
+// RunSpec describes a flow to run on Windows.
+type RunSpec struct {
+	Script  []byte // flow module source
+	Browser string // defaults to cfg.PlaywrightBrowser
+	Policies
+	// HAR records the browser context's network traffic to this file name.
+	HAR string
+	// Viewport is the page size as WIDTHxHEIGHT; empty keeps Playwright's default.
+	Viewport string
+	// Profile names the storage state the context starts from and, when the flow
+	// succeeds, saves back to; empty runs with clean cookies and storage.
+	Profile string
+}
+
+// RunResult lists the files a run produced, relative to the local output directory.
+type RunResult struct {
+	Browser string   `json:"browser"`
+	Files   []string `json:"files"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// RunScript uploads the runner and the flow, runs it with node on Windows and
+// downloads the files it produced (screenshots, trace.zip, videos) into localDir.
+// The flow connects to the browser's server when one is configured and launches
+// the browser locally otherwise. Traces and videos under retain-on-failure are
+// discarded on Windows when the flow succeeds. The remote staging directory is
+// removed afterwards.
+func RunScript(ctx context.Context, cfg config.Config, spec RunSpec, localDir string) (RunResult, error) {
+	if spec.Browser == "" {
+		spec.Browser = cfg.PlaywrightBrowser
+	}
+	if err := ValidateBrowser(spec.Browser); err != nil {
+		return RunResult{}, err
+	}
+	if err := spec.Policies.Validate(); err != nil {
+		return RunResult{}, err
+	}
+	if spec.Profile != "" {
+		if err := ValidateProfile(spec.Profile); err != nil {
+			return RunResult{}, err
+		}
+	}
+	spec.Policies = spec.Policies.withDefaults(cfg.PlaywrightTrace, cfg.PlaywrightVideo, cfg.PlaywrightScreenshot)
+
+	runner, err := RunScriptJS()
+	if err != nil {
+		return RunResult{}, err
+	}
+	remoteDir := runsDir + `\` + uuid.NewString()[:8]
+	outDir := remoteDir + `\out`
+	flowPath := remoteDir + `\flow.js`
+	runnerPath := InstallDir + `\run-script.js`
+
+	mkdir := fmt.Sprintf("New-Item -ItemType Directory -Force -Path '%s' | Out-Null", outDir)
+	if _, err := runSSH(ctx, cfg, win.PowerShellCommand(mkdir)); err != nil {
+		return RunResult{}, fmt.Errorf("prepare run directory: %w", err)
+	}
+	defer cleanupRun(cfg, remoteDir)
+
+	if err := upload(ctx, cfg, runner, runnerPath); err != nil {
+		return RunResult{}, fmt.Errorf("upload runner: %w", err)
+	}
+	if err := upload(ctx, cfg, spec.Script, flowPath); err != nil {
+		return RunResult{}, fmt.Errorf("upload script: %w", err)
+	}
+
+	cmd := fmt.Sprintf("node '%s' '--script=%s' '--out=%s' '--browser=%s'",
+		runnerPath, flowPath, outDir, spec.Browser)
+	if Configured(cfg, spec.Browser) {
+		cmd += fmt.Sprintf(" '--port=%d'", ServerFor(cfg, spec.Browser).Port)
+	}
+	cmd += spec.Policies.args()
+	if spec.HAR != "" {
+		cmd += fmt.Sprintf(" '--har=%s'", spec.HAR)
+	}
+	if spec.Viewport != "" {
+		cmd += fmt.Sprintf(" '--viewport=%s'", spec.Viewport)
+	}
+	if spec.Profile != "" {
+		cmd += fmt.Sprintf(" '--profile=%s'", ProfilePath(spec.Profile))
+	}
+	res, runErr := runSSH(ctx, cfg, win.PowerShellCommand(cmd))
+	result, parseErr := parseRunOutput(res.Stdout)
+	if parseErr != nil {
+		if runErr != nil {
+			return RunResult{}, fmt.Errorf("run script: %w: %s", runErr, strings.TrimSpace(res.Stderr))
+		}
+		return RunResult{}, parseErr
+	}
+
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return result, err
+	}
+	for _, name := range result.Files {
+		if err := downloadFile(ctx, cfg, outDir+`\`+name, filepath.Join(localDir, name)); err != nil {
+			return result, fmt.Errorf("download %s: %w", name, err)
+		}
+	}
+
+	if result.Error != "" {
+		return result, fmt.Errorf("%w: %s", ErrScriptFailed, firstLine(result.Error))
+	}
+	if runErr != nil {
+		return result, fmt.Errorf("run script: %w: %s", runErr, strings.TrimSpace(res.Stderr))
+	}
+	return result, nil
+}
+
+// parseRunOutput reads the runner's JSON summary from the last non-empty stdout
+// line; anything the flow logs comes before it. Only plain file names are accepted
+// so a flow cannot direct downloads outside the output directory.
+func parseRunOutput(stdout string) (RunResult, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var result RunResult
+	if err := json.Unmarshal([]byte(last), &result); err != nil {
+		return RunResult{}, fmt.Errorf("parse runner output: %w", err)
+	}
+	for _, name := range result.Files {
+		if name == "" || name != filepath.Base(name) || strings.ContainsAny(name, `\/:`) || name == ".." {
+			return RunResult{}, fmt.Errorf("runner reported invalid file name %q", name)
+		}
+	}
+	return result, nil
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
+
+// cleanupRun removes the remote staging directory. It runs after the caller's
+// context may have expired, so it uses a fresh one bounded by cfg.Timeout.
+func cleanupRun(cfg config.Config, remoteDir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	cmd := fmt.Sprintf("Remove-Item -Recurse -Force -Path '%s' -ErrorAction SilentlyContinue", remoteDir)
+	if _, err := runSSH(ctx, cfg, win.PowerShellCommand(cmd)); err != nil {
+		logx.Error("playwright", "run", "remote cleanup failed", err, logx.Field{Key: "path", Value: remoteDir})
+	}
+}
+
+func upload(ctx context.Context, cfg config.Config, data []byte, remotePath string) error {
+	tmp, err := os.CreateTemp("", "playwright-run-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return uploadFile(ctx, cfg, tmp.Name(), remotePath)
+}
@@ -0,0 +1,155 @@
+package playwright
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+func TestParseRunOutput(t *testing.T) {
+	stdout := "flow log line\n{\"files\":[\"home.png\",\"trace.zip\"],\"browser\":\"msedge\",\"error\":null}\n"
+	got, err := parseRunOutput(stdout)
+	if err != nil {
+		t.Fatalf("parseRunOutput() error = %v", err)
+	}
+	if got.Browser != "msedge" || len(got.Files) != 2 || got.Files[1] != "trace.zip" || got.Error != "" {
+		t.Errorf("parseRunOutput() = %+v", got)
+	}
+
+	for _, bad := range []string{
+		"not json",
+		`{"files":["..\\evil.png"]}`,
+		`{"files":["sub/evil.png"]}`,
+		`{"files":[".."]}`,
+	} {
+		if _, err := parseRunOutput(bad); err == nil {
+			t.Errorf("parseRunOutput(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+// fakePlaywrightJS stands in for the playwright package: its context records
+// the storage state it was created from and writes {"from": ...} as its state.
+const fakePlaywrightJS = `
+const fs = require('fs');
+const page = { screenshot: async () => {}, video: () => null };
+const browser = {
+  newContext: async options => ({
+    on() {},
+    newPage: async () => page,
+    storageState: async ({ path }) => fs.writeFileSync(path, JSON.stringify({ from: options.storageState || null })),
+    close: async () => {},
+  }),
+  close: async () => {},
+};
+const engine = { launch: async () => browser, connect: async () => browser };
+module.exports = { chromium: engine, firefox: engine, webkit: engine };
+`
+
+// runScriptJS runs run-script.js with node against the fake playwright package
+// and a flow that throws when fail is set.
+func runScriptJS(t *testing.T, profile string, fail bool) RunResult {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	dir := t.TempDir()
+	runner, err := RunScriptJS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow := "module.exports = async () => {};"
+	if fail {
+		flow = "module.exports = async () => { throw new Error('boom'); };"
+	}
+	files := map[string]string{
+		"run-script.js":                        string(runner),
+		"flow.js":                              flow,
+		"node_modules/playwright/index.js":     fakePlaywrightJS,
+		"node_modules/playwright/package.json": `{"name":"playwright","main":"index.js"}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(node, filepath.Join(dir, "run-script.js"),
+		"--script="+filepath.Join(dir, "flow.js"), "--out="+filepath.Join(dir, "out"), "--profile="+profile)
+	out, _ := cmd.Output()
+	result, err := parseRunOutput(string(out))
+	if err != nil {
+		t.Fatalf("run-script.js output %q: %v", out, err)
+	}
+	return result
+}
+
+func TestRunScriptJS_SavesProfile(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "profiles", "crm.json")
+
+	if result := runScriptJS(t, profile, true); result.Error == "" {
+		t.Fatal("failing flow reported no error")
+	}
+	if _, err := os.Stat(profile); !os.IsNotExist(err) {
+		t.Fatalf("failing flow saved the profile (stat error %v)", err)
+	}
+
+	for i, wantFrom := range []any{nil, profile} {
+		if result := runScriptJS(t, profile, false); result.Error != "" {
+			t.Fatalf("run %d error = %s", i, result.Error)
+		}
+		data, err := os.ReadFile(profile)
+		if err != nil {
+			t.Fatalf("run %d did not save the profile: %v", i, err)
+		}
+		var state map[string]any
+		if err := json.Unmarshal(data, &state); err != nil {
+			t.Fatalf("run %d saved invalid state %q: %v", i, data, err)
+		}
+		if state["from"] != wantFrom {
+			t.Errorf("run %d started from %v, want %v", i, state["from"], wantFrom)
+		}
+	}
+	leftovers, _ := filepath.Glob(profile + ".*.tmp")
+	if len(leftovers) > 0 {
+		t.Errorf("temporary state files left behind: %v", leftovers)
+	}
+}
+
+func TestRunScript_PassesProfilePath(t *testing.T) {
+	var command string
+	origRun, origUpload := runSSH, uploadFile
+	runSSH = func(_ context.Context, _ config.Config, script string) (sshx.Result, error) {
+		if strings.Contains(script, "run-script.js") {
+			command = script
+			return sshx.Result{Stdout: `{"files":[],"browser":"chromium","error":null}`}, nil
+		}
+		return sshx.Result{}, nil
+	}
+	uploadFile = func(context.Context, config.Config, string, string) error { return nil }
+	t.Cleanup(func() { runSSH, uploadFile = origRun, origUpload })
+
+	spec := RunSpec{Script: []byte("module.exports = async () => {};"), Browser: BrowserChromium, Profile: "crm"}
+	if _, err := RunScript(context.Background(), config.Config{}, spec, t.TempDir()); err != nil {
+		t.Fatalf("RunScript() error = %v", err)
+	}
+	// PowerShellCommand escapes the backslashes of the paths.
+	for strings.Contains(command, `\\`) {
+		command = strings.ReplaceAll(command, `\\`, `\`)
+	}
+	if want := "'--profile=" + ProfilePath("crm") + "'"; !strings.Contains(command, want) {
+		t.Errorf("runner command %q lacks %s", command, want)
+	}
+}
@@ -0,0 +1,16 @@
+// Capture flow for `playwright capture`. win-automation prepends
+// `const opts = {...};` with url, waitFor, screenshot and pdf.
+const path = require('path');
+
+module.exports = async ({ page, outDir }) => {
+  await page.goto(opts.url, { waitUntil: 'load' });
+  if (opts.waitFor) {
+    await page.waitForSelector(opts.waitFor);
+  }
+  if (opts.screenshot) {
+    await page.screenshot({ path: path.join(outDir, opts.screenshot), fullPage: true });
+  }
+  if (opts.pdf) {
+    await page.pdf({ path: path.join(outDir, opts.pdf), printBackground: true });
+  }
+};
@@ -1,20 +1,26 @@
 param(
-    [Parameter(Mandatory=$true)]
+    # Optional: win-automation uploads ws_path.txt itself so the secret never
+    # appears on a command line.
     [string]$WsPath,
-    [int]$Port = 9323
+    [int]$Port = 9323,
+    [ValidateSet('chromium', 'msedge', 'firefox', 'webkit')]
+    [string]$Browser = 'chromium',
+    [string]$TaskName = 'WinAutomation-Playwright'
 )
 
 $installDir = "C:\ProgramData\win-automation\playwright"
 New-Item -ItemType Directory -Force -Path $installDir | Out-Null
 
 # Write ws_path.txt
-Set-Content -Path "$installDir\ws_path.txt" -Value $WsPath
+if ($WsPath) {
+    Set-Content -Path "$installDir\ws_path.txt" -Value $WsPath -NoNewline
+}
 
-# Create scheduled task
-$taskName = "WinAutomation-Playwright"
+# Create scheduled task (one per browser server)
+$taskName = $TaskName
 $nodePath = "C:\Program Files\nodejs\node.exe"
 $scriptPath = "$installDir\launch-server.js"
-$action = New-ScheduledTaskAction -Execute $nodePath -Argument "`"$scriptPath`" --host=0.0.0.0 --port=$Port"
+$action = New-ScheduledTaskAction -Execute $nodePath -Argument "`"$scriptPath`" --host=0.0.0.0 --port=$Port --browser=$Browser"
 $trigger = New-ScheduledTaskTrigger -AtStartup
 $principal = New-ScheduledTaskPrincipal -UserId "SYSTEM" -RunLevel Highest
 $settings = New-ScheduledTaskSettingsSet -AllowStartIfOnBatteries -DontStopIfGoingOnBatteries
@@ -22,4 +28,4 @@ $settings = New-ScheduledTaskSettingsSet -AllowStartIfOnBatteries -DontStopIfGoi
 Unregister-ScheduledTask -TaskName $taskName -Confirm:$false -ErrorAction SilentlyContinue
 Register-ScheduledTask -TaskName $taskName -Action $action -Trigger $trigger -Principal $principal -Settings $settings
 
-Write-Host "state=installed task=$taskName port=$Port"
+Write-Host "state=installed task=$taskName browser=$Browser port=$Port"
@@ -1,20 +1,37 @@
 // Playwright launchServer for Windows
-const { chromium } = require('playwright');
 const fs = require('fs');
 const path = require('path');
 
+// Browsers pinned by `playwright install --version` live next to this script, so
+// the server (SYSTEM) and scripts (SSH user) share them.
+const browsersDir = path.join(__dirname, 'browsers');
+if (!process.env.PLAYWRIGHT_BROWSERS_PATH && fs.existsSync(browsersDir)) {
+  process.env.PLAYWRIGHT_BROWSERS_PATH = browsersDir;
+}
+const { chromium, firefox, webkit } = require('playwright');
+
 const args = process.argv.slice(2);
 const host = args.find(a => a.startsWith('--host='))?.split('=')[1] || '0.0.0.0';
 const port = parseInt(args.find(a => a.startsWith('--port='))?.split('=')[1] || '9323');
+const browser = args.find(a => a.startsWith('--browser='))?.split('=')[1] || 'chromium';
+
+// msedge is the Edge channel of the chromium engine.
+const engines = { chromium, msedge: chromium, firefox, webkit };
 
 const wsPathFile = path.join(__dirname, 'ws_path.txt');
 const wsPath = fs.existsSync(wsPathFile) ? fs.readFileSync(wsPathFile, 'utf8').trim() : undefined;
 
 (async () => {
-  const server = await chromium.launchServer({
+  const engine = engines[browser];
+  if (!engine) {
+    console.error(`unsupported browser: ${browser}`);
+    process.exit(2);
+  }
+  const server = await engine.launchServer({
     host,
     port,
     wsPath,
+    ...(browser === 'msedge' ? { channel: 'msedge' } : {}),
   });
-  console.log(`Playwright server started: ${server.wsEndpoint()}`);
+  console.log(`Playwright ${browser} server started on port ${port}`);
 })();
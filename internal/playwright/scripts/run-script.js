@@ -0,0 +1,125 @@
+// Runs a user flow against a local Playwright server (or a locally launched
+// browser) and reports the produced files as JSON on the last stdout line.
+//
+// The flow module exports an async function receiving
+// { browser, context, page, outDir, screenshot(name) }.
+const fs = require('fs');
+const path = require('path');
+
+// Browsers pinned by `playwright install --version` live next to this script, so
+// the server (SYSTEM) and scripts (SSH user) share them.
+const browsersDir = path.join(__dirname, 'browsers');
+if (!process.env.PLAYWRIGHT_BROWSERS_PATH && fs.existsSync(browsersDir)) {
+  process.env.PLAYWRIGHT_BROWSERS_PATH = browsersDir;
+}
+const { chromium, firefox, webkit } = require('playwright');
+
+const args = process.argv.slice(2);
+const arg = (name, def) => {
+  const found = args.find(a => a.startsWith(`--${name}=`));
+  return found ? found.slice(name.length + 3) : def;
+};
+
+const scriptPath = arg('script');
+const outDir = arg('out');
+const browserName = arg('browser', 'chromium');
+const port = arg('port');
+// Policies: off, on or retain-on-failure (kept only when the flow throws).
+const trace = arg('trace', 'off');
+const video = arg('video', 'off');
+const finalScreenshot = arg('screenshot', 'off');
+const har = arg('har');
+const viewport = arg('viewport');
+// A named profile's storage state: loaded into the context and saved back when the
+// flow succeeds. Without it every run starts with empty cookies and storage.
+const profile = arg('profile');
+
+const wsPathFile = path.join(__dirname, 'ws_path.txt');
+const wsPath = fs.existsSync(wsPathFile) ? fs.readFileSync(wsPathFile, 'utf8').trim() : '';
+
+const engines = { chromium, msedge: chromium, firefox, webkit };
+
+// With --port the run reuses the browser behind that local launchServer; without
+// it the browser is launched for the run.
+const videoDir = path.join(outDir, '.video');
+
+async function openBrowser() {
+  const engine = engines[browserName];
+  if (!engine) throw new Error(`unsupported browser ${browserName}`);
+  if (port) {
+    return engine.connect(`ws://127.0.0.1:${port}/${wsPath}`);
+  }
+  return engine.launch(browserName === 'msedge' ? { channel: 'msedge' } : {});
+}
+
+// saveVideos copies the recording of every page into outDir as video.webm,
+// video-2.webm, ... in page order. saveAs also works when the browser runs behind
+// a server, where recordings are not written to videoDir directly.
+async function saveVideos(pages) {
+  const videos = pages.map(p => p.video()).filter(Boolean);
+  for (const [i, v] of videos.entries()) {
+    await v.saveAs(path.join(outDir, i === 0 ? 'video.webm' : `video-${i + 1}.webm`)).catch(() => {});
+    await v.delete().catch(() => {});
+  }
+  fs.rmSync(videoDir, { recursive: true, force: true });
+}
+
+(async () => {
+  fs.mkdirSync(outDir, { recursive: true });
+  let error = null;
+  let browser;
+  let context;
+  let page;
+  const pages = [];
+  try {
+    browser = await openBrowser();
+    const options = {};
+    if (har) options.recordHar = { path: path.join(outDir, har) };
+    if (video !== 'off') options.recordVideo = { dir: videoDir };
+    if (viewport) {
+      const [width, height] = viewport.split('x').map(Number);
+      options.viewport = { width, height };
+    }
+    if (profile && fs.existsSync(profile)) options.storageState = profile;
+    context = await browser.newContext(options);
+    context.on('page', p => pages.push(p));
+    if (trace !== 'off') {
+      await context.tracing.start({ screenshots: true, snapshots: true, sources: true });
+    }
+    page = await context.newPage();
+    const flow = require(path.resolve(scriptPath));
+    const screenshot = name => page.screenshot({ path: path.join(outDir, name || 'screenshot.png'), fullPage: true });
+    await flow({ browser, context, page, outDir, screenshot });
+    if (profile) {
+      // Written aside and renamed so a run starting meanwhile never reads half a file.
+      fs.mkdirSync(path.dirname(profile), { recursive: true });
+      const tmp = `${profile}.${process.pid}.tmp`;
+      await context.storageState({ path: tmp });
+      fs.renameSync(tmp, profile);
+    }
+  } catch (err) {
+    error = String(err && err.stack || err);
+  } finally {
+    if (page && (finalScreenshot === 'on' || finalScreenshot === 'retain-on-failure' && error)) {
+      await page.screenshot({ path: path.join(outDir, 'final.png'), fullPage: true }).catch(() => {});
+    }
+    if (context && trace !== 'off') {
+      await context.tracing.stop({ path: path.join(outDir, 'trace.zip') }).catch(() => {});
+    }
+    // Videos are only complete once the context is closed.
+    if (context) await context.close().catch(() => {});
+    if (video !== 'off') await saveVideos(pages);
+    if (browser) await browser.close().catch(() => {});
+  }
+  if (!error) {
+    if (trace === 'retain-on-failure') fs.rmSync(path.join(outDir, 'trace.zip'), { force: true });
+    if (video === 'retain-on-failure') {
+      for (const f of fs.readdirSync(outDir).filter(f => /^video(-\d+)?\.webm$/.test(f))) {
+        fs.rmSync(path.join(outDir, f));
+      }
+    }
+  }
+  const files = fs.readdirSync(outDir).filter(f => fs.statSync(path.join(outDir, f)).isFile());
+  console.log(JSON.stringify({ files, browser: browserName, error }));
+  process.exit(error ? 1 : 0);
+})();
@@ -0,0 +1,15 @@
+package playwright
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewWSPath returns a random WebSocket path secret (64 hex characters).
+func NewWSPath() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
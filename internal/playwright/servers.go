@@ -0,0 +1,116 @@
+package playwright
+
+import (
+	"fmt"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+// Browsers a server can run. msedge is the Edge channel of the chromium engine.
+const (
+	BrowserChromium = "chromium"
+	BrowserMSEdge   = "msedge"
+	BrowserFirefox  = "firefox"
+	BrowserWebKit   = "webkit"
+)
+
+// browserPortOffsets assigns each browser server a fixed port above
+// cfg.PlaywrightPort, so chromium keeps the historical port.
+var browserPortOffsets = map[string]int{
+	BrowserChromium: 0,
+	BrowserMSEdge:   1,
+	BrowserFirefox:  2,
+	BrowserWebKit:   3,
+}
+
+// Server is one browser server on Windows: a scheduled task running
+// launch-server.js for a single browser on its own port.
+type Server struct {
+	Browser string
+	Port    int
+	Task    string
+}
+
+// ValidateBrowser reports whether browser is a supported engine or channel.
+func ValidateBrowser(browser string) error {
+	if _, ok := browserPortOffsets[browser]; !ok {
+		return fmt.Errorf("unsupported browser %q (want %s, %s, %s or %s)", browser, BrowserChromium, BrowserMSEdge, BrowserFirefox, BrowserWebKit)
+	}
+	return nil
+}
+
+// Engine returns the Playwright browser type that drives browser.
+func Engine(browser string) string {
+	if browser == BrowserMSEdge {
+		return BrowserChromium
+	}
+	return browser
+}
+
+// TaskName returns the scheduled task of the browser's server. chromium keeps the
+// name used before per-browser servers existed.
+func TaskName(browser string) string {
+	if browser == BrowserChromium {
+		return "WinAutomation-Playwright"
+	}
+	return "WinAutomation-Playwright-" + browser
+}
+
+// ServerFor returns the server of browser, configured or not.
+func ServerFor(cfg config.Config, browser string) Server {
+	return Server{
+		Browser: browser,
+		Port:    cfg.PlaywrightPort + browserPortOffsets[browser],
+		Task:    TaskName(browser),
+	}
+}
+
+// Servers returns the configured browser servers (cfg.PlaywrightBrowsers).
+func Servers(cfg config.Config) []Server {
+	servers := make([]Server, 0, len(cfg.PlaywrightBrowsers))
+	for _, browser := range cfg.PlaywrightBrowsers {
+		servers = append(servers, ServerFor(cfg, browser))
+	}
+	return servers
+}
+
+// Configured reports whether cfg runs a server for browser.
+func Configured(cfg config.Config, browser string) bool {
+	for _, b := range cfg.PlaywrightBrowsers {
+		if b == browser {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoint returns the WebSocket endpoint of the server as reached from Linux.
+func (s Server) Endpoint(cfg config.Config) Endpoint {
+	return Endpoint{Host: cfg.PlaywrightHost, Port: s.Port, Path: cfg.PlaywrightWSPath}
+}
+
+// Engines returns the browser builds to download for servers; msedge uses the
+// Edge installed on Windows.
+func Engines(browsers []string) []string {
+	var engines []string
+	for _, b := range browsers {
+		if b != BrowserMSEdge {
+			engines = append(engines, b)
+		}
+	}
+	return engines
+}
+
+// AllServers returns the server of every supported browser, configured or not.
+func AllServers(cfg config.Config) []Server {
+	servers := make([]Server, 0, len(browserPortOffsets))
+	for _, browser := range []string{BrowserChromium, BrowserMSEdge, BrowserFirefox, BrowserWebKit} {
+		servers = append(servers, ServerFor(cfg, browser))
+	}
+	return servers
+}
+
+// FirewallRule returns the name of the inbound rule for the server's port.
+func (s Server) FirewallRule() string {
+	return fmt.Sprintf("Playwright-%d", s.Port)
+}
@@ -0,0 +1,72 @@
+package playwright
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+)
+
+func TestValidateBrowser(t *testing.T) {
+	for _, browser := range []string{BrowserChromium, BrowserMSEdge, BrowserFirefox, BrowserWebKit} {
+		if err := ValidateBrowser(browser); err != nil {
+			t.Errorf("ValidateBrowser(%q) error = %v", browser, err)
+		}
+	}
+	for _, browser := range []string{"", "opera", "Chromium"} {
+		if err := ValidateBrowser(browser); err == nil {
+			t.Errorf("ValidateBrowser(%q) error = nil, want error", browser)
+		}
+	}
+}
+
+func TestServers(t *testing.T) {
+	cfg := config.Config{
+		PlaywrightPort:     3000,
+		PlaywrightBrowsers: []string{BrowserChromium, BrowserFirefox, BrowserMSEdge},
+	}
+	want := []Server{
+		{Browser: BrowserChromium, Port: 3000, Task: "WinAutomation-Playwright"},
+		{Browser: BrowserFirefox, Port: 3002, Task: "WinAutomation-Playwright-firefox"},
+		{Browser: BrowserMSEdge, Port: 3001, Task: "WinAutomation-Playwright-msedge"},
+	}
+	if got := Servers(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Servers() = %+v, want %+v", got, want)
+	}
+	if !Configured(cfg, BrowserFirefox) || Configured(cfg, BrowserWebKit) {
+		t.Error("Configured() does not match PlaywrightBrowsers")
+	}
+	if got := ServerFor(cfg, BrowserWebKit).Port; got != 3003 {
+		t.Errorf("ServerFor(webkit).Port = %d, want 3003", got)
+	}
+}
+
+func TestEngines(t *testing.T) {
+	got := Engines([]string{BrowserMSEdge, BrowserChromium, BrowserWebKit})
+	want := []string{BrowserChromium, BrowserWebKit}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engines() = %v, want %v", got, want)
+	}
+	if got := Engines([]string{BrowserMSEdge}); len(got) != 0 {
+		t.Errorf("Engines(msedge) = %v, want none", got)
+	}
+}
+
+func TestComponent(t *testing.T) {
+	cfg := config.Config{PlaywrightPort: 9323, PlaywrightBrowsers: []string{BrowserFirefox}}
+	c, err := Component(cfg, Servers(cfg))
+	if err != nil {
+		t.Fatalf("Component() error = %v", err)
+	}
+	if len(c.Files) != 2 || len(c.Tasks) != 1 || len(c.Firewall) != 1 {
+		t.Fatalf("Component() = %d files, %d tasks, %d rules; want 2, 1, 1", len(c.Files), len(c.Tasks), len(c.Firewall))
+	}
+	if c.Tasks[0].Name != "WinAutomation-Playwright-firefox" || c.Firewall[0].Name != "Playwright-9325" || c.Firewall[0].Port != 9325 {
+		t.Errorf("Component() task = %+v, rule = %+v", c.Tasks[0], c.Firewall[0])
+	}
+
+	r := Removal(cfg)
+	if len(r.Tasks) != 4 || len(r.Firewall) != 4 || r.Dir != InstallDir {
+		t.Errorf("Removal() = %+v, want every browser and %s", r, InstallDir)
+	}
+}
@@ -0,0 +1,82 @@
+package playwright
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// ErrNotInstalled is returned by RemoteVersion when no pinned Playwright package
+// exists in InstallDir.
+var ErrNotInstalled = errors.New("playwright not installed in " + InstallDir)
+
+// Version is a Playwright release. Client and server are compatible when major
+// and minor match; the patch level is ignored.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses major.minor or major.minor.patch, with an optional leading v.
+func ParseVersion(value string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid playwright version %q: want major.minor[.patch]", value)
+	}
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid playwright version %q: want major.minor[.patch]", value)
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compatible reports whether a client at v can talk to a server at other.
+func (v Version) Compatible(other Version) bool {
+	return v.Major == other.Major && v.Minor == other.Minor
+}
+
+// RemoteVersion reads the Playwright version pinned in InstallDir on Windows.
+func RemoteVersion(ctx context.Context, cfg config.Config) (Version, error) {
+	res, err := runSSH(ctx, cfg, win.NodePackageVersion(InstallDir, "playwright"))
+	if err != nil {
+		if res.ExitCode == 3 {
+			return Version{}, ErrNotInstalled
+		}
+		return Version{}, fmt.Errorf("read remote playwright version: %w", err)
+	}
+	return ParseVersion(res.Stdout)
+}
+
+// LocalVersion reads the version of the playwright package node resolves on this
+// host, i.e. the client scripts on Linux use.
+func LocalVersion(ctx context.Context) (Version, error) {
+	out, err := exec.CommandContext(ctx, "node", "-p", "require('playwright/package.json').version").Output()
+	if err != nil {
+		return Version{}, fmt.Errorf("read local playwright version: %w", err)
+	}
+	return ParseVersion(string(out))
+}
+
+// ExpectedVersion returns the client version the Windows server must match:
+// cfg.PlaywrightVersion, else the local package. ok is false when neither is known.
+func ExpectedVersion(ctx context.Context, cfg config.Config) (v Version, ok bool) {
+	if cfg.PlaywrightVersion != "" {
+		v, err := ParseVersion(cfg.PlaywrightVersion)
+		return v, err == nil
+	}
+	v, err := LocalVersion(ctx)
+	return v, err == nil
+}
@@ -0,0 +1,35 @@
+package playwright
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{"1.49", Version{1, 49, 0}},
+		{"1.49.1\r\n", Version{1, 49, 1}},
+		{"v1.50.0", Version{1, 50, 0}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "1", "1.x", "1.2.3.4", "latest"} {
+		if _, err := ParseVersion(bad); err == nil {
+			t.Errorf("ParseVersion(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestVersion_Compatible(t *testing.T) {
+	v := Version{1, 49, 1}
+	if !v.Compatible(Version{1, 49, 0}) {
+		t.Error("1.49.1 should be compatible with 1.49.0")
+	}
+	if v.Compatible(Version{1, 50, 0}) || v.Compatible(Version{2, 49, 1}) {
+		t.Error("different major.minor should be incompatible")
+	}
+}
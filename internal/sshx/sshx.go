@@ -13,6 +13,20 @@ import (
 	"github.com/alejg/win-automation/internal/config"
 )
 
+// ExitConnectionFailed is the status ssh itself exits with when it cannot
+// connect or the connection drops, as opposed to the remote command's.
+const ExitConnectionFailed = 255
+
+// ExitError is returned when ssh exits non-zero. Code is the remote command's
+// exit code unless it is ExitConnectionFailed.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("ssh command failed (exit %d)", e.Code)
+}
+
 type Result struct {
 	Stdout   string
 	Stderr   string
@@ -63,7 +77,7 @@ func Run(ctx context.Context, cfg config.Config, remoteCommand string) (Result,
 		var exitErr *exec.ExitError
 		if errors.As(runErr, &exitErr) {
 			res.ExitCode = exitErr.ExitCode()
-			return res, fmt.Errorf("ssh command failed (exit %d)", res.ExitCode)
+			return res, &ExitError{Code: res.ExitCode}
 		}
 		res.ExitCode = -1
 
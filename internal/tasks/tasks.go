@@ -0,0 +1,287 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Param types accepted in template definitions.
+const (
+	ParamString = "string"
+	ParamInt    = "int"
+	ParamBool   = "bool"
+)
+
+// Template job types.
+const (
+	TypeAlohaRun    = "aloha.run"
+	TypeWindowsExec = "windows.exec"
+)
+
+// Param declares a typed template parameter.
+type Param struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type,omitempty"` // string (default), int, bool
+	Default     *string `json:"default,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// Template is a named, parameterized Aloha prompt or PowerShell command.
+type Template struct {
+	Name            string  `json:"name"`
+	Description     string  `json:"description,omitempty"`
+	Type            string  `json:"type"` // aloha.run or windows.exec
+	Prompt          string  `json:"prompt,omitempty"`
+	Command         string  `json:"command,omitempty"`
+	Params          []Param `json:"params,omitempty"`
+	IdempotentCheck string  `json:"idempotent_check,omitempty"`
+	Verify          string  `json:"verify,omitempty"`
+	MaxSteps        int     `json:"max_steps,omitempty"`
+}
+
+// Rendered holds the template fields after parameter substitution.
+type Rendered struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Task            string `json:"task,omitempty"`
+	Command         string `json:"command,omitempty"`
+	IdempotentCheck string `json:"idempotent_check,omitempty"`
+	Verify          string `json:"verify,omitempty"`
+	MaxSteps        int    `json:"max_steps,omitempty"`
+}
+
+// Library is a set of templates keyed by name.
+type Library map[string]Template
+
+// LoadDir reads every *.json file in dir as a Template. The file name (without
+// extension) is used when the template has no name. A missing directory yields an
+// empty library.
+func LoadDir(dir string) (Library, error) {
+	lib := Library{}
+	if strings.TrimSpace(dir) == "" {
+		return lib, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var tmpl Template
+		if err := json.Unmarshal(data, &tmpl); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if tmpl.Name == "" {
+			tmpl.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if err := tmpl.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, dup := lib[tmpl.Name]; dup {
+			return nil, fmt.Errorf("%s: duplicate template %q", path, tmpl.Name)
+		}
+		lib[tmpl.Name] = tmpl
+	}
+	return lib, nil
+}
+
+// Get returns the named template.
+func (l Library) Get(name string) (Template, error) {
+	tmpl, ok := l[name]
+	if !ok {
+		return Template{}, fmt.Errorf("unknown template: %s", name)
+	}
+	return tmpl, nil
+}
+
+// Sorted returns the templates ordered by name.
+func (l Library) Sorted() []Template {
+	out := make([]Template, 0, len(l))
+	for _, tmpl := range l {
+		out = append(out, tmpl)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Validate checks the template definition: its type, parameter declarations and
+// defaults, and that every text field parses.
+func (t Template) Validate() error {
+	switch t.Type {
+	case TypeAlohaRun:
+		if strings.TrimSpace(t.Prompt) == "" {
+			return errors.New("prompt is required for aloha.run templates")
+		}
+	case TypeWindowsExec:
+		if strings.TrimSpace(t.Command) == "" {
+			return errors.New("command is required for windows.exec templates")
+		}
+	default:
+		return fmt.Errorf("unknown template type: %q", t.Type)
+	}
+	if t.MaxSteps < 0 {
+		return errors.New("max_steps must not be negative")
+	}
+
+	seen := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		if p.Name == "" {
+			return errors.New("param name is required")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate param %q", p.Name)
+		}
+		seen[p.Name] = true
+		switch p.paramType() {
+		case ParamString, ParamInt, ParamBool:
+		default:
+			return fmt.Errorf("param %q: unknown type %q", p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if _, err := p.parse(*p.Default); err != nil {
+				return fmt.Errorf("param %q default: %w", p.Name, err)
+			}
+		}
+	}
+
+	// Execute against zero values so references to undeclared params fail here
+	// rather than at dispatch time.
+	zero := make(map[string]any, len(t.Params))
+	for _, p := range t.Params {
+		zero[p.Name], _ = p.parse(zeroValue(p.paramType()))
+	}
+	for field, text := range t.texts() {
+		tmpl, err := parseText(field, text)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(io.Discard, zero); err != nil {
+			return fmt.Errorf("render %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func zeroValue(paramType string) string {
+	switch paramType {
+	case ParamInt:
+		return "0"
+	case ParamBool:
+		return "false"
+	default:
+		return ""
+	}
+}
+
+// Render validates values against the declared parameters and executes every
+// template field. Unknown parameters and missing required ones are errors.
+func (t Template) Render(values map[string]string) (Rendered, error) {
+	declared := make(map[string]Param, len(t.Params))
+	for _, p := range t.Params {
+		declared[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return Rendered{}, fmt.Errorf("template %s: unknown param %q", t.Name, name)
+		}
+	}
+
+	data := make(map[string]any, len(t.Params))
+	for _, p := range t.Params {
+		raw, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return Rendered{}, fmt.Errorf("template %s: param %q is required", t.Name, p.Name)
+			}
+			raw = *p.Default
+		}
+		v, err := p.parse(raw)
+		if err != nil {
+			return Rendered{}, fmt.Errorf("template %s: param %q: %w", t.Name, p.Name, err)
+		}
+		data[p.Name] = v
+	}
+
+	out := Rendered{Name: t.Name, Type: t.Type, MaxSteps: t.MaxSteps}
+	targets := map[string]*string{
+		"prompt":           &out.Task,
+		"command":          &out.Command,
+		"idempotent_check": &out.IdempotentCheck,
+		"verify":           &out.Verify,
+	}
+	for field, text := range t.texts() {
+		tmpl, err := parseText(field, text)
+		if err != nil {
+			return Rendered{}, err
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return Rendered{}, fmt.Errorf("template %s: render %s: %w", t.Name, field, err)
+		}
+		*targets[field] = sb.String()
+	}
+	return out, nil
+}
+
+func (t Template) texts() map[string]string {
+	texts := map[string]string{}
+	for field, text := range map[string]string{
+		"prompt":           t.Prompt,
+		"command":          t.Command,
+		"idempotent_check": t.IdempotentCheck,
+		"verify":           t.Verify,
+	} {
+		if text != "" {
+			texts[field] = text
+		}
+	}
+	return texts
+}
+
+func parseText(field, text string) (*template.Template, error) {
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", field, err)
+	}
+	return tmpl, nil
+}
+
+func (p Param) paramType() string {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+func (p Param) parse(raw string) (any, error) {
+	switch p.paramType() {
+	case ParamString:
+		return raw, nil
+	case ParamInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be an int")
+		}
+		return n, nil
+	case ParamBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be a bool")
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown param type %q", p.Type)
+	}
+}
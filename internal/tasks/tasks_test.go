@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl := Template{
+		Name:     "open-app",
+		Type:     TypeAlohaRun,
+		Prompt:   "Open {{.app}} and wait {{.wait}}s",
+		Verify:   "Get-Process -Name {{.app}}",
+		MaxSteps: 15,
+		Params: []Param{
+			{Name: "app"},
+			{Name: "wait", Type: ParamInt, Default: strPtr("5")},
+		},
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	got, err := tmpl.Render(map[string]string{"app": "Notepad"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got.Task != "Open Notepad and wait 5s" {
+		t.Errorf("Task = %q", got.Task)
+	}
+	if got.Verify != "Get-Process -Name Notepad" {
+		t.Errorf("Verify = %q", got.Verify)
+	}
+	if got.MaxSteps != 15 {
+		t.Errorf("MaxSteps = %d, want 15", got.MaxSteps)
+	}
+}
+
+func TestTemplate_RenderErrors(t *testing.T) {
+	tmpl := Template{
+		Name:   "open-app",
+		Type:   TypeAlohaRun,
+		Prompt: "Open {{.app}} {{.count}} times",
+		Params: []Param{{Name: "app"}, {Name: "count", Type: ParamInt, Default: strPtr("1")}},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr string
+	}{
+		{"Missing", map[string]string{}, `param "app" is required`},
+		{"Unknown", map[string]string{"app": "x", "other": "y"}, `unknown param "other"`},
+		{"BadType", map[string]string{"app": "x", "count": "many"}, "must be an int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tmpl.Render(tt.values)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Render() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplate_ValidateRejectsUndeclaredParam(t *testing.T) {
+	tmpl := Template{Name: "bad", Type: TypeWindowsExec, Command: "echo {{.missing}}"}
+	if err := tmpl.Validate(); err == nil {
+		t.Fatal("Validate() expected error for undeclared param")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"type":"windows.exec","command":"Get-Service {{.name}}","params":[{"name":"name"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "service.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+	tmpl, err := lib.Get("service")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if tmpl.Type != TypeWindowsExec {
+		t.Errorf("Type = %q, want %q", tmpl.Type, TypeWindowsExec)
+	}
+
+	lib, err = LoadDir(filepath.Join(dir, "missing"))
+	if err != nil || len(lib) != 0 {
+		t.Errorf("LoadDir(missing) = %v, %v; want empty library", lib, err)
+	}
+}
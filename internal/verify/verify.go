@@ -0,0 +1,91 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+	"github.com/alejg/win-automation/internal/win"
+)
+
+// ErrFailed is returned when a postcondition never exits 0 within its retry budget.
+var ErrFailed = errors.New("verification failed")
+
+// Spec describes a PowerShell postcondition that must exit 0 once a task has finished.
+type Spec struct {
+	Script  string        `json:"script"`
+	Retries int           `json:"retries,omitempty"`
+	Delay   time.Duration `json:"delay,omitempty"`
+}
+
+// Attempt records the outcome of a single postcondition run.
+type Attempt struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Result aggregates all postcondition attempts for one task run.
+type Result struct {
+	Passed   bool      `json:"passed"`
+	Attempts []Attempt `json:"attempts"`
+}
+
+// runSSH is replaced in tests that run without a Windows host.
+var runSSH = sshx.Run
+
+// Check runs spec.Script over SSH until it exits 0, retrying spec.Retries times
+// with spec.Delay between attempts. It returns an error wrapping ErrFailed when the
+// script keeps failing, or the transport error when the last attempt could not run.
+// ssh failing to connect (exit 255) is a transport error, not a failed check.
+func Check(ctx context.Context, cfg config.Config, spec Spec) (Result, error) {
+	if strings.TrimSpace(spec.Script) == "" {
+		return Result{}, errors.New("verify script is required")
+	}
+
+	var result Result
+	var lastErr error
+	for attempt := 0; attempt <= spec.Retries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, spec.Delay); err != nil {
+				return result, err
+			}
+		}
+
+		res, err := runSSH(ctx, cfg, win.PowerShellCommand(spec.Script))
+		result.Attempts = append(result.Attempts, Attempt{
+			Stdout:   res.Stdout,
+			Stderr:   res.Stderr,
+			ExitCode: res.ExitCode,
+		})
+		if err == nil {
+			result.Passed = true
+			return result, nil
+		}
+		var exitErr *sshx.ExitError
+		if errors.As(err, &exitErr) && exitErr.Code != sshx.ExitConnectionFailed {
+			lastErr = fmt.Errorf("%w: exit %d", ErrFailed, exitErr.Code)
+		} else {
+			lastErr = fmt.Errorf("run verify script: %w", err)
+		}
+	}
+	return result, lastErr
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alejg/win-automation/internal/config"
+	"github.com/alejg/win-automation/internal/sshx"
+)
+
+// stubSSH makes runSSH return the exit codes in order, 0 once they run out.
+func stubSSH(t *testing.T, codes ...int) *int {
+	t.Helper()
+	calls := 0
+	orig := runSSH
+	runSSH = func(context.Context, config.Config, string) (sshx.Result, error) {
+		code := 0
+		if calls < len(codes) {
+			code = codes[calls]
+		}
+		calls++
+		if code != 0 {
+			return sshx.Result{ExitCode: code}, &sshx.ExitError{Code: code}
+		}
+		return sshx.Result{Stdout: "ok"}, nil
+	}
+	t.Cleanup(func() { runSSH = orig })
+	return &calls
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		codes      []int
+		retries    int
+		wantPassed bool
+		wantCalls  int
+		wantFailed bool
+		wantErr    bool
+	}{
+		{name: "pass", codes: nil, wantPassed: true, wantCalls: 1},
+		{name: "fail", codes: []int{1, 1}, retries: 1, wantCalls: 2, wantFailed: true, wantErr: true},
+		{name: "retry then pass", codes: []int{1}, retries: 2, wantPassed: true, wantCalls: 2},
+		{name: "connection failure", codes: []int{255}, wantCalls: 1, wantErr: true},
+		{name: "connection failure then pass", codes: []int{255}, retries: 1, wantPassed: true, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := stubSSH(t, tt.codes...)
+			result, err := Check(context.Background(), config.Config{}, Spec{Script: "Test-Path C:\\x", Retries: tt.retries})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrFailed); got != tt.wantFailed {
+				t.Errorf("errors.Is(err, ErrFailed) = %v, want %v (err %v)", got, tt.wantFailed, err)
+			}
+			if result.Passed != tt.wantPassed || *calls != tt.wantCalls || len(result.Attempts) != tt.wantCalls {
+				t.Errorf("Passed = %v, calls = %d, attempts = %d; want %v, %d", result.Passed, *calls, len(result.Attempts), tt.wantPassed, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCheck_ConnectionFailureIsTransportError(t *testing.T) {
+	stubSSH(t, 255)
+	_, err := Check(context.Background(), config.Config{}, Spec{Script: "exit 0"})
+	var exitErr *sshx.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != sshx.ExitConnectionFailed || errors.Is(err, ErrFailed) {
+		t.Errorf("Check() error = %v, want the ssh connection error", err)
+	}
+}
+
+func TestCheck_RequiresScript(t *testing.T) {
+	if _, err := Check(context.Background(), config.Config{}, Spec{Script: " "}); err == nil {
+		t.Error("Check() with an empty script error = nil, want error")
+	}
+}
@@ -1,9 +1,12 @@
 package win
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf16"
 )
 
 // PowerShellCommand wraps an inline script in the standard powershell invocation.
@@ -29,3 +32,301 @@ func DesktopUnlockedCheck() string {
 	script := "if (Get-Process -Name LogonUI -ErrorAction SilentlyContinue) { $false } else { $true }"
 	return PowerShellCommand(script)
 }
+
+// DesktopLockTry returns a PowerShell command that enqueues ticket for the desktop lock in
+// dir and takes the lock when it is free and ticket is first in line. It prints "acquired"
+// on success, otherwise "waiting position=<n> holder=<current holder>". Expired locks and
+// tickets not refreshed within staleSeconds are discarded.
+func DesktopLockTry(dir, ticket, holder string, ttlSeconds, staleSeconds int) string {
+	script := strings.Join([]string{
+		desktopLockPaths(dir, ticket),
+		"New-Item -ItemType Directory -Force -Path $queue | Out-Null",
+		fmt.Sprintf("if (Test-Path $ticket) { (Get-Item $ticket).LastWriteTimeUtc = [DateTime]::UtcNow } else { Set-Content -Path $ticket -Value '%s' }", psEscape(holder)),
+		fmt.Sprintf("$stale = [DateTime]::UtcNow.AddSeconds(-%d)", staleSeconds),
+		"Get-ChildItem $queue | Where-Object { $_.LastWriteTimeUtc -lt $stale } | Remove-Item -Force -ErrorAction SilentlyContinue",
+		"$now = [DateTimeOffset]::UtcNow.ToUnixTimeSeconds()",
+		"if (Test-Path $lock) { $cur = Get-Content $lock -Raw | ConvertFrom-Json; if ($cur.expires -lt $now) { Remove-Item $lock -Force -ErrorAction SilentlyContinue } }",
+		"$names = @(Get-ChildItem $queue | Sort-Object Name | ForEach-Object { $_.Name })",
+		"$pos = [Array]::IndexOf($names, $ticketName)",
+		fmt.Sprintf("if ((-not (Test-Path $lock)) -and $pos -eq 0) { try { $fs = [System.IO.File]::Open($lock, 'CreateNew', 'Write'); $bytes = [Text.Encoding]::UTF8.GetBytes((@{ holder = '%s'; expires = $now + %d } | ConvertTo-Json -Compress)); $fs.Write($bytes, 0, $bytes.Length); $fs.Close(); Remove-Item $ticket -Force; 'acquired'; exit 0 } catch { } }", psEscape(holder), ttlSeconds),
+		"$holder = ''; if (Test-Path $lock) { $holder = (Get-Content $lock -Raw | ConvertFrom-Json).holder }",
+		"'waiting position=' + ($pos + 1) + ' holder=' + $holder",
+	}, "; ")
+	return PowerShellCommand(script)
+}
+
+// DesktopLockRenew returns a PowerShell command that extends the desktop lock held by holder.
+func DesktopLockRenew(dir, holder string, ttlSeconds int) string {
+	script := strings.Join([]string{
+		desktopLockPaths(dir, ""),
+		fmt.Sprintf("if (Test-Path $lock) { $cur = Get-Content $lock -Raw | ConvertFrom-Json; if ($cur.holder -eq '%s') { $cur.expires = [DateTimeOffset]::UtcNow.ToUnixTimeSeconds() + %d; Set-Content -Path $lock -Value ($cur | ConvertTo-Json -Compress) -NoNewline; exit 0 } }", psEscape(holder), ttlSeconds),
+		"exit 1",
+	}, "; ")
+	return PowerShellCommand(script)
+}
+
+// DesktopLockRelease returns a PowerShell command that drops ticket from the queue and
+// removes the desktop lock when it is held by holder.
+func DesktopLockRelease(dir, ticket, holder string) string {
+	script := strings.Join([]string{
+		desktopLockPaths(dir, ticket),
+		"if ($ticketName -and (Test-Path $ticket)) { Remove-Item $ticket -Force -ErrorAction SilentlyContinue }",
+		fmt.Sprintf("if (Test-Path $lock) { $cur = Get-Content $lock -Raw | ConvertFrom-Json; if ($cur.holder -eq '%s') { Remove-Item $lock -Force } }", psEscape(holder)),
+	}, "; ")
+	return PowerShellCommand(script)
+}
+
+func desktopLockPaths(dir, ticket string) string {
+	paths := fmt.Sprintf("$dir = '%s'; $lock = Join-Path $dir 'desktop.lock'; $queue = Join-Path $dir 'desktop.queue'; $ticketName = '%s'", psEscape(dir), psEscape(ticket))
+	if ticket == "" {
+		return paths + "; $ticket = ''"
+	}
+	return paths + "; $ticket = Join-Path $queue $ticketName"
+}
+
+func psEscape(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+// RestrictToAdmins returns a PowerShell command that removes inherited permissions from
+// path and grants full control to SYSTEM and Administrators only (by SID, so it works
+// on localized Windows).
+func RestrictToAdmins(path string) string {
+	return PowerShellCommand(fmt.Sprintf(
+		"icacls '%s' /inheritance:r /grant:r '*S-1-5-18:F' '*S-1-5-32-544:F' | Out-Null", psEscape(path)))
+}
+
+// ScheduledTaskRestart returns a PowerShell command that stops and starts a scheduled task.
+func ScheduledTaskRestart(taskName string) string {
+	name := psEscape(taskName)
+	return PowerShellCommand(fmt.Sprintf(
+		"Stop-ScheduledTask -TaskName '%s' -ErrorAction SilentlyContinue; Start-Sleep -Seconds 1; Start-ScheduledTask -TaskName '%s'", name, name))
+}
+
+// ScheduledTaskStop returns a PowerShell command that stops a scheduled task if it runs.
+func ScheduledTaskStop(taskName string) string {
+	return PowerShellCommand(fmt.Sprintf("Stop-ScheduledTask -TaskName '%s' -ErrorAction SilentlyContinue", psEscape(taskName)))
+}
+
+// ScheduledTaskStart returns a PowerShell command that starts a scheduled task.
+func ScheduledTaskStart(taskName string) string {
+	return PowerShellCommand(fmt.Sprintf("Start-ScheduledTask -TaskName '%s'", psEscape(taskName)))
+}
+
+// ScheduledTaskUnregister returns a PowerShell command that stops and removes a
+// scheduled task; a missing task is not an error.
+func ScheduledTaskUnregister(taskName string) string {
+	name := psEscape(taskName)
+	return PowerShellCommand(fmt.Sprintf(
+		"Stop-ScheduledTask -TaskName '%s' -ErrorAction SilentlyContinue; Unregister-ScheduledTask -TaskName '%s' -Confirm:$false -ErrorAction SilentlyContinue", name, name))
+}
+
+// ScheduledTaskRegisterLogon returns a PowerShell command that (re)registers a task
+// running script with PowerShell in user's interactive session at logon.
+func ScheduledTaskRegisterLogon(taskName, user, script string) string {
+	return PowerShellCommand(strings.Join([]string{
+		fmt.Sprintf("$action = New-ScheduledTaskAction -Execute 'powershell.exe' -Argument '-NoProfile -WindowStyle Hidden -ExecutionPolicy Bypass -File \"%s\"'", psEscape(script)),
+		fmt.Sprintf("$trigger = New-ScheduledTaskTrigger -AtLogOn -User '%s'", psEscape(user)),
+		fmt.Sprintf("$principal = New-ScheduledTaskPrincipal -UserId '%s' -LogonType Interactive", psEscape(user)),
+		fmt.Sprintf("Register-ScheduledTask -TaskName '%s' -Action $action -Trigger $trigger -Principal $principal -Force | Out-Null", psEscape(taskName)),
+	}, "; "))
+}
+
+// FirewallRuleEnsure returns a PowerShell command that creates an inbound TCP allow rule
+// for port, or enables it when it exists. It prints "created", "enabled" or "ok".
+func FirewallRuleEnsure(displayName string, port int) string {
+	name := psEscape(displayName)
+	return PowerShellCommand(fmt.Sprintf(
+		"$rule = Get-NetFirewallRule -DisplayName '%s' -ErrorAction SilentlyContinue; if ($null -eq $rule) { New-NetFirewallRule -DisplayName '%s' -Direction Inbound -Action Allow -Protocol TCP -LocalPort %d -Profile Any | Out-Null; 'created' } elseif ($rule.Enabled -ne 'True') { Set-NetFirewallRule -DisplayName '%s' -Enabled True | Out-Null; 'enabled' } else { 'ok' }",
+		name, name, port, name))
+}
+
+// FirewallRuleRemove returns a PowerShell command that deletes a firewall rule if present.
+func FirewallRuleRemove(displayName string) string {
+	return PowerShellCommand(fmt.Sprintf("Remove-NetFirewallRule -DisplayName '%s' -ErrorAction SilentlyContinue", psEscape(displayName)))
+}
+
+// RemoveDirectory returns a PowerShell command that deletes dir and its contents.
+// A missing dir is not an error; one that is still there afterwards exits 1.
+func RemoveDirectory(dir string) string {
+	return PowerShellCommand(fmt.Sprintf(
+		"$d = '%s'; if (Test-Path -LiteralPath $d) { Remove-Item -Recurse -Force -LiteralPath $d -ErrorAction Stop }; if (Test-Path -LiteralPath $d) { Write-Error \"$d still exists\"; exit 1 }",
+		psEscape(dir)))
+}
+
+// NodePackageVersion returns a PowerShell command that prints the version of the npm
+// package installed in dir\node_modules, exiting 3 when it is missing.
+func NodePackageVersion(dir, pkg string) string {
+	return PowerShellCommand(fmt.Sprintf(
+		"$p = Join-Path '%s' 'node_modules\\%s\\package.json'; if (-not (Test-Path $p)) { exit 3 }; (Get-Content -Raw $p | ConvertFrom-Json).version",
+		psEscape(dir), psEscape(pkg)))
+}
+
+// PlaywrightInstall returns a PowerShell command that pins playwright@version in dir
+// (npm resolves major.minor to the latest patch) and downloads the builds of engines
+// to dir\browsers, shared by every account that runs the servers or scripts.
+func PlaywrightInstall(dir, version string, engines []string) string {
+	d := psEscape(dir)
+	steps := []string{
+		fmt.Sprintf("Set-Location '%s'", d),
+		"if (-not (Test-Path 'package.json')) { npm init -y | Out-Null }",
+		fmt.Sprintf("npm install --save-exact --no-fund --no-audit 'playwright@%s'", psEscape(version)),
+		"if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }",
+	}
+	if len(engines) > 0 {
+		quoted := make([]string, len(engines))
+		for i, engine := range engines {
+			quoted[i] = "'" + psEscape(engine) + "'"
+		}
+		steps = append(steps,
+			fmt.Sprintf("$env:PLAYWRIGHT_BROWSERS_PATH = '%s\\browsers'", d),
+			"npx --no-install playwright install "+strings.Join(quoted, " "),
+			"exit $LASTEXITCODE",
+		)
+	}
+	return PowerShellCommand(strings.Join(steps, "; "))
+}
+
+// refreshPath reloads PATH from the registry: sshd hands new sessions the PATH it
+// started with, so a Node.js installed after that is otherwise not found.
+const refreshPath = "$env:Path = [Environment]::GetEnvironmentVariable('Path', 'Machine') + ';' + $env:Path"
+
+// NodeVersion returns a PowerShell command that prints `node --version`, exiting 3
+// when node is not installed.
+func NodeVersion() string {
+	return PowerShellCommand(refreshPath + "; if (-not (Get-Command node -ErrorAction SilentlyContinue)) { exit 3 }; node --version")
+}
+
+// MSIInstall returns a PowerShell command that installs an MSI package silently. Exit
+// code 3010 (reboot required) counts as success.
+func MSIInstall(path string) string {
+	return PowerShellCommand(fmt.Sprintf(
+		"$p = Start-Process msiexec.exe -ArgumentList '/i', '\"%s\"', '/qn', '/norestart' -Wait -PassThru; if ($p.ExitCode -ne 0 -and $p.ExitCode -ne 3010) { exit $p.ExitCode }",
+		psEscape(path)))
+}
+
+// NodeZipInstall returns a PowerShell command that expands a Node.js zip distribution
+// into dest and adds dest to the machine PATH.
+func NodeZipInstall(zipPath, dest string) string {
+	d := psEscape(dest)
+	return PowerShellCommand(strings.Join([]string{
+		"$tmp = Join-Path $env:TEMP 'win-automation-node'",
+		"Remove-Item -Recurse -Force $tmp -ErrorAction SilentlyContinue",
+		fmt.Sprintf("Expand-Archive -Path '%s' -DestinationPath $tmp -Force", psEscape(zipPath)),
+		"$src = (Get-ChildItem $tmp -Directory | Select-Object -First 1).FullName",
+		fmt.Sprintf("New-Item -ItemType Directory -Force -Path '%s' | Out-Null", d),
+		fmt.Sprintf("Copy-Item -Path (Join-Path $src '*') -Destination '%s' -Recurse -Force", d),
+		"Remove-Item -Recurse -Force $tmp",
+		"$path = [Environment]::GetEnvironmentVariable('Path', 'Machine')",
+		fmt.Sprintf("if (($path -split ';') -notcontains '%s') { [Environment]::SetEnvironmentVariable('Path', $path + ';%s', 'Machine') }", d, d),
+	}, "; "))
+}
+
+// FileSHA256 returns a PowerShell command that prints the lowercase SHA-256 of path,
+// or nothing when the file does not exist.
+func FileSHA256(path string) string {
+	return PowerShellCommand(fmt.Sprintf(
+		"$p = '%s'; if (Test-Path $p) { (Get-FileHash -Algorithm SHA256 -Path $p).Hash.ToLower() }", psEscape(path)))
+}
+
+// NpmInstallOffline returns a PowerShell command that installs package tarballs into
+// dir without contacting a registry.
+func NpmInstallOffline(dir string, tarballs []string) string {
+	quoted := make([]string, len(tarballs))
+	for i, tarball := range tarballs {
+		quoted[i] = "'" + psEscape(tarball) + "'"
+	}
+	return PowerShellCommand(strings.Join([]string{
+		refreshPath,
+		fmt.Sprintf("Set-Location '%s'", psEscape(dir)),
+		"if (-not (Test-Path 'package.json')) { npm init -y | Out-Null }",
+		"npm install --offline --save-exact --no-fund --no-audit " + strings.Join(quoted, " "),
+		"exit $LASTEXITCODE",
+	}, "; "))
+}
+
+// BrowsersPresent returns a PowerShell command that exits 3 unless marker exists and
+// dir holds a build directory (<engine>-<revision>) for every engine.
+func BrowsersPresent(dir, marker string, engines []string) string {
+	d := psEscape(dir)
+	steps := []string{fmt.Sprintf("if (-not (Test-Path '%s\\%s')) { exit 3 }", d, psEscape(marker))}
+	for _, engine := range engines {
+		steps = append(steps, fmt.Sprintf("if (-not (Test-Path '%s\\%s-*')) { exit 3 }", d, psEscape(engine)))
+	}
+	return PowerShellCommand(strings.Join(steps, "; "))
+}
+
+// BrowsersExpand returns a PowerShell command that expands a browsers archive into dir
+// and writes marker, which BrowsersPresent checks on the next run.
+func BrowsersExpand(archive, dir, marker string) string {
+	d := psEscape(dir)
+	return PowerShellCommand(strings.Join([]string{
+		fmt.Sprintf("New-Item -ItemType Directory -Force -Path '%s' | Out-Null", d),
+		fmt.Sprintf("Expand-Archive -Path '%s' -DestinationPath '%s' -Force", psEscape(archive), d),
+		fmt.Sprintf("Set-Content -Path '%s\\%s' -Value (Get-Date -Format o)", d, psEscape(marker)),
+	}, "; "))
+}
+
+// displaysScript enumerates the screens of the session it runs in as a JSON array with
+// index, name, bounds, effective DPI scale and primary flag.
+const displaysScript = `Add-Type -AssemblyName System.Windows.Forms
+Add-Type -TypeDefinition @'
+using System;
+using System.Runtime.InteropServices;
+public static class WinAutomationDpi {
+    [StructLayout(LayoutKind.Sequential)] public struct POINT { public int X; public int Y; }
+    [DllImport("user32.dll")] public static extern bool SetProcessDPIAware();
+    [DllImport("user32.dll")] public static extern IntPtr MonitorFromPoint(POINT pt, uint flags);
+    [DllImport("shcore.dll")] public static extern int GetDpiForMonitor(IntPtr monitor, int dpiType, out uint dpiX, out uint dpiY);
+}
+'@
+[WinAutomationDpi]::SetProcessDPIAware() | Out-Null
+$i = 0
+$list = foreach ($s in [System.Windows.Forms.Screen]::AllScreens) {
+    $pt = New-Object WinAutomationDpi+POINT
+    $pt.X = $s.Bounds.X + [int]($s.Bounds.Width / 2)
+    $pt.Y = $s.Bounds.Y + [int]($s.Bounds.Height / 2)
+    $dx = [uint32]96; $dy = [uint32]96
+    [WinAutomationDpi]::GetDpiForMonitor([WinAutomationDpi]::MonitorFromPoint($pt, 2), 0, [ref]$dx, [ref]$dy) | Out-Null
+    [pscustomobject]@{ index = $i; name = $s.DeviceName; x = $s.Bounds.X; y = $s.Bounds.Y; width = $s.Bounds.Width; height = $s.Bounds.Height; scale = [math]::Round($dx / 96.0, 2); primary = $s.Primary }
+    $i++
+}
+ConvertTo-Json -InputObject @($list) -Compress | Set-Content -Path '%s' -Encoding UTF8
+`
+
+// DisplaysQuery returns a PowerShell command that lists the monitors of the interactive
+// user session. SSH sessions cannot see the interactive desktop, so the enumeration runs
+// as a one-shot scheduled task for the logged-on user, writing JSON to outPath, which is
+// then printed and removed. Exits 3 when nobody is logged on interactively.
+func DisplaysQuery(outPath, taskName string) string {
+	inner := encodedCommand(fmt.Sprintf(displaysScript, psEscape(outPath)))
+	script := strings.Join([]string{
+		fmt.Sprintf("$out = '%s'; $task = '%s'", psEscape(outPath), psEscape(taskName)),
+		"New-Item -ItemType Directory -Force -Path (Split-Path $out) | Out-Null",
+		"Remove-Item $out -ErrorAction SilentlyContinue",
+		"$user = (Get-CimInstance Win32_ComputerSystem).UserName",
+		"if (-not $user) { [Console]::Error.WriteLine('no interactive user session'); exit 3 }",
+		fmt.Sprintf("$action = New-ScheduledTaskAction -Execute 'powershell.exe' -Argument '-NoProfile -WindowStyle Hidden -EncodedCommand %s'", inner),
+		"$principal = New-ScheduledTaskPrincipal -UserId $user -LogonType Interactive",
+		"Register-ScheduledTask -TaskName $task -Action $action -Principal $principal -Force | Out-Null",
+		"Start-ScheduledTask -TaskName $task",
+		"$deadline = (Get-Date).AddSeconds(20)",
+		"while (-not (Test-Path $out) -and (Get-Date) -lt $deadline) { Start-Sleep -Milliseconds 250 }",
+		"Start-Sleep -Milliseconds 250",
+		"Unregister-ScheduledTask -TaskName $task -Confirm:$false",
+		"if (-not (Test-Path $out)) { [Console]::Error.WriteLine('display query timed out'); exit 1 }",
+		"Get-Content $out -Raw",
+		"Remove-Item $out -ErrorAction SilentlyContinue",
+	}, "; ")
+	return PowerShellCommand(script)
+}
+
+// encodedCommand encodes a script for powershell -EncodedCommand (base64 of UTF-16LE).
+func encodedCommand(script string) string {
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[i*2:], u)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
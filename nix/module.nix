@@ -22,6 +22,8 @@ let
         client_url = cfg.aloha.clientUrl;
         server_start_cmd = cfg.aloha.serverStartCmd;
         client_start_cmd = cfg.aloha.clientStartCmd;
+        task_budget = cfg.aloha.taskBudget;
+        daily_step_budget = cfg.aloha.dailyStepBudget;
       };
       hatchet = {
         http_url = cfg.hatchet.httpUrl;
@@ -33,15 +35,37 @@ let
         job_timeout = cfg.hatchet.jobTimeout;
         retry_max = cfg.hatchet.retryMax;
         retry_backoff = cfg.hatchet.retryBackoff;
+        scheduler = cfg.hatchet.scheduler;
+        schedule_file = cfg.hatchet.scheduleFile;
+        dedup_window = cfg.hatchet.dedupWindow;
+        idempotency_store = cfg.hatchet.idempotencyStore;
+        idempotency_file = cfg.hatchet.idempotencyFile;
+        concurrency = cfg.hatchet.concurrency;
+        concurrency_groups = cfg.hatchet.concurrencyGroups;
       };
       playwright = {
         host = cfg.playwright.host;
         port = cfg.playwright.port;
+        version = cfg.playwright.version;
+        browser = cfg.playwright.browser;
+        browsers = cfg.playwright.browsers;
+        cache_dir = cfg.playwright.cacheDir;
+        trace = cfg.playwright.trace;
+        video = cfg.playwright.video;
+        screenshot = cfg.playwright.screenshot;
+        max_sessions = cfg.playwright.maxSessions;
       };
       artifacts = {
         out_dir = cfg.artifacts.outDir;
         retention_days = cfg.artifacts.retentionDays;
       };
+      tasks = {
+        dir = cfg.tasks.dir;
+      };
+      desktop = {
+        lock_timeout = cfg.desktop.lockTimeout;
+        lock_ttl = cfg.desktop.lockTtl;
+      };
       timeout = cfg.timeout;
     }
   );
@@ -109,6 +133,18 @@ in
         default = null;
         description = "Command to start Aloha client on Windows.";
       };
+
+      taskBudget = lib.mkOption {
+        type = lib.types.str;
+        default = "15m";
+        description = "Wall-clock budget per Aloha task, verification included.";
+      };
+
+      dailyStepBudget = lib.mkOption {
+        type = lib.types.int;
+        default = 0;
+        description = "Maximum Aloha steps per UTC day and namespace (0 disables).";
+      };
     };
 
     # Hatchet options
@@ -172,6 +208,61 @@ in
         default = "5s";
         description = "Retry backoff duration.";
       };
+
+      scheduler = lib.mkOption {
+        type = lib.types.enum [ "hatchet" "local" ];
+        default = "hatchet";
+        description = "Where job schedules run: Hatchet cron triggers or the worker's built-in scheduler.";
+      };
+
+      scheduleFile = lib.mkOption {
+        type = lib.types.str;
+        default = "/var/lib/win-automation/schedules.json";
+        description = "Schedule file for the local scheduler.";
+      };
+
+      dedupWindow = lib.mkOption {
+        type = lib.types.str;
+        default = "24h";
+        description = "How long an idempotency key returns its job instead of enqueueing a new one.";
+      };
+
+      idempotencyStore = lib.mkOption {
+        type = lib.types.enum [ "hatchet" "local" ];
+        default = "hatchet";
+        description = "Where idempotency keys live: Hatchet run metadata or a local file.";
+      };
+
+      idempotencyFile = lib.mkOption {
+        type = lib.types.str;
+        default = "/var/lib/win-automation/idempotency.json";
+        description = "Key file for the local idempotency store.";
+      };
+
+      concurrency = lib.mkOption {
+        type = lib.types.attrsOf lib.types.ints.positive;
+        default = { };
+        example = { "aloha.run" = 1; "windows.exec" = 4; };
+        description = "Max running jobs per job type; unset types are bound only by workerConcurrency.";
+      };
+
+      concurrencyGroups = lib.mkOption {
+        type = lib.types.attrsOf (lib.types.submodule {
+          options = {
+            key = lib.mkOption {
+              type = lib.types.str;
+              description = "Payload field whose value groups the jobs.";
+            };
+            max_runs = lib.mkOption {
+              type = lib.types.ints.positive;
+              description = "Max running jobs per value.";
+            };
+          };
+        });
+        default = { };
+        example = { "playwright.run" = { key = "profile"; max_runs = 1; }; };
+        description = "Per job type, max running jobs sharing the value of a payload field.";
+      };
     };
 
     # Playwright options
@@ -193,6 +284,57 @@ in
         default = null;
         description = "Path to file containing Playwright WebSocket path secret.";
       };
+
+      version = lib.mkOption {
+        type = lib.types.nullOr (lib.types.strMatching "[0-9]+\\.[0-9]+(\\.[0-9]+)?");
+        default = null;
+        example = "1.49";
+        description = "Playwright version pinned on Windows and expected from clients (major.minor must match). Null detects the local package.";
+      };
+
+      browser = lib.mkOption {
+        type = lib.types.enum [ "chromium" "msedge" "firefox" "webkit" ];
+        default = "chromium";
+        description = "Default browser for Playwright runs and captures.";
+      };
+
+      browsers = lib.mkOption {
+        type = lib.types.listOf (lib.types.enum [ "chromium" "msedge" "firefox" "webkit" ]);
+        default = [ cfg.playwright.browser ];
+        defaultText = lib.literalExpression "[ config.services.win-automation.playwright.browser ]";
+        description = "Browsers that get their own Playwright server on Windows (port offset per browser).";
+      };
+
+      cacheDir = lib.mkOption {
+        type = lib.types.nullOr lib.types.path;
+        default = null;
+        example = "/var/cache/win-automation";
+        description = "Directory with the Node.js installer and Playwright package/browser archives used by `playwright install --bootstrap`.";
+      };
+
+      trace = lib.mkOption {
+        type = lib.types.enum [ "off" "on" "retain-on-failure" ];
+        default = "off";
+        description = "Default trace policy for Playwright runs; retain-on-failure keeps trace.zip only for failed flows.";
+      };
+
+      video = lib.mkOption {
+        type = lib.types.enum [ "off" "on" "retain-on-failure" ];
+        default = "off";
+        description = "Default video policy for Playwright runs.";
+      };
+
+      screenshot = lib.mkOption {
+        type = lib.types.enum [ "off" "on" "retain-on-failure" ];
+        default = "off";
+        description = "Default policy for the final screenshot of Playwright runs.";
+      };
+
+      maxSessions = lib.mkOption {
+        type = lib.types.ints.between 1 32;
+        default = 4;
+        description = "Playwright jobs the worker runs at once.";
+      };
     };
 
     # Artifacts options
@@ -210,6 +352,30 @@ in
       };
     };
 
+    # Task template options
+    tasks = {
+      dir = lib.mkOption {
+        type = lib.types.path;
+        default = "/var/lib/win-automation/tasks";
+        description = "Directory of named task templates (*.json).";
+      };
+    };
+
+    # Desktop lock options
+    desktop = {
+      lockTimeout = lib.mkOption {
+        type = lib.types.str;
+        default = "10m";
+        description = "Maximum wait for the exclusive desktop lock.";
+      };
+
+      lockTtl = lib.mkOption {
+        type = lib.types.str;
+        default = "5m";
+        description = "Desktop lock expiry when the holder stops renewing it.";
+      };
+    };
+
     # General options
     timeout = lib.mkOption {
       type = lib.types.str;